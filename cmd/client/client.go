@@ -4,20 +4,27 @@ import (
 	"context"
 	"log"
 	"math/rand"
+	"sort"
+	"sync"
 
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 
 	"github.com/mjolk/epx2/cmd/util"
+	epaxospb "github.com/mjolk/epx2/epaxos/epaxospb"
 	"github.com/mjolk/epx2/transport"
 	transpb "github.com/mjolk/epx2/transport/transportpb"
 )
 
 type client struct {
 	id        uint64
-	seqNum    uint64
 	serverSet map[*transport.ExternalClient]struct{}
+
+	// mu is held while a request that writes is in flight, so that they are
+	// issued one at a time.
+	mu     sync.Mutex
+	seqNum uint64
 }
 
 func newClient(addrs []util.Addr) (*client, error) {
@@ -57,21 +64,99 @@ func (c *client) sendReadRequest(
 	return gou, nil
 }
 
+// nextSession returns the session of the client's next request that writes.
+// All attempts of the request carry the session, so that the servers apply it
+// at most once even if an attempt that appeared to fail was applied. The
+// servers only remember the latest request of each client, so the request
+// must complete before the next one is issued. c.mu must be held.
+func (c *client) nextSession() epaxospb.ClientSession {
+	c.seqNum++
+	return epaxospb.ClientSession{ClientID: c.id, SeqNum: c.seqNum}
+}
+
 func (c *client) sendWriteRequest(
 	ctx context.Context, key, value []byte,
 ) (*transpb.KVResult, error) {
-	s := c.randomServer()
-	gou, err := s.Write(ctx, &transpb.KVWriteRequest{
-		Key:   key,
-		Value: value,
-	})
-	if err != nil {
-		if retry := c.onServerError(s, err); retry {
-			return c.sendWriteRequest(ctx, key, value)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	req := &transpb.KVWriteRequest{
+		Key:     key,
+		Value:   value,
+		Session: c.nextSession(),
+	}
+	for {
+		s := c.randomServer()
+		gou, err := s.Write(ctx, req)
+		if err != nil {
+			if retry := c.onServerError(s, err); retry {
+				continue
+			}
+			return nil, err
 		}
-		return nil, err
+		return gou, nil
 	}
-	return gou, nil
+}
+
+func (c *client) sendCompareAndSwapRequest(
+	ctx context.Context, key, expected, value []byte, expectAbsent bool,
+) (*transpb.KVResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	req := &transpb.KVCompareAndSwapRequest{
+		Key:           key,
+		ExpectedValue: expected,
+		ExpectAbsent:  expectAbsent,
+		Value:         value,
+		Session:       c.nextSession(),
+	}
+	for {
+		s := c.randomServer()
+		gou, err := s.CompareAndSwap(ctx, req)
+		if err != nil {
+			if retry := c.onServerError(s, err); retry {
+				continue
+			}
+			return nil, err
+		}
+		return gou, nil
+	}
+}
+
+func (c *client) sendTxnRequest(
+	ctx context.Context, req *transpb.KVTxnRequest,
+) (*transpb.KVTxnResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	req.Session = c.nextSession()
+	for {
+		s := c.randomServer()
+		gou, err := s.Txn(ctx, req)
+		if err != nil {
+			if retry := c.onServerError(s, err); retry {
+				continue
+			}
+			return nil, err
+		}
+		return gou, nil
+	}
+}
+
+// statuses returns the status of each available server, ordered by their
+// IDs. Servers that fail to report their status are skipped.
+func (c *client) statuses(ctx context.Context) []*transpb.NodeStatus {
+	var statuses []*transpb.NodeStatus
+	for s := range c.serverSet {
+		status, err := s.Status(ctx, &transpb.Empty{})
+		if err != nil {
+			log.Printf("could not get status: %v", err)
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].ID < statuses[j].ID
+	})
+	return statuses
 }
 
 func (c *client) randomServer() *transport.ExternalClient {
@@ -58,21 +58,47 @@ func main() {
 	}
 
 	ctx := context.Background()
+	if args := flag.Args(); len(args) > 0 {
+		switch args[0] {
+		case "status":
+			for _, status := range client.statuses(ctx) {
+				printStatus(status)
+			}
+		default:
+			log.Fatalf("unknown command %q", args[0])
+		}
+		return
+	}
+
 	reader := bufio.NewReader(os.Stdin)
 	for {
-		fmt.Print("Read or Write [r/w]: ")
-		writeStr, err := reader.ReadString('\n')
+		fmt.Print("Read, Write, Compare-and-swap or Transaction [r/w/c/t]: ")
+		opStr, err := reader.ReadString('\n')
 		if err != nil {
 			log.Fatal(err)
 		}
-		writeStr = strings.ToLower(strings.TrimSpace(writeStr))
-		write := false
+		opStr = strings.ToLower(strings.TrimSpace(opStr))
+		var write, cas bool
 		switch {
-		case writeStr == "w" || writeStr == "write":
+		case opStr == "w" || opStr == "write":
 			write = true
-		case writeStr == "r" || writeStr == "read":
+		case opStr == "c" || opStr == "cas":
+			cas = true
+		case opStr == "r" || opStr == "read":
+		case opStr == "t" || opStr == "txn":
+			req := readTxnRequest(reader)
+			res, err := client.sendTxnRequest(ctx, req)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			fmt.Printf("Applied %d puts\n", len(req.Puts))
+			for _, get := range res.Gets {
+				fmt.Printf("Key %q: %q\n", get.Key, get.Value)
+			}
+			continue
 		default:
-			fmt.Printf("Unexpected response %q\n", writeStr)
+			fmt.Printf("Unexpected response %q\n", opStr)
 			continue
 		}
 
@@ -83,15 +109,29 @@ func main() {
 		}
 		key = bytes.TrimSpace(key)
 
+		var expected []byte
+		if cas {
+			fmt.Print("Enter the expected value (empty if the key should be absent): ")
+			expected, err = reader.ReadBytes('\n')
+			if err != nil {
+				log.Fatal(err)
+			}
+			expected = bytes.TrimSpace(expected)
+		}
+
 		var res *transpb.KVResult
-		if write {
+		if write || cas {
 			fmt.Print("Enter an update: ")
 			value, err := reader.ReadBytes('\n')
 			if err != nil {
 				log.Fatal(err)
 			}
 			value = bytes.TrimSpace(value)
-			res, err = client.sendWriteRequest(ctx, key, value)
+			if cas {
+				res, err = client.sendCompareAndSwapRequest(ctx, key, expected, value, len(expected) == 0)
+			} else {
+				res, err = client.sendWriteRequest(ctx, key, value)
+			}
 		} else {
 			res, err = client.sendReadRequest(ctx, key)
 		}
@@ -99,6 +139,51 @@ func main() {
 			log.Println(err)
 			continue
 		}
+		if cas && !res.Succeeded {
+			fmt.Print("Compare-and-swap failed. ")
+		}
 		fmt.Printf("Key %q: %q\n", res.Key, res.Value)
 	}
 }
+
+// readTxnRequest prompts the user for the puts and gets of a transaction. Each
+// line holds either a "key=value" put or a key to get, and an empty line ends
+// the transaction.
+func readTxnRequest(reader *bufio.Reader) *transpb.KVTxnRequest {
+	var req transpb.KVTxnRequest
+	fmt.Println("Enter puts as key=value and gets as key, followed by an empty line:")
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			log.Fatal(err)
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			return &req
+		}
+		if i := bytes.IndexByte(line, '='); i >= 0 {
+			req.Puts = append(req.Puts, transpb.KVWriteRequest{
+				Key:   bytes.TrimSpace(line[:i]),
+				Value: bytes.TrimSpace(line[i+1:]),
+			})
+		} else {
+			req.Gets = append(req.Gets, transpb.KVReadRequest{Key: line})
+		}
+	}
+}
+
+// printStatus prints the status of a server.
+func printStatus(status *transpb.NodeStatus) {
+	fmt.Printf("Server %d (epoch %d, nodes %v, last conf change %v)\n",
+		status.ID, status.Epoch, status.Nodes, status.ConfChangeInstance)
+	for _, rs := range status.Replicas {
+		fmt.Printf("  replica %d: max instance %d, max seq %d, truncated up to %d\n",
+			rs.ReplicaID, rs.MaxInstanceNum, rs.MaxSeqNum, rs.MaxTruncatedInstanceNum)
+	}
+	for _, ic := range status.InstanceCounts {
+		fmt.Printf("  %s instances: %d\n", ic.Status, ic.Count)
+	}
+	fmt.Printf("  pending executions: %v (%d blocked)\n",
+		status.PendingExecutions, status.BlockedExecutions)
+	fmt.Printf("  armed timers: %d, pending reads: %d\n", status.ArmedTimers, status.PendingReads)
+}
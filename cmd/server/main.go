@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/pkg/errors"
 	flag "github.com/spf13/pflag"
@@ -25,6 +26,30 @@ const (
 		"processes in the hostfile are running on the same host, otherwise it can " +
 		"be deduced from the hostfile. 0-indexed."
 	verboseDesc = "Sets the logging level to verbose."
+	joinDesc    = "The optional address of a server in a running EPaxos network. If set, " +
+		"this process asks the server to add it to the network, and starts with the " +
+		"configuration in the snapshot that it is sent once it has been added. The " +
+		"hostfile of a joining process must list all current servers as well as itself."
+	ackOnCommitDesc = "Acknowledge write-only requests as soon as they are committed, " +
+		"instead of once they are executed."
+	tickIntervalDesc = "The interval at which the EPaxos state machine ticks. All " +
+		"timeouts are specified in ticks, and a timeout of 0 uses the library default."
+	slowPathTimeoutDesc = "The number of ticks a command leader waits for a fast " +
+		"path quorum once it is able to take the slow path."
+	recoveryTimeoutDesc = "The number of ticks this process waits for a command " +
+		"led by another process to commit before it attempts to recover it."
+	retransmitTimeoutDesc = "The number of ticks a thrifty command leader waits " +
+		"for replies before sending its message to all other processes."
+	executorTimeoutDesc = "The number of ticks between runs of the executor, which " +
+		"executes committed commands in batches."
+	truncateTimeoutDesc = "The number of ticks between attempts to truncate executed " +
+		"instances from memory and storage."
+	snapshotTimeoutDesc = "The minimum number of ticks between two snapshots sent " +
+		"to the same process."
+	metricsAddrDesc = "The optional address, like localhost:9090, to serve protocol " +
+		"metrics on at /metrics in the Prometheus text format."
+	sessionTTLDesc = "The duration after which the session of a client that has not " +
+		"sent any updates is ended. Clients must not retry an update for longer than this."
 )
 
 var (
@@ -33,6 +58,19 @@ var (
 	hostfile = flag.StringP("hostfile", "h", "hostfile", hostfileDesc)
 	port     = flag.IntP("port", "p", 2346, portDesc)
 	hostID   = flag.IntP("id", "i", -1, idDesc)
+	join     = flag.StringP("join", "j", "", joinDesc)
+
+	ackOnCommit = flag.Bool("ack-on-commit", false, ackOnCommitDesc)
+	metricsAddr = flag.String("metrics-addr", "", metricsAddrDesc)
+	sessionTTL  = flag.Duration("session-ttl", 10*time.Minute, sessionTTLDesc)
+
+	tickInterval      = flag.Duration("tick-interval", 10*time.Millisecond, tickIntervalDesc)
+	slowPathTimeout   = flag.Int("slow-path-timeout", 0, slowPathTimeoutDesc)
+	recoveryTimeout   = flag.Int("recovery-timeout", 0, recoveryTimeoutDesc)
+	retransmitTimeout = flag.Int("retransmit-timeout", 0, retransmitTimeoutDesc)
+	executorTimeout   = flag.Int("executor-timeout", 0, executorTimeoutDesc)
+	truncateTimeout   = flag.Int("truncate-timeout", 0, truncateTimeoutDesc)
+	snapshotTimeout   = flag.Int("snapshot-timeout", 0, snapshotTimeoutDesc)
 )
 
 func main() {
@@ -84,6 +122,7 @@ func parseHostfile() (parsedHostfile, error) {
 					"Consider using the --id flag.")
 			}
 			ph.myID = addr.Idx
+			ph.myAddr = addr.AddrStr()
 			ph.myPort = addr.Port
 		} else {
 			ph.peerAddrs = append(ph.peerAddrs, addr)
@@ -104,6 +143,7 @@ func parseHostfile() (parsedHostfile, error) {
 // all remove servers.
 type parsedHostfile struct {
 	myID      int
+	myAddr    string
 	myPort    int
 	peerAddrs []util.Addr
 }
@@ -118,9 +158,15 @@ func (ph parsedHostfile) toPaxosConfig() *epaxos.Config {
 		nodes[i] = epaxospb.ReplicaID(i)
 	}
 	return &epaxos.Config{
-		ID:     epaxospb.ReplicaID(ph.myID),
-		Nodes:  nodes,
-		Logger: logger,
+		ID:                epaxospb.ReplicaID(ph.myID),
+		Nodes:             nodes,
+		SlowPathTimeout:   *slowPathTimeout,
+		RecoveryTimeout:   *recoveryTimeout,
+		RetransmitTimeout: *retransmitTimeout,
+		ExecutorTimeout:   *executorTimeout,
+		TruncateTimeout:   *truncateTimeout,
+		SnapshotTimeout:   *snapshotTimeout,
+		Logger:            logger,
 	}
 }
 
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/mjolk/epx2/epaxos"
+)
+
+// latencyBuckets are the upper bounds of the buckets of the latency
+// histograms, in ticks.
+var latencyBuckets = []int{1, 2, 3, 5, 10, 20, 50, 100, 200, 500, 1000}
+
+// histogram is a cumulative histogram of latencies in ticks.
+type histogram struct {
+	// buckets holds the number of observations that are smaller than or equal
+	// to each of the latencyBuckets.
+	buckets []uint64
+	count   uint64
+	sum     uint64
+}
+
+func makeHistogram() histogram {
+	return histogram{buckets: make([]uint64, len(latencyBuckets))}
+}
+
+func (h *histogram) observe(ticks int) {
+	for i, le := range latencyBuckets {
+		if ticks <= le {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += uint64(ticks)
+}
+
+// metrics is an implementation of the epaxos.Metrics interface that serves
+// its measurements over HTTP in the Prometheus text format.
+type metrics struct {
+	mu               sync.Mutex
+	fastPathCommits  uint64
+	slowPaths        uint64
+	slowPathTimeouts uint64
+	commitLatency    histogram
+	executeLatency   histogram
+	sent             map[string]uint64
+	received         map[string]uint64
+}
+
+// metrics implements the epaxos.Metrics interface.
+var _ epaxos.Metrics = &metrics{}
+
+func newMetrics() *metrics {
+	return &metrics{
+		commitLatency:  makeHistogram(),
+		executeLatency: makeHistogram(),
+		sent:           make(map[string]uint64),
+		received:       make(map[string]uint64),
+	}
+}
+
+// FastPathCommit implements the epaxos.Metrics interface.
+func (m *metrics) FastPathCommit() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fastPathCommits++
+}
+
+// SlowPath implements the epaxos.Metrics interface.
+func (m *metrics) SlowPath() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.slowPaths++
+}
+
+// SlowPathTimeout implements the epaxos.Metrics interface.
+func (m *metrics) SlowPathTimeout() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.slowPathTimeouts++
+}
+
+// CommitLatency implements the epaxos.Metrics interface.
+func (m *metrics) CommitLatency(ticks int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.commitLatency.observe(ticks)
+}
+
+// ExecuteLatency implements the epaxos.Metrics interface.
+func (m *metrics) ExecuteLatency(ticks int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.executeLatency.observe(ticks)
+}
+
+// MessageSent implements the epaxos.Metrics interface.
+func (m *metrics) MessageSent(msgType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent[msgType]++
+}
+
+// MessageReceived implements the epaxos.Metrics interface.
+func (m *metrics) MessageReceived(msgType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.received[msgType]++
+}
+
+// ServeHTTP implements the http.Handler interface. It writes all measurements
+// in the Prometheus text format.
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	m.mu.Lock()
+	writeCounter(&buf, "epaxos_fast_path_commits_total",
+		"Instances led by this server that were committed on the fast path.", m.fastPathCommits)
+	writeCounter(&buf, "epaxos_slow_paths_total",
+		"Instances led by this server that took the slow path.", m.slowPaths)
+	writeCounter(&buf, "epaxos_slow_path_timeouts_total",
+		"Instances led by this server whose slow path timer fired.", m.slowPathTimeouts)
+	writeHistogram(&buf, "epaxos_commit_latency_ticks",
+		"Ticks between this server proposing an instance and committing it.", m.commitLatency)
+	writeHistogram(&buf, "epaxos_execute_latency_ticks",
+		"Ticks between an instance being committed and executed on this server.", m.executeLatency)
+	writeLabeledCounter(&buf, "epaxos_messages_sent_total",
+		"Messages sent by this server, by type.", m.sent)
+	writeLabeledCounter(&buf, "epaxos_messages_received_total",
+		"Messages received by this server, by type.", m.received)
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(buf.Bytes())
+}
+
+func writeHeader(buf *bytes.Buffer, name, help, typ string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, typ)
+}
+
+func writeCounter(buf *bytes.Buffer, name, help string, v uint64) {
+	writeHeader(buf, name, help, "counter")
+	fmt.Fprintf(buf, "%s %d\n", name, v)
+}
+
+func writeLabeledCounter(buf *bytes.Buffer, name, help string, vs map[string]uint64) {
+	writeHeader(buf, name, help, "counter")
+	types := make([]string, 0, len(vs))
+	for t := range vs {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		fmt.Fprintf(buf, "%s{type=%q} %d\n", name, t, vs[t])
+	}
+}
+
+func writeHistogram(buf *bytes.Buffer, name, help string, h histogram) {
+	writeHeader(buf, name, help, "histogram")
+	for i, le := range latencyBuckets {
+		fmt.Fprintf(buf, "%s_bucket{le=\"%d\"} %d\n", name, le, h.buckets[i])
+	}
+	fmt.Fprintf(buf, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(buf, "%s_sum %d\n", name, h.sum)
+	fmt.Fprintf(buf, "%s_count %d\n", name, h.count)
+}
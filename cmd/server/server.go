@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"math/rand"
+	"net/http"
+	"sort"
 	"time"
 
 	"github.com/pkg/errors"
@@ -14,25 +18,64 @@ import (
 	transpb "github.com/mjolk/epx2/transport/transportpb"
 )
 
-const (
-	// tickInterval is the interval at which the Paxos state machine "ticks".
-	tickInterval = 10 * time.Millisecond
-)
-
 type server struct {
 	id     epaxospb.ReplicaID
 	node   epaxos.Node
 	logger epaxos.Logger
 	ticker *time.Ticker
 
-	server          *transport.EPaxosServer
-	clients         map[epaxospb.ReplicaID]*transport.EPaxosClient
-	unavailClients  map[epaxospb.ReplicaID]struct{}
-	pendingRequests map[uint64]chan<- transpb.KVResult
+	server         *transport.EPaxosServer
+	clients        map[epaxospb.ReplicaID]*transport.EPaxosClient
+	unavailClients map[epaxospb.ReplicaID]struct{}
+	newClients     chan peerClient
+
+	// addr is the address that other servers can reach this server on.
+	addr string
+	// joinAddr, if set, is the address of a server that this server asks to
+	// add it to the EPaxos network. The node is only started once the server
+	// has joined, with the configuration in config.
+	joinAddr string
+	config   *epaxos.Config
+	// ackOnCommit determines whether write-only requests are acknowledged
+	// when they are committed instead of when they are executed.
+	ackOnCommit bool
+	// metricsAddr, if set, is the address that metrics are served on.
+	metricsAddr string
+	metrics     *metrics
+
+	// pendingSnaps holds the snapshots for nodes whose clients are still
+	// being created. They are sent once the clients are added.
+	pendingSnaps map[epaxospb.ReplicaID]epaxospb.Snapshot
+
+	// sessionTTL is the duration after which the session of a client that
+	// has not sent any updates is ended.
+	sessionTTL   time.Duration
+	expiryTicker *time.Ticker
+	// sessions holds the latest request of each client session and when it
+	// was last applied. It is only accessed by the goroutine that handles
+	// the node's Readys.
+	sessions map[uint64]sessionActivity
 
 	kv *store
 }
 
+// sessionActivity is the latest request of a client session.
+type sessionActivity struct {
+	seqNum     uint64
+	lastActive time.Time
+}
+
+// errStaleRequest is reported for attempts of client requests that were
+// superseded by a later request of the same client.
+var errStaleRequest = errors.New("client request superseded by a later request")
+
+// peerClient is an EPaxosClient for a node that has been added to the EPaxos
+// network.
+type peerClient struct {
+	id     epaxospb.ReplicaID
+	client *transport.EPaxosClient
+}
+
 func newServer(ph parsedHostfile) (*server, error) {
 	// Create a new EPaxosServer to listen on.
 	ps, err := transport.NewEPaxosServer(ph.myPort)
@@ -50,52 +93,121 @@ func newServer(ph parsedHostfile) (*server, error) {
 		clients[epaxospb.ReplicaID(addr.Idx)] = pc
 	}
 
-	kv := newStore()
+	if *sessionTTL <= 0 {
+		return nil, errors.Errorf("invalid session TTL %v", *sessionTTL)
+	}
+	kv, err := newStore()
+	if err != nil {
+		return nil, err
+	}
 
+	m := newMetrics()
 	config := ph.toPaxosConfig()
 	config.Storage = kv
+	config.Metrics = m
 
-	return &server{
-		id:              config.ID,
-		node:            epaxos.StartNode(config),
-		logger:          config.Logger,
-		ticker:          time.NewTicker(tickInterval),
-		server:          ps,
-		clients:         clients,
-		unavailClients:  make(map[epaxospb.ReplicaID]struct{}, len(ph.peerAddrs)),
-		pendingRequests: make(map[uint64]chan<- transpb.KVResult),
-		kv:              kv,
-	}, nil
+	// A joining server does not know the configuration of the EPaxos network
+	// until it has joined, so its node is started by joinNetwork. A server
+	// that joined before it restarted recovers the configuration from its
+	// HardState instead.
+	joinAddr := *join
+	if _, ok, err := kv.HardState(); err != nil {
+		return nil, err
+	} else if ok {
+		joinAddr = ""
+	}
+	var node epaxos.Node
+	if joinAddr == "" {
+		if node, err = epaxos.StartNode(config); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &server{
+		id:             config.ID,
+		node:           node,
+		logger:         config.Logger,
+		ticker:         time.NewTicker(*tickInterval),
+		server:         ps,
+		clients:        clients,
+		unavailClients: make(map[epaxospb.ReplicaID]struct{}, len(ph.peerAddrs)),
+		newClients:     make(chan peerClient),
+		addr:           ph.myAddr,
+		joinAddr:       joinAddr,
+		config:         config,
+		ackOnCommit:    *ackOnCommit,
+		metricsAddr:    *metricsAddr,
+		metrics:        m,
+		pendingSnaps:   make(map[epaxospb.ReplicaID]epaxospb.Snapshot),
+		sessionTTL:     *sessionTTL,
+		expiryTicker:   time.NewTicker(*sessionTTL / 2),
+		kv:             kv,
+	}
+	if err := s.loadSessions(); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
 func (s *server) Stop() {
 	s.ticker.Stop()
+	s.expiryTicker.Stop()
 	for _, c := range s.clients {
 		c.Close()
 	}
 	s.server.Stop()
-	s.node.Stop()
+	if s.node != nil {
+		s.node.Stop()
+	}
 }
 
 func (s *server) Run() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	if s.metricsAddr != "" {
+		go s.serveMetrics()
+	}
 	go func() {
+		if s.joinAddr != "" {
+			if err := s.joinNetwork(ctx); err != nil {
+				s.logger.Fatalf("failed to join EPaxos network: %v", err)
+			}
+		}
 		for {
 			select {
 			case <-s.ticker.C:
 				s.node.Tick()
+			case <-s.expiryTicker.C:
+				s.expireSessions(ctx)
 			case m := <-s.server.Msgs():
 				s.node.Step(ctx, *m)
+			case snap := <-s.server.Snapshots():
+				s.node.ApplySnapshot(ctx, *snap)
+			case pc := <-s.newClients:
+				s.addClient(pc)
 			case req := <-s.server.Requests():
-				s.registerClientRequest(req)
-				s.node.Propose(ctx, req.Command)
+				go s.handleRequest(req)
+			case req := <-s.server.StatusRequests():
+				go s.handleStatusRequest(req)
 			case rd := <-s.node.Ready():
+				// Nothing may be sent before the state of the Ready is
+				// durable, or other replicas may be told about promises that
+				// are forgotten after a crash. Instances that executed are
+				// only persisted as Executed once their commands are applied.
+				insts, executed := splitExecutedInstances(rd)
+				if err := s.kv.PersistReady(rd.HardState, insts); err != nil {
+					s.logger.Fatalf("failed to persist EPaxos state: %v", err)
+				}
 				if err := s.sendAll(ctx, rd.Messages); err != nil {
 					s.logger.Warning(err)
 				}
 
-				s.handleExecutedCmds(rd.ExecutedCommands)
+				if rd.Snapshot != nil {
+					s.applySnapshot(*rd.Snapshot)
+				}
+				s.handleExecutedCmds(rd.ExecutedCommands, executed, rd.TruncatedInstances)
+				s.sendSnapshots(ctx, rd.SendSnapshots)
+				s.node.Advance()
 			case <-ctx.Done():
 				return
 			}
@@ -105,42 +217,315 @@ func (s *server) Run() error {
 	return s.server.Serve()
 }
 
-func (s *server) registerClientRequest(req transport.Request) {
-	s.pendingRequests[req.Command.ID] = req.ReturnC
+// handleRequest proposes the client's update and returns its result once it
+// has been executed. If ackOnCommit is set, unconditional single-key writes
+// are acknowledged as soon as they are committed instead. Their order relative
+// to all interfering updates is fixed once they commit, and their result does
+// not depend on the state of the key-value store, so the client does not need
+// to wait for them to execute. Reads are not proposed at all, but are served
+// by the local key-value store once it reflects all writes that may have
+// completed before the read. Transactions are always proposed, even if they
+// only read, so that all of their keys are read at a single point.
+func (s *server) handleRequest(req transport.Request) {
+	defer close(req.ReturnC)
+	cmd := req.Command
+	var res interface{}
+	var err error
+	if !cmd.Writing && !cmd.IsConfChange() && !cmd.IsMultiSpan() {
+		res, err = s.node.Read(req.Context, cmd)
+	} else {
+		stage := epaxos.WaitExecuted
+		if s.ackOnCommit && !cmd.IsConfChange() && !cmd.IsConditional() && !cmd.IsMultiSpan() {
+			stage = epaxos.WaitCommitted
+		}
+		res, err = s.node.ProposeAndWait(req.Context, cmd, stage)
+	}
+	if err != nil {
+		s.logger.Warningf("failed to propose command %+v: %v", cmd, err)
+		return
+	}
+
+	switch r := res.(type) {
+	case error:
+		s.logger.Warningf("failed to apply command %+v: %v", cmd, r)
+	case transpb.KVResult:
+		req.ReturnC <- r
+	case epaxos.CommittedCommand:
+		s.logger.Infof("Committed command as command leader in instance %v %+v", r.InstanceID, cmd)
+		req.ReturnC <- transpb.KVResult{
+			Key:   cmd.Span.Key,
+			Value: cmd.Data,
+		}
+	default:
+		s.logger.Panicf("unexpected result %T for command %+v", res, cmd)
+	}
 }
 
-func (s *server) handleExecutedCmds(committed []epaxospb.Command) {
-	for _, cmd := range committed {
-		ret, ok := s.pendingRequests[cmd.ID]
+// handleStatusRequest returns the status of the local EPaxos node.
+func (s *server) handleStatusRequest(req transport.StatusRequest) {
+	defer close(req.ReturnC)
+	status := s.node.Status()
+	res := transpb.NodeStatus{
+		ID:                 uint64(status.ID),
+		Epoch:              status.Epoch,
+		ConfChangeInstance: status.ConfChangeInstance,
+		PendingExecutions:  status.PendingExecutions,
+		BlockedExecutions:  uint64(status.BlockedExecutions),
+		ArmedTimers:        uint64(status.ArmedTimers),
+		PendingReads:       uint64(status.PendingReads),
+	}
+	for _, n := range status.Nodes {
+		res.Nodes = append(res.Nodes, uint64(n))
+	}
+	for r, rs := range status.Replicas {
+		res.Replicas = append(res.Replicas, transpb.ReplicaStatus{
+			ReplicaID:               uint64(r),
+			MaxInstanceNum:          uint64(rs.MaxInstanceNum),
+			MaxSeqNum:               uint64(rs.MaxSeqNum),
+			MaxTruncatedInstanceNum: uint64(rs.MaxTruncatedInstanceNum),
+		})
+	}
+	sort.Slice(res.Replicas, func(i, j int) bool {
+		return res.Replicas[i].ReplicaID < res.Replicas[j].ReplicaID
+	})
+	for st, c := range status.InstanceCounts {
+		res.InstanceCounts = append(res.InstanceCounts, transpb.InstanceCount{
+			Status: st,
+			Count:  uint64(c),
+		})
+	}
+	sort.Slice(res.InstanceCounts, func(i, j int) bool {
+		return res.InstanceCounts[i].Status < res.InstanceCounts[j].Status
+	})
+	req.ReturnC <- res
+}
 
-		asLeader := ""
-		if ok {
-			asLeader = " as command leader"
+// splitExecutedInstances returns the InstanceStates of the Ready, in which the
+// instances whose commands are in its ExecutedCommands are still Committed.
+// Their Executed states are returned separately, keyed by the IDs of their
+// commands, to be persisted along with the writes of the commands. An
+// instance that is durably Executed does not execute again after a restart,
+// so persisting it before its commands are applied could lose them in a
+// crash.
+func splitExecutedInstances(
+	rd epaxos.Ready,
+) ([]epaxospb.InstanceState, map[uint64]*epaxospb.InstanceState) {
+	executedCmds := make(map[uint64]struct{}, len(rd.ExecutedCommands))
+	for _, cmd := range rd.ExecutedCommands {
+		executedCmds[cmd.ID] = struct{}{}
+	}
+	insts := make([]epaxospb.InstanceState, len(rd.InstanceStates))
+	executed := make(map[uint64]*epaxospb.InstanceState)
+	for i := range rd.InstanceStates {
+		is := &rd.InstanceStates[i]
+		insts[i] = *is
+		if is.Status != epaxospb.InstanceState_Executed || is.Command == nil {
+			continue
 		}
+		cmds := is.Command.Commands()
+		if _, ok := executedCmds[cmds[0].ID]; !ok {
+			continue
+		}
+		insts[i].Status = epaxospb.InstanceState_Committed
+		for _, cmd := range cmds {
+			executed[cmd.ID] = is
+		}
+	}
+	return insts, executed
+}
 
-		s.logger.Infof("Executed command%s %+v", asLeader, cmd)
-		res := s.executeCommand(cmd)
+// handleExecutedCmds applies the executed commands to the key-value store and
+// reports their results. The writes of all commands in an instance are applied
+// in a single batch along with the instance's Executed state, so after a crash
+// the instance has either been applied completely or executes again. The
+// truncated instances are removed from the store once all commands have been
+// applied.
+func (s *server) handleExecutedCmds(
+	executed []epaxospb.Command,
+	insts map[uint64]*epaxospb.InstanceState,
+	truncated map[epaxospb.ReplicaID]epaxospb.InstanceNum,
+) {
+	b := s.kv.NewBatch()
+	var ids []uint64
+	var results []interface{}
+	for _, cmd := range executed {
+		s.logger.Infof("Executed command %+v", cmd)
+		var res interface{} = transpb.KVResult{}
+		if cmd.IsConfChange() {
+			s.applyConfChange(*cmd.ConfChange)
+		} else if cmd.Op == epaxospb.Command_ExpireSession {
+			s.applyExpireSession(cmd.Session, b)
+		} else if cached, ok := s.sessionResult(cmd, b); ok {
+			s.logger.Infof("Skipped duplicate of client request %+v", cmd.Session)
+			res = cached
+		} else {
+			kvRes := s.executeCommand(cmd, b)
+			s.recordSessionResult(cmd, kvRes, b)
+			res = kvRes
+		}
+		ids = append(ids, cmd.ID)
+		results = append(results, res)
+
+		// The commands of an instance are executed consecutively, and the
+		// batch is committed after the last of them.
+		if is, ok := insts[cmd.ID]; ok {
+			if cmds := is.Command.Commands(); cmds[len(cmds)-1].ID != cmd.ID {
+				continue
+			}
+			if err := b.SetInstance(is); err != nil {
+				s.logger.Panic(err)
+			}
+		}
+		if err := b.Commit(); err != nil {
+			s.logger.Panic(err)
+		}
+		for i, id := range ids {
+			s.node.ReportResult(id, results[i])
+		}
+		b, ids, results = s.kv.NewBatch(), nil, nil
+	}
 
-		if ok {
-			delete(s.pendingRequests, cmd.ID)
-			ret <- res
-			close(ret)
+	for r, i := range truncated {
+		if err := b.TruncateInstances(r, i); err != nil {
+			s.logger.Panic(err)
 		}
 	}
+	if err := b.Commit(); err != nil {
+		s.logger.Panic(err)
+	}
+}
+
+// deduplicated returns whether the command is applied at most once for its
+// client session. Only commands that write are deduplicated, because reads
+// can safely be repeated.
+//
+// The dedup table only holds the latest request of each client and its
+// result. Clients issue their requests one at a time, so a request that is
+// older than the latest one has already been answered and is never applied.
+// All commands in a client session write the client's session key, so they
+// interfere and execute in the same order on every replica, and every replica
+// makes the same decisions about them.
+func deduplicated(cmd epaxospb.Command) bool {
+	return cmd.HasSession() && (cmd.Writing || len(cmd.WriteSpans) > 0)
+}
+
+// sessionResult returns the result for the client request that the command
+// was proposed for, if the request must not be applied. That is the recorded
+// result if the request is the latest one of its client that was applied, or
+// errStaleRequest if the client has moved on to a later request.
+func (s *server) sessionResult(cmd epaxospb.Command, b *batch) (interface{}, bool) {
+	if !deduplicated(cmd) {
+		return nil, false
+	}
+	latest, data, ok, err := b.Session(cmd.Session.ClientID)
+	if err != nil {
+		s.logger.Panic(err)
+	}
+	if !ok || cmd.Session.SeqNum > latest.SeqNum {
+		return nil, false
+	}
+	if cmd.Session.SeqNum < latest.SeqNum {
+		return errStaleRequest, true
+	}
+	var res transpb.KVResult
+	if err := res.Unmarshal(data); err != nil {
+		s.logger.Panicf("invalid result for client request %+v: %v", cmd.Session, err)
+	}
+	return res, true
+}
+
+// recordSessionResult records the client request that the command was
+// proposed for as the latest one of its client in the batch, along with its
+// result, so that later attempts of the request are not applied again.
+func (s *server) recordSessionResult(cmd epaxospb.Command, res transpb.KVResult, b *batch) {
+	if !deduplicated(cmd) {
+		return
+	}
+	data, err := res.Marshal()
+	if err != nil {
+		s.logger.Panic(err)
+	}
+	b.SetSession(cmd.Session, data)
+	s.sessions[cmd.Session.ClientID] = sessionActivity{
+		seqNum:     cmd.Session.SeqNum,
+		lastActive: time.Now(),
+	}
+}
+
+// loadSessions starts tracking the activity of all client sessions in the
+// key-value store, as if they were just active.
+func (s *server) loadSessions() error {
+	sessions, err := s.kv.Sessions()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	s.sessions = make(map[uint64]sessionActivity, len(sessions))
+	for _, session := range sessions {
+		s.sessions[session.ClientID] = sessionActivity{seqNum: session.SeqNum, lastActive: now}
+	}
+	return nil
 }
 
-func (s *server) executeCommand(cmd epaxospb.Command) transpb.KVResult {
+// expireSessions proposes to end the sessions of all clients that have not
+// sent an update for sessionTTL. Every server proposes to end them, and all but
+// the first of the proposals that execute are ignored. An expiry that fails is
+// proposed again after another sessionTTL.
+func (s *server) expireSessions(ctx context.Context) {
+	now := time.Now()
+	for clientID, a := range s.sessions {
+		if now.Sub(a.lastActive) < s.sessionTTL {
+			continue
+		}
+		cmd := epaxospb.Command{
+			ID:      rand.Uint64(),
+			Op:      epaxospb.Command_ExpireSession,
+			Session: epaxospb.ClientSession{ClientID: clientID, SeqNum: a.seqNum},
+		}
+		if err := s.node.Propose(ctx, cmd); err != nil {
+			s.logger.Warningf("failed to propose command %+v: %v", cmd, err)
+		}
+		a.lastActive = now
+		s.sessions[clientID] = a
+	}
+}
+
+// applyExpireSession ends the client's session if the request in the session
+// is still its latest one. A client that sent another request in the meantime
+// keeps its session.
+func (s *server) applyExpireSession(session epaxospb.ClientSession, b *batch) {
+	latest, _, ok, err := b.Session(session.ClientID)
+	if err != nil {
+		s.logger.Panic(err)
+	}
+	if !ok || latest.SeqNum != session.SeqNum {
+		return
+	}
+	b.DeleteSession(session.ClientID)
+	delete(s.sessions, session.ClientID)
+	s.logger.Infof("Ended session of client %d", session.ClientID)
+}
+
+// executeCommand executes the command against the key-value store through the
+// batch, and adds its writes to the batch.
+func (s *server) executeCommand(cmd epaxospb.Command, b *batch) transpb.KVResult {
+	if cmd.IsMultiSpan() {
+		return s.executeTxn(cmd, b)
+	}
 	if cmd.Span.EndKey != nil {
 		s.logger.Panicf("unexpected EndKey in command %+v", cmd)
 	}
+	if cmd.IsConditional() {
+		return s.executeConditionalCommand(cmd, b)
+	}
 	key := cmd.Span.Key
 	var val []byte
 	if cmd.Writing {
 		val = cmd.Data
-		s.kv.SetKey(key, val)
+		b.SetKey(key, val)
 	} else {
 		var err error
-		val, err = s.kv.GetKey(key)
+		val, err = b.GetKey(key)
 		if err != nil {
 			s.logger.Panic(err)
 		}
@@ -151,6 +536,234 @@ func (s *server) executeCommand(cmd epaxospb.Command) transpb.KVResult {
 	}
 }
 
+// executeConditionalCommand writes the command's data to its key if the key
+// holds the value that the command expects. The result holds the key's value
+// after the command has executed.
+func (s *server) executeConditionalCommand(cmd epaxospb.Command, b *batch) transpb.KVResult {
+	key := cmd.Span.Key
+	val, err := b.GetKey(key)
+	if err != nil {
+		s.logger.Panic(err)
+	}
+	var succeeded bool
+	switch cmd.Op {
+	case epaxospb.Command_CompareAndSwap:
+		succeeded = val != nil && bytes.Equal(val, cmd.ExpectedValue)
+	case epaxospb.Command_PutIfAbsent:
+		succeeded = val == nil
+	default:
+		s.logger.Panicf("unexpected operation %v in command %+v", cmd.Op, cmd)
+	}
+	if succeeded {
+		val = cmd.Data
+		b.SetKey(key, val)
+	}
+	return transpb.KVResult{
+		Key:       key,
+		Value:     val,
+		Succeeded: succeeded,
+	}
+}
+
+// executeTxn applies the transaction that the multi-span command carries as
+// its data. Its gets and puts access distinct keys, and all of them take
+// effect at the command's position in the execution order. The puts are added
+// to the batch.
+func (s *server) executeTxn(cmd epaxospb.Command, b *batch) transpb.KVResult {
+	var req transpb.KVTxnRequest
+	if err := req.Unmarshal(cmd.Data); err != nil {
+		s.logger.Panicf("invalid transaction in command %+v: %v", cmd, err)
+	}
+	txn := &transpb.KVTxnResult{Gets: make([]transpb.KVResult, len(req.Gets))}
+	for i, get := range req.Gets {
+		val, err := b.GetKey(get.Key)
+		if err != nil {
+			s.logger.Panic(err)
+		}
+		txn.Gets[i] = transpb.KVResult{Key: get.Key, Value: val}
+	}
+	keys := make([][]byte, len(req.Puts))
+	vals := make([][]byte, len(req.Puts))
+	for i, put := range req.Puts {
+		keys[i], vals[i] = put.Key, put.Value
+	}
+	b.SetKeys(keys, vals)
+	return transpb.KVResult{Txn: txn}
+}
+
+// serveMetrics serves the server's metrics over HTTP on metricsAddr.
+func (s *server) serveMetrics() {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.metrics)
+	if err := http.ListenAndServe(s.metricsAddr, mux); err != nil {
+		s.logger.Errorf("failed to serve metrics: %v", err)
+	}
+}
+
+// joinNetwork asks the server at joinAddr to add the local server to the
+// EPaxos network, and starts the local node once the server that led the
+// change has sent it a snapshot. The snapshot holds the current configuration
+// of the network and reflects every command executed before the local server
+// joined, so the node starts out with the same nodes, epoch, and state as the
+// other servers instead of the ones in its hostfile.
+func (s *server) joinNetwork(ctx context.Context) error {
+	c, err := transport.NewEPaxosClient(s.joinAddr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	_, err = c.ProposeConfChange(ctx, &epaxospb.ConfChange{
+		Type:      epaxospb.ConfChange_AddNode,
+		ReplicaID: s.id,
+		Context:   []byte(s.addr),
+	})
+	if err != nil {
+		return err
+	}
+
+	var snap *epaxospb.Snapshot
+	select {
+	case snap = <-s.server.Snapshots():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	s.config.Nodes = snap.Metadata.Nodes
+	node, err := epaxos.StartNode(s.config)
+	if err != nil {
+		return err
+	}
+	s.node = node
+	if err := node.ApplySnapshot(ctx, *snap); err != nil {
+		return err
+	}
+	s.logger.Infof("Joined EPaxos network through %s in epoch %d", s.joinAddr, snap.Metadata.Epoch)
+	return nil
+}
+
+// applyConfChange adds or removes the EPaxosClient of the node that the
+// ConfChange refers to. Clients for new nodes are created asynchronously,
+// because dialing blocks until the node is reachable.
+func (s *server) applyConfChange(cc epaxospb.ConfChange) {
+	if cc.ReplicaID == s.id {
+		if cc.Type == epaxospb.ConfChange_RemoveNode {
+			s.logger.Warning("removed from EPaxos network")
+		}
+		return
+	}
+	switch cc.Type {
+	case epaxospb.ConfChange_AddNode:
+		if _, ok := s.clients[cc.ReplicaID]; ok {
+			return
+		}
+		addr := string(cc.Context)
+		go func() {
+			c, err := transport.NewEPaxosClient(addr)
+			if err != nil {
+				s.logger.Warningf("failed to connect to node %d at %s: %v", cc.ReplicaID, addr, err)
+				return
+			}
+			s.newClients <- peerClient{id: cc.ReplicaID, client: c}
+		}()
+	case epaxospb.ConfChange_RemoveNode:
+		s.removeClient(cc.ReplicaID)
+	default:
+		s.logger.Panicf("unexpected ConfChange type %v", cc.Type)
+	}
+}
+
+func (s *server) addClient(pc peerClient) {
+	if _, ok := s.clients[pc.id]; ok {
+		pc.client.Close()
+		return
+	}
+	s.logger.Infof("Added node %d to EPaxos network", pc.id)
+	s.clients[pc.id] = pc.client
+	delete(s.unavailClients, pc.id)
+	if snap, ok := s.pendingSnaps[pc.id]; ok {
+		delete(s.pendingSnaps, pc.id)
+		s.sendSnapshot(context.Background(), pc.id, pc.client, snap)
+	}
+}
+
+func (s *server) removeClient(id epaxospb.ReplicaID) {
+	delete(s.pendingSnaps, id)
+	c, ok := s.clients[id]
+	if !ok {
+		return
+	}
+	s.logger.Infof("Removed node %d from EPaxos network", id)
+	if _, unavail := s.unavailClients[id]; !unavail {
+		c.Close()
+	}
+	delete(s.clients, id)
+	delete(s.unavailClients, id)
+}
+
+func (s *server) applySnapshot(snap epaxospb.Snapshot) {
+	s.logger.Infof("Applying snapshot %+v", snap.Metadata.ExecutedInstanceNums)
+	if err := s.kv.ApplySnapshot(snap.Data); err != nil {
+		s.logger.Panic(err)
+	}
+	if err := s.loadSessions(); err != nil {
+		s.logger.Panic(err)
+	}
+
+	// Drop the clients of all nodes that were removed from the EPaxos network
+	// by ConfChanges reflected in the snapshot.
+	if len(snap.Metadata.Nodes) == 0 {
+		return
+	}
+	nodes := make(map[epaxospb.ReplicaID]struct{}, len(snap.Metadata.Nodes))
+	for _, id := range snap.Metadata.Nodes {
+		nodes[id] = struct{}{}
+	}
+	for id := range s.clients {
+		if _, ok := nodes[id]; !ok {
+			s.removeClient(id)
+		}
+	}
+}
+
+// sendSnapshots sends a snapshot of the key-value store to each replica that
+// has requested one. The snapshot is taken synchronously, so that it matches
+// the snapshot metadata, but is sent asynchronously, because it may be large.
+func (s *server) sendSnapshots(ctx context.Context, reqs []epaxos.SnapshotRequest) {
+	if len(reqs) == 0 {
+		return
+	}
+	data, err := s.kv.Snapshot()
+	if err != nil {
+		s.logger.Warning(err)
+		return
+	}
+	for _, req := range reqs {
+		snap := epaxospb.Snapshot{Metadata: req.Metadata, Data: data}
+		c, ok := s.clients[req.To]
+		if !ok {
+			// The node was just added to the EPaxos network, and its client
+			// is still being created.
+			s.pendingSnaps[req.To] = snap
+			continue
+		}
+		if _, unavail := s.unavailClients[req.To]; unavail {
+			continue
+		}
+		s.sendSnapshot(ctx, req.To, c, snap)
+	}
+}
+
+// sendSnapshot sends the snapshot to the node asynchronously.
+func (s *server) sendSnapshot(
+	ctx context.Context, to epaxospb.ReplicaID, c *transport.EPaxosClient, snap epaxospb.Snapshot,
+) {
+	go func() {
+		s.logger.Infof("Sending snapshot to node %d", to)
+		if err := c.SendSnapshot(ctx, snap); err != nil {
+			s.logger.Warningf("failed to send snapshot to node %d: %v", to, err)
+		}
+	}()
+}
+
 func (s *server) sendAll(ctx context.Context, msgs []epaxospb.Message) error {
 	outboxes := make(map[epaxospb.ReplicaID][]epaxospb.Message)
 	for _, m := range msgs {
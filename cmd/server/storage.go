@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/binary"
 	"io/ioutil"
 
@@ -18,9 +19,14 @@ var (
 
 	nullByte             = byte(0x00)
 	userspacePrefix      = []byte("u")
+	sessionPrefix        = []byte("s")
 	epaxosPrefix         = []byte("p")
 	epaxosHS             = append(epaxosPrefix, []byte("hs")...)
 	epaxosInstancePrefix = append(epaxosPrefix, []byte("is")...)
+
+	// stateMachinePrefixes are the prefixes of the keyspaces that hold the
+	// replicated state machine, which are included in snapshots.
+	stateMachinePrefixes = [][]byte{userspacePrefix, sessionPrefix}
 )
 
 type store struct {
@@ -42,13 +48,22 @@ func getItemValue(item *badger.KVItem) ([]byte, error) {
 	return val, nil
 }
 
-func newStore() *store {
+func newStore() (*store, error) {
 	opt := badger.DefaultOptions
-	dir, _ := ioutil.TempDir("/tmp", dirName)
+	dir, err := ioutil.TempDir("/tmp", dirName)
+	if err != nil {
+		return nil, err
+	}
 	opt.Dir = dir
 	opt.ValueDir = dir
-	kv, _ := badger.NewKV(&opt)
-	return &store{kv: kv}
+	// Each epaxos.Ready is persisted in a single batch, so syncing writes
+	// costs a single fsync per Ready.
+	opt.SyncWrites = true
+	kv, err := badger.NewKV(&opt)
+	if err != nil {
+		return nil, err
+	}
+	return &store{kv: kv}, nil
 }
 
 func (s *store) Close() {
@@ -59,75 +74,380 @@ func encodeUserKey(key []byte) []byte {
 	return append(userspacePrefix, key...)
 }
 
+// get gets the value at the given key, or returns nil if no key exists.
+func (s *store) get(key []byte) ([]byte, error) {
+	var item badger.KVItem
+	if err := s.kv.Get(key, &item); err != nil {
+		return nil, err
+	}
+	return getItemValue(&item)
+}
+
+// batch collects writes to the store, which are applied atomically by Commit.
+// Reads through the batch observe its writes.
+type batch struct {
+	s       *store
+	entries []*badger.Entry
+	// vals holds the values that the entries write, keyed by their keys.
+	// Deleted keys hold nil.
+	vals map[string][]byte
+}
+
+// NewBatch returns an empty batch of writes to the store.
+func (s *store) NewBatch() *batch {
+	return &batch{s: s, vals: make(map[string][]byte)}
+}
+
+func (b *batch) get(key []byte) ([]byte, error) {
+	if val, ok := b.vals[string(key)]; ok {
+		return val, nil
+	}
+	return b.s.get(key)
+}
+
+func (b *batch) set(key, val []byte) {
+	b.entries = badger.EntriesSet(b.entries, key, val)
+	b.vals[string(key)] = val
+}
+
+func (b *batch) delete(key []byte) {
+	b.entries = badger.EntriesDelete(b.entries, key)
+	b.vals[string(key)] = nil
+}
+
+// Commit applies the writes of the batch in a single batch, so that either
+// all or none of them are applied.
+func (b *batch) Commit() error {
+	if err := b.s.batchSet(b.entries); err != nil {
+		return errors.Wrap(err, "Error while committing batch")
+	}
+	return nil
+}
+
+// GetKey gets the value at the given key, or returns nil if no key exists.
+func (b *batch) GetKey(key []byte) ([]byte, error) {
+	val, err := b.get(encodeUserKey(key))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error while getting key: %q", key)
+	}
+	return val, nil
+}
+
 // SetKey sets the given key to the value provided.
-func (s *store) SetKey(key, val []byte) {
-	s.kv.Set(encodeUserKey(key), val, 0x00)
+func (b *batch) SetKey(key, val []byte) {
+	b.set(encodeUserKey(key), val)
 }
 
-// GetKey gets the value at the given key, or returns false if
-// no key exists.
-func (s *store) GetKey(key []byte) ([]byte, error) {
-	var item badger.KVItem
-	if err := s.kv.Get(encodeUserKey(key), &item); err != nil {
-		errors.Wrapf(err, "Error while getting key: %q", key)
+// SetKeys sets each of the given keys to the corresponding value provided.
+func (b *batch) SetKeys(keys, vals [][]byte) {
+	for i, key := range keys {
+		b.SetKey(key, vals[i])
 	}
-	return getItemValue(&item)
+}
+
+// Session gets the latest request of the client that was applied and its
+// result, if the client has a session.
+func (b *batch) Session(clientID uint64) (epaxospb.ClientSession, []byte, bool, error) {
+	key := encodeSessionKey(clientID)
+	val, err := b.get(key)
+	if err != nil {
+		return epaxospb.ClientSession{}, nil, false, errors.Wrapf(err, "Error while getting session: %d", clientID)
+	}
+	if val == nil {
+		return epaxospb.ClientSession{}, nil, false, nil
+	}
+	session, res, err := decodeSession(key, val)
+	return session, res, err == nil, err
+}
+
+// SetSession records the client request identified by the session as the
+// latest one of its client, along with its result.
+func (b *batch) SetSession(session epaxospb.ClientSession, res []byte) {
+	val := make([]byte, 8+len(res))
+	binary.BigEndian.PutUint64(val, session.SeqNum)
+	copy(val[8:], res)
+	b.set(encodeSessionKey(session.ClientID), val)
+}
+
+// DeleteSession deletes the client's session.
+func (b *batch) DeleteSession(clientID uint64) {
+	b.delete(encodeSessionKey(clientID))
+}
+
+// SetInstance persists the InstanceState.
+func (b *batch) SetInstance(is *epaxospb.InstanceState) error {
+	val, err := proto.Marshal(is)
+	if err != nil {
+		return errors.Wrapf(err, "Error while encoding instance %v", is.InstanceID)
+	}
+	b.set(encodeInstanceKey(is), val)
+	return nil
+}
+
+// TruncateInstances deletes all instances in the replica's instance space up
+// to and including the provided instance number.
+func (b *batch) TruncateInstances(r epaxospb.ReplicaID, i epaxospb.InstanceNum) error {
+	// Instance numbers are not encoded in sorted order, so all of the
+	// replica's instances are scanned.
+	prefix := encodeReplicaPrefix(r)
+
+	opt := badger.DefaultIteratorOptions
+	opt.FetchValues = false
+	itr := b.s.kv.NewIterator(opt)
+	defer itr.Close()
+	for itr.Seek(prefix); itr.ValidForPrefix(prefix); itr.Next() {
+		key := itr.Item().Key()
+		instNum, n := binary.Uvarint(key[len(prefix):])
+		if n <= 0 {
+			return errors.Errorf("invalid instance key: %q", key)
+		}
+		if epaxospb.InstanceNum(instNum) <= i {
+			b.delete(append([]byte(nil), key...))
+		}
+	}
+	return nil
+}
+
+// encodeSessionKey encodes a client's session into a unique key.
+//
+// encoding scheme:
+//   prefix <clientID>
+func encodeSessionKey(clientID uint64) []byte {
+	key := make([]byte, len(sessionPrefix)+8)
+	n := copy(key, sessionPrefix)
+	binary.BigEndian.PutUint64(key[n:], clientID)
+	return key
+}
+
+// decodeSession decodes the latest request of a session and its result out of
+// an entry written by batch.SetSession.
+func decodeSession(key, val []byte) (epaxospb.ClientSession, []byte, error) {
+	if len(key) != len(sessionPrefix)+8 || len(val) < 8 {
+		return epaxospb.ClientSession{}, nil, errors.Errorf("invalid session: %q", key)
+	}
+	session := epaxospb.ClientSession{
+		ClientID: binary.BigEndian.Uint64(key[len(sessionPrefix):]),
+		SeqNum:   binary.BigEndian.Uint64(val),
+	}
+	return session, val[8:], nil
+}
+
+// Sessions returns the latest request of every client that has a session.
+func (s *store) Sessions() ([]epaxospb.ClientSession, error) {
+	var sessions []epaxospb.ClientSession
+	itr := s.kv.NewIterator(badger.DefaultIteratorOptions)
+	defer itr.Close()
+	for itr.Seek(sessionPrefix); itr.ValidForPrefix(sessionPrefix); itr.Next() {
+		item := itr.Item()
+		val, err := getItemValue(item)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error while reading session: %q", item.Key())
+		}
+		session, _, err := decodeSession(item.Key(), val)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// Snapshot returns an encoding of all keys in the state machine's keyspaces,
+// which can be applied to another store with ApplySnapshot.
+//
+// encoding scheme:
+//   (<len(key)> <key> <len(value)> <value>)*
+func (s *store) Snapshot() ([]byte, error) {
+	var data []byte
+	var lenBuf [binary.MaxVarintLen64]byte
+	appendBytes := func(b []byte) {
+		n := binary.PutUvarint(lenBuf[:], uint64(len(b)))
+		data = append(data, lenBuf[:n]...)
+		data = append(data, b...)
+	}
+
+	itr := s.kv.NewIterator(badger.DefaultIteratorOptions)
+	defer itr.Close()
+	for _, prefix := range stateMachinePrefixes {
+		for itr.Seek(prefix); itr.ValidForPrefix(prefix); itr.Next() {
+			item := itr.Item()
+			val, err := getItemValue(item)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Error while reading key: %q", item.Key())
+			}
+			appendBytes(item.Key())
+			appendBytes(val)
+		}
+	}
+	return data, nil
+}
+
+// ApplySnapshot replaces all keys in the state machine's keyspaces with those
+// in the snapshot, which was created by Snapshot.
+func (s *store) ApplySnapshot(data []byte) error {
+	var entries []*badger.Entry
+
+	opt := badger.DefaultIteratorOptions
+	opt.FetchValues = false
+	itr := s.kv.NewIterator(opt)
+	for _, prefix := range stateMachinePrefixes {
+		for itr.Seek(prefix); itr.ValidForPrefix(prefix); itr.Next() {
+			key := append([]byte(nil), itr.Item().Key()...)
+			entries = badger.EntriesDelete(entries, key)
+		}
+	}
+	itr.Close()
+
+	readBytes := func() ([]byte, error) {
+		l, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < l {
+			return nil, errors.New("corrupt snapshot")
+		}
+		b := data[n : n+int(l)]
+		data = data[n+int(l):]
+		return b, nil
+	}
+	for len(data) > 0 {
+		key, err := readBytes()
+		if err != nil {
+			return err
+		}
+		if !hasStateMachinePrefix(key) {
+			return errors.Errorf("unexpected key %q in snapshot", key)
+		}
+		val, err := readBytes()
+		if err != nil {
+			return err
+		}
+		entries = badger.EntriesSet(entries, key, val)
+	}
+	return s.batchSet(entries)
+}
+
+// hasStateMachinePrefix returns whether the key belongs to one of the state
+// machine's keyspaces.
+func hasStateMachinePrefix(key []byte) bool {
+	for _, prefix := range stateMachinePrefixes {
+		if bytes.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 // store implements the epaxos.Storage interface.
 var _ epaxos.Storage = &store{}
 
-func (s *store) HardState() (hs epaxospb.HardState, found bool) {
+func (s *store) HardState() (hs epaxospb.HardState, found bool, err error) {
 	var item badger.KVItem
 	if err := s.kv.Get(epaxosHS, &item); err != nil {
-		panic(err)
+		return hs, false, errors.Wrap(err, "Error while getting HardState")
 	}
 	val, err := getItemValue(&item)
 	if err != nil {
-		return hs, false
+		return hs, false, errors.Wrap(err, "Error while reading HardState")
 	}
 	if val == nil {
-		return hs, false
+		return hs, false, nil
 	}
 	if err := proto.Unmarshal(val, &hs); err != nil {
-		panic(err)
+		return hs, false, errors.Wrap(err, "Error while decoding HardState")
 	}
-	return hs, true
+	return hs, true, nil
 }
 
-func (s *store) PersistHardState(hs epaxospb.HardState) {
-	val, err := proto.Marshal(&hs)
-	if err != nil {
-		panic(err)
-	}
-	s.kv.Set(epaxosHS, val, 0x00)
+func (s *store) PersistHardState(hs epaxospb.HardState) error {
+	return s.PersistReady(&hs, nil)
 }
 
-func (s *store) Instances() []*epaxospb.InstanceState {
+func (s *store) Instances() ([]*epaxospb.InstanceState, error) {
 	var insts []*epaxospb.InstanceState
 
 	opt := badger.DefaultIteratorOptions
 	itr := s.kv.NewIterator(opt)
-	for itr.Rewind(); itr.ValidForPrefix(epaxosInstancePrefix); itr.Next() {
+	defer itr.Close()
+	for itr.Seek(epaxosInstancePrefix); itr.ValidForPrefix(epaxosInstancePrefix); itr.Next() {
 		item := itr.Item()
-		val, _ := getItemValue(item)
+		val, err := getItemValue(item)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error while reading instance: %q", item.Key())
+		}
 
 		inst := &epaxospb.InstanceState{}
 		if err := proto.Unmarshal(val, inst); err != nil {
-			panic(err)
+			return nil, errors.Wrapf(err, "Error while decoding instance: %q", item.Key())
 		}
 		insts = append(insts, inst)
 	}
-	itr.Close()
-	return insts
+	return insts, nil
 }
 
-func (s *store) PersistInstance(is *epaxospb.InstanceState) {
-	val, err := proto.Marshal(is)
-	if err != nil {
-		panic(err)
+func (s *store) PersistInstance(is *epaxospb.InstanceState) error {
+	return s.PersistReady(nil, []epaxospb.InstanceState{*is})
+}
+
+// PersistReady persists the HardState and InstanceStates of an epaxos.Ready in
+// a single batch, so that they are synced to disk together.
+func (s *store) PersistReady(hs *epaxospb.HardState, insts []epaxospb.InstanceState) error {
+	var entries []*badger.Entry
+	if hs != nil {
+		val, err := proto.Marshal(hs)
+		if err != nil {
+			return errors.Wrap(err, "Error while encoding HardState")
+		}
+		entries = badger.EntriesSet(entries, epaxosHS, val)
+	}
+	for i := range insts {
+		is := &insts[i]
+		val, err := proto.Marshal(is)
+		if err != nil {
+			return errors.Wrapf(err, "Error while encoding instance %v", is.InstanceID)
+		}
+		entries = badger.EntriesSet(entries, encodeInstanceKey(is), val)
+	}
+	return s.batchSet(entries)
+}
+
+// batchSet applies the entries in a single batch.
+func (s *store) batchSet(entries []*badger.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := s.kv.BatchSet(entries); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Error != nil {
+			return e.Error
+		}
 	}
-	s.kv.Set(encodeInstanceKey(is), val, 0x00)
+	return nil
+}
+
+func (s *store) TruncateInstances(r epaxospb.ReplicaID, i epaxospb.InstanceNum) error {
+	b := s.NewBatch()
+	if err := b.TruncateInstances(r, i); err != nil {
+		return err
+	}
+	return b.Commit()
+}
+
+// encodeReplicaPrefix encodes the key prefix of all instances in the
+// replica's instance space.
+//
+// encoding scheme:
+//   prefix <replicaID> 0x00
+func encodeReplicaPrefix(r epaxospb.ReplicaID) []byte {
+	l := len(epaxosInstancePrefix) + binary.MaxVarintLen64 + 1
+	key := make([]byte, l)
+	copy(key, epaxosInstancePrefix)
+	end := len(epaxosInstancePrefix)
+
+	end += binary.PutUvarint(key[end:], uint64(r))
+
+	key[end] = nullByte
+	end++
+	return key[:end]
 }
 
 // encodeInstanceKey encodes an InstanceState into a unique key.
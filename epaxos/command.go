@@ -7,6 +7,21 @@ import (
 	pb "github.com/mjolk/epx2/epaxos/epaxospb"
 )
 
+// addInstanceSpace adds an empty command space for the replica, if it does not
+// already have one.
+func (p *epaxos) addInstanceSpace(r pb.ReplicaID) {
+	if _, ok := p.commands[r]; !ok {
+		p.commands[r] = btree.New(32 /* degree */)
+		p.conflicts[r] = newConflictIndex()
+	}
+}
+
+// hasInstanceSpace returns whether the replica has a command space.
+func (p *epaxos) hasInstanceSpace(r pb.ReplicaID) bool {
+	_, ok := p.commands[r]
+	return ok
+}
+
 func (p *epaxos) maxInstance(r pb.ReplicaID) *instance {
 	if maxInstItem := p.commands[r].Max(); maxInstItem != nil {
 		return maxInstItem.(*instance)
@@ -18,7 +33,7 @@ func (p *epaxos) maxInstanceNum(r pb.ReplicaID) pb.InstanceNum {
 	if maxInst := p.maxInstance(r); maxInst != nil {
 		return maxInst.is.InstanceNum
 	}
-	return 0
+	return p.maxTruncatedInstanceNum[r]
 }
 
 func (p *epaxos) maxSeqNum(r pb.ReplicaID) pb.SeqNum {
@@ -42,18 +57,24 @@ func (p *epaxos) getInstance(r pb.ReplicaID, i pb.InstanceNum) *instance {
 	return nil
 }
 
+// hasTruncated returns whether the instance has been truncated from the
+// replica's command space. Only executed instances are truncated.
+func (p *epaxos) hasTruncated(r pb.ReplicaID, i pb.InstanceNum) bool {
+	return i <= p.maxTruncatedInstanceNum[r]
+}
+
 func (p *epaxos) hasAccepted(r pb.ReplicaID, i pb.InstanceNum) bool {
 	if inst := p.getInstance(r, i); inst != nil {
 		return inst.is.Status >= pb.InstanceState_Accepted
 	}
-	return false
+	return p.hasTruncated(r, i)
 }
 
 func (p *epaxos) hasExecuted(r pb.ReplicaID, i pb.InstanceNum) bool {
 	if inst := p.getInstance(r, i); inst != nil {
 		return inst.is.Status == pb.InstanceState_Executed
 	}
-	return false
+	return p.hasTruncated(r, i)
 }
 
 // HasExecuted implements the history interface.
@@ -66,11 +87,23 @@ func (p *epaxos) HasExecuted(e executableID) bool {
 // number and dependencies for a given command.
 func (p *epaxos) seqAndDepsForCommand(
 	cmd *pb.Command, ignoredInstance pb.InstanceID,
+) (pb.SeqNum, map[pb.InstanceID]struct{}) {
+	return p.seqAndDeps(cmd, ignoredInstance, !cmd.IsConfChange())
+}
+
+// seqAndDeps determines the locally known maximum interfering sequence number
+// and dependencies for a given command. If useIndex is set, only the instances
+// that the conflict index finds to overlap with the command are considered.
+// Otherwise, every instance is. Reconfiguration commands interfere with all
+// commands, so they can not use the index.
+func (p *epaxos) seqAndDeps(
+	cmd *pb.Command, ignoredInstance pb.InstanceID, useIndex bool,
 ) (pb.SeqNum, map[pb.InstanceID]struct{}) {
 	var maxSeq pb.SeqNum
 	deps := make(map[pb.InstanceID]struct{})
 
-	cmdRage := rangeForCmd(cmd)
+	cmdAccesses := cmd.Accesses()
+	cmdRanges := rangesForAccesses(cmdAccesses)
 	for rID, cmds := range p.commands {
 		// Adding to the writeRG and readRG allows us to minimize the number of
 		// dependencies we add for this command without building a directed graph
@@ -78,8 +111,10 @@ func (p *epaxos) seqAndDepsForCommand(
 		// commands ove a given key-range being transitive. It also relies on the
 		// causality of subsequent instances within the same replica instance space.
 		// The logic here is very similar to that in CockroachDB's Command Queue.
-		cmds.Descend(func(i btree.Item) bool {
-			inst := i.(*instance)
+		//
+		// Instances are visited in descending order of instance number until
+		// visit returns false.
+		visit := func(inst *instance) bool {
 			if inst.is.InstanceID == ignoredInstance {
 				return true
 			}
@@ -92,43 +127,112 @@ func (p *epaxos) seqAndDepsForCommand(
 				deps[dep] = struct{}{}
 			}
 
-			if otherCmd := inst.is.Command; otherCmd.Interferes(*cmd) {
+			// Instances without a command either have not been learned yet
+			// or are no-ops. Neither interfere with the command.
+			otherCmd := inst.is.Command
+			if otherCmd == nil {
+				return true
+			}
+
+			if otherCmd.Interferes(*cmd) {
 				maxSeq = pb.MaxSeqNum(maxSeq, inst.is.SeqNum)
 
-				otherCmdRange := rangeForCmd(otherCmd)
-				if otherCmd.Writing {
-					// We add the other command's range to the RangeGroup and
-					// observe if it grows the group. If it does, that means
-					// that it is not a full transitive dependency of other
-					// dependencies of ours. If it is, that means that we do
-					// not need to depend on it because previous dependencies
-					// necessarily already have it as a dependency themself.
-					if p.rangeGroup.Add(otherCmdRange) {
-						addDep()
-						if p.rangeGroup.Len() == 1 && p.rangeGroup.Encloses(cmdRage) {
-							return false
+				// Reconfiguration commands interfere with all commands, so
+				// they can not be tracked in the RangeGroup.
+				if cmd.IsConfChange() || otherCmd.IsConfChange() {
+					addDep()
+					return true
+				}
+
+				// The spans that the other command accesses, including those
+				// of the commands in a batch, are considered individually. The
+				// command is a dependency if any of them is.
+				isDep := false
+				for _, otherAccess := range otherCmd.Accesses() {
+					if !accessInterferes(otherAccess, cmdAccesses) {
+						continue
+					}
+					otherCmdRange := rangeForSpan(otherAccess.Span)
+					if otherAccess.Writing {
+						// We add the other command's range to the RangeGroup and
+						// observe if it grows the group. If it does, that means
+						// that it is not a full transitive dependency of other
+						// dependencies of ours. If it is, that means that we do
+						// not need to depend on it because previous dependencies
+						// necessarily already have it as a dependency themself.
+						if p.rangeGroup.Add(otherCmdRange) {
+							isDep = true
+						}
+					} else {
+						// We check if the current RangeGroup overlaps the read
+						// dependency. Reads don't depend on reads, so this will
+						// only happen if a write was inserted that fully covers
+						// the read.
+						if !p.rangeGroup.Overlaps(otherCmdRange) {
+							isDep = true
 						}
 					}
-				} else {
-					// We check if the current RangeGroup overlaps the read
-					// dependency. Reads don't depend on reads, so this will
-					// only happen if a write was inserted that fully covers
-					// the read.
-					if !p.rangeGroup.Overlaps(otherCmdRange) {
-						addDep()
+				}
+				if isDep {
+					addDep()
+					if p.rangeGroup.Len() == 1 && p.rangeGroupEncloses(cmdRanges) {
+						return false
 					}
 				}
 			}
 			return true
-		})
+		}
+
+		if useIndex {
+			for _, inst := range p.conflicts[rID].overlapping(cmd, ignoredInstance) {
+				if !visit(inst) {
+					break
+				}
+			}
+		} else {
+			cmds.Descend(func(i btree.Item) bool {
+				return visit(i.(*instance))
+			})
+		}
 		p.rangeGroup.Clear()
 	}
 	return maxSeq, deps
 }
 
-func rangeForCmd(cmd *pb.Command) interval.Range {
-	startKey := cmd.Span.Key
-	endKey := cmd.Span.EndKey
+// rangeGroupEncloses returns whether the RangeGroup encloses all of the
+// ranges.
+func (p *epaxos) rangeGroupEncloses(rs []interval.Range) bool {
+	for _, r := range rs {
+		if !p.rangeGroup.Encloses(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// accessInterferes returns whether the access interferes with any of the
+// accesses.
+func accessInterferes(a pb.Access, as []pb.Access) bool {
+	for _, o := range as {
+		if a.Interferes(o) {
+			return true
+		}
+	}
+	return false
+}
+
+// rangesForAccesses returns the ranges of the accessed spans.
+func rangesForAccesses(as []pb.Access) []interval.Range {
+	rs := make([]interval.Range, len(as))
+	for i, a := range as {
+		rs[i] = rangeForSpan(a.Span)
+	}
+	return rs
+}
+
+func rangeForSpan(span pb.Span) interval.Range {
+	startKey := span.Key
+	endKey := span.EndKey
 	if len(endKey) == 0 {
 		endKey = append(startKey, 0)
 	}
@@ -144,45 +248,136 @@ func (p *epaxos) onRequest(cmd *pb.Command) *instance {
 
 	// Add a new instance for the command in the local commands.
 	maxLocalSeq, localDeps := p.seqAndDepsForCommand(cmd, pb.InstanceID{})
+	p.addTruncationDeps(localDeps)
+	// The sequence number is kept above those of all truncated instances,
+	// which may have interfered with the command.
 	newInst := p.newInstance(p.id, i)
-	newInst.is.Command = cmd
-	newInst.is.SeqNum = maxLocalSeq + 1
+	newInst.setCommand(cmd)
+	newInst.is.SeqNum = pb.MaxSeqNum(maxLocalSeq, p.maxTruncatedSeqNum) + 1
 	newInst.is.Deps = depSliceFromMap(localDeps)
+	newInst.proposed = true
+	newInst.proposedAt = p.ticks
 	p.commands[p.id].ReplaceOrInsert(newInst)
 
 	// Transition the new instance into a preAccepted state.
-	newInst.transitionTo(pb.InstanceState_PreAccepted)
+	newInst.transitionTo(pb.InstanceState_PreAccepted, ReasonProposed)
 	return newInst
 }
 
+// addTruncationDeps adds a dependency on the last truncated instance of each
+// replica. Truncated instances are not considered when determining the
+// dependencies of a command, so a replica that never learned about them would
+// otherwise be able to execute the command without them. Instead, it will find
+// that it has fallen behind while trying to learn about the dependency.
+func (p *epaxos) addTruncationDeps(deps map[pb.InstanceID]struct{}) {
+	for r, i := range p.maxTruncatedInstanceNum {
+		if i > 0 {
+			deps[pb.InstanceID{ReplicaID: r, InstanceNum: i}] = struct{}{}
+		}
+	}
+}
+
 func (p *epaxos) prepareToExecute(inst *instance) {
 	inst.assertState(pb.InstanceState_Committed)
+	p.unregisterTimer(&inst.recoveryTimer)
+	p.unregisterTimer(&inst.thriftyTimer)
+	inst.committedAt = p.ticks
+	if inst.proposed {
+		inst.proposed = false
+		p.metrics.CommitLatency(p.ticks - inst.proposedAt)
+	}
+	p.watchDependencies(inst)
+	// Instances without a command are no-ops, which are committed during
+	// recovery. There is nobody to acknowledge them to.
+	if inst.is.Command != nil {
+		for _, cmd := range inst.is.Command.Commands() {
+			p.deliverCommittedCommand(inst.is.InstanceID, cmd)
+		}
+	}
+	// The instance is executed in a batch on the next run of the executor,
+	// which is driven by the executorTimer.
 	p.executor.addExec(inst)
-	// TODO pull executor into a different goroutine and run asynchronously.
-	p.executor.run()
-	// p.truncateCommands()
-}
-
-// TODO reintroduce instance space truncation.
-// func (p *epaxos) truncateCommands() {
-// 	for r, cmds := range p.commands {
-// 		var executedItems []btree.Item
-// 		cmds.Ascend(func(i btree.Item) bool {
-// 			if i.(*instance).is.Status == pb.InstanceState_Executed {
-// 				executedItems = append(executedItems, i)
-// 				return true
-// 			}
-// 			return false
-// 		})
-// 		if len(executedItems) > 0 {
-// 			curMaxInstNum := p.maxTruncatedInstanceNum[r]
-// 			for _, executedItem := range executedItems {
-// 				inst := executedItem.(*instance)
-// 				p.maxTruncatedSeqNum = pb.MaxSeqNum(p.maxTruncatedSeqNum, inst.is.SeqNum)
-// 				curMaxInstNum = pb.MaxInstanceNum(curMaxInstNum, inst.is.InstanceNum)
-// 				cmds.Delete(executedItem)
-// 			}
-// 			p.maxTruncatedInstanceNum[r] = curMaxInstNum
-// 		}
-// 	}
-// }
+}
+
+// watchDependencies makes sure that the local replica will eventually learn
+// the outcome of each of the instance's dependencies, which it needs before
+// the instance can execute. Dependencies that the replica has never heard of
+// are created so that they can be recovered if their leader never commits
+// them.
+func (p *epaxos) watchDependencies(inst *instance) {
+	for _, dep := range inst.is.Deps {
+		p.watchInstance(dep.ReplicaID, dep.InstanceNum)
+	}
+}
+
+// watchInstance creates the instance if the local replica has never heard of
+// it, so that it will be recovered if its leader never commits it.
+func (p *epaxos) watchInstance(r pb.ReplicaID, i pb.InstanceNum) {
+	if r == p.id || !p.hasInstanceSpace(r) || p.hasTruncated(r, i) || p.getInstance(r, i) != nil {
+		return
+	}
+	inst := p.newInstance(r, i)
+	p.commands[r].ReplaceOrInsert(inst)
+	inst.resetRecoveryTimer()
+}
+
+// truncateCommands truncates executed instances from each replica's command
+// space, both in memory and in storage. Only a contiguous prefix of executed
+// instances is truncated, so that the truncation index of each command space
+// is a single instance number. Instances are truncated on the run after the
+// one on which they were found to be executed, which gives replicas that are
+// recovering them a chance to learn their outcome first. Instances whose
+// commands have not been handed out to be applied are not found.
+func (p *epaxos) truncateCommands() {
+	var truncated []pb.ReplicaID
+	for r, cmds := range p.commands {
+		if upTo := p.truncationCandidates[r]; upTo > p.maxTruncatedInstanceNum[r] {
+			p.truncateInstances(r, upTo)
+			truncated = append(truncated, r)
+		}
+
+		next := p.maxTruncatedInstanceNum[r]
+		cmds.Ascend(func(i btree.Item) bool {
+			inst := i.(*instance)
+			if inst.is.InstanceNum != next+1 || !inst.isStates(pb.InstanceState_Executed) ||
+				inst.unapplied {
+				return false
+			}
+			next++
+			return true
+		})
+		p.truncationCandidates[r] = next
+	}
+
+	// The new truncation indexes are handed out in the HardState of the next
+	// Ready, along with the instances that the application must remove from
+	// storage. Any instances left over after a crash are removed when the
+	// node restarts. Candidates were handed out to be applied in an
+	// earlier Ready, which is advanced before the next one is handed out, so
+	// their commands are applied before their truncation is persisted.
+	for _, r := range truncated {
+		p.truncateStorage(r)
+	}
+}
+
+// truncateInstances removes all instances up to and including the provided
+// instance number from the replica's command space in memory and advances its
+// truncation index.
+func (p *epaxos) truncateInstances(r pb.ReplicaID, upTo pb.InstanceNum) {
+	cmds := p.commands[r]
+	for {
+		minItem := cmds.Min()
+		if minItem == nil || minItem.(*instance).is.InstanceNum > upTo {
+			break
+		}
+		inst := minItem.(*instance)
+		p.unregisterTimer(&inst.slowPathTimer)
+		p.unregisterTimer(&inst.recoveryTimer)
+		p.unregisterTimer(&inst.thriftyTimer)
+		p.executor.removeExec(inst.Identifier())
+		inst.unindex()
+		p.maxTruncatedSeqNum = pb.MaxSeqNum(p.maxTruncatedSeqNum, inst.is.SeqNum)
+		cmds.DeleteMin()
+	}
+	p.maxTruncatedInstanceNum[r] = upTo
+}
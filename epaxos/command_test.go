@@ -42,33 +42,33 @@ func newTestingEPaxos() *epaxos {
 	p := newEPaxos(&c)
 
 	inst01 := p.newInstance(0, 1)
-	inst01.is.InstanceData = pb.InstanceData{
+	inst01.setInstanceData(pb.InstanceData{
 		Command: newTestingCommand("a", "z"),
 		SeqNum:  1,
 		Deps:    []pb.InstanceID{},
-	}
+	})
 
 	inst11 := p.newInstance(1, 1)
-	inst11.is.InstanceData = pb.InstanceData{
+	inst11.setInstanceData(pb.InstanceData{
 		Command: newTestingCommand("a", "z"),
 		SeqNum:  2,
 		Deps: []pb.InstanceID{
 			pb.InstanceID{ReplicaID: 0, InstanceNum: 1},
 		},
-	}
+	})
 
 	inst21 := p.newInstance(2, 1)
-	inst21.is.InstanceData = pb.InstanceData{
+	inst21.setInstanceData(pb.InstanceData{
 		Command: newTestingCommand("a", "b"),
 		SeqNum:  3,
 		Deps: []pb.InstanceID{
 			pb.InstanceID{ReplicaID: 0, InstanceNum: 1},
 			pb.InstanceID{ReplicaID: 1, InstanceNum: 1},
 		},
-	}
+	})
 
 	inst02 := p.newInstance(0, 2)
-	inst02.is.InstanceData = pb.InstanceData{
+	inst02.setInstanceData(pb.InstanceData{
 		Command: newTestingCommand("a", "m"),
 		SeqNum:  4,
 		Deps: []pb.InstanceID{
@@ -76,17 +76,17 @@ func newTestingEPaxos() *epaxos {
 			pb.InstanceID{ReplicaID: 1, InstanceNum: 1},
 			pb.InstanceID{ReplicaID: 2, InstanceNum: 1},
 		},
-	}
+	})
 
 	inst12 := p.newInstance(1, 2)
-	inst12.is.InstanceData = pb.InstanceData{
+	inst12.setInstanceData(pb.InstanceData{
 		Command: newTestingCommand("n", "z"),
 		SeqNum:  5,
 		Deps: []pb.InstanceID{
 			pb.InstanceID{ReplicaID: 0, InstanceNum: 1},
 			pb.InstanceID{ReplicaID: 1, InstanceNum: 1},
 		},
-	}
+	})
 
 	p.commands[0].ReplaceOrInsert(inst01)
 	p.commands[1].ReplaceOrInsert(inst11)
@@ -241,3 +241,223 @@ func TestOnRequestDependencies(t *testing.T) {
 	}
 	assertMaxDeps()
 }
+
+// TestOnRequestBatchDependencies verifies that a batch of commands depends on
+// all instances that any of its commands would depend on on its own.
+func TestOnRequestBatchDependencies(t *testing.T) {
+	p := newTestingEPaxos()
+	batch := &pb.Command{Batch: []pb.Command{
+		*newTestingCommand("a", "b"),
+		*newTestingReadCommand("n", "z"),
+	}}
+
+	var expSeq pb.SeqNum
+	expDeps := make(map[pb.InstanceID]struct{})
+	for i := range batch.Batch {
+		seq, deps := p.seqAndDepsForCommand(&batch.Batch[i], pb.InstanceID{})
+		expSeq = pb.MaxSeqNum(expSeq, seq)
+		for dep := range deps {
+			expDeps[dep] = struct{}{}
+		}
+	}
+
+	seq, deps := p.seqAndDepsForCommand(batch, pb.InstanceID{})
+	if seq != expSeq {
+		t.Errorf("expected sequence number %v for batch, found %v", expSeq, seq)
+	}
+	if !reflect.DeepEqual(deps, expDeps) {
+		t.Errorf("expected dependencies %v for batch, found %v", expDeps, deps)
+	}
+}
+
+// TestOnRequestMultiSpanDependencies verifies that a multi-span command has the
+// dependencies of all of the spans that it reads and writes.
+func TestOnRequestMultiSpanDependencies(t *testing.T) {
+	p := newTestingEPaxos()
+	multi := &pb.Command{
+		ID:         rand.Uint64(),
+		ReadSpans:  []pb.Span{{Key: pb.Key("n"), EndKey: pb.Key("z")}},
+		WriteSpans: []pb.Span{{Key: pb.Key("a"), EndKey: pb.Key("b")}},
+	}
+	batch := &pb.Command{Batch: []pb.Command{
+		*newTestingCommand("a", "b"),
+		*newTestingReadCommand("n", "z"),
+	}}
+
+	expSeq, expDeps := p.seqAndDepsForCommand(batch, pb.InstanceID{})
+	seq, deps := p.seqAndDepsForCommand(multi, pb.InstanceID{})
+	if seq != expSeq {
+		t.Errorf("expected sequence number %v for multi-span command, found %v", expSeq, seq)
+	}
+	if !reflect.DeepEqual(deps, expDeps) {
+		t.Errorf("expected dependencies %v for multi-span command, found %v", expDeps, deps)
+	}
+
+	// Writes to any of the command's spans depend on it, while reads only
+	// depend on it if it writes their span.
+	inst := p.onRequest(multi)
+	testCases := []struct {
+		cmd *pb.Command
+		dep bool
+	}{
+		{newTestingCommand("a", ""), true},
+		{newTestingReadCommand("a", ""), true},
+		{newTestingCommand("p", ""), true},
+		{newTestingReadCommand("p", ""), false},
+		{newTestingCommand("c", ""), false},
+	}
+	for _, tc := range testCases {
+		_, deps := p.seqAndDepsForCommand(tc.cmd, pb.InstanceID{})
+		if _, ok := deps[inst.is.InstanceID]; ok != tc.dep {
+			t.Errorf("expected dependency %t of %v on multi-span command, found %t", tc.dep, tc.cmd, ok)
+		}
+	}
+}
+
+// executeTestingInstances marks the given instances of the testing epaxos
+// state machine as executed.
+func executeTestingInstances(p *epaxos, ids ...pb.InstanceID) {
+	for _, id := range ids {
+		p.getInstance(id.ReplicaID, id.InstanceNum).is.Status = pb.InstanceState_Executed
+	}
+}
+
+func TestTruncateCommands(t *testing.T) {
+	p := newTestingEPaxos()
+
+	// Execute all instances other than 1.2. Instance 2.3 is executed, but 2.2
+	// is unknown, so only 2.1 may be truncated.
+	inst23 := p.newInstance(2, 3)
+	p.commands[2].ReplaceOrInsert(inst23)
+	executeTestingInstances(p,
+		pb.InstanceID{ReplicaID: 0, InstanceNum: 1},
+		pb.InstanceID{ReplicaID: 0, InstanceNum: 2},
+		pb.InstanceID{ReplicaID: 1, InstanceNum: 1},
+		pb.InstanceID{ReplicaID: 2, InstanceNum: 1},
+		pb.InstanceID{ReplicaID: 2, InstanceNum: 3},
+	)
+
+	// The first run only finds instances to truncate.
+	p.truncateCommands()
+	for r := range p.commands {
+		if p.hasTruncated(r, 1) {
+			t.Errorf("expected no instances to be truncated for replica %v", r)
+		}
+	}
+
+	// The second run truncates them.
+	p.truncateCommands()
+	expTruncated := map[pb.ReplicaID]pb.InstanceNum{
+		0: 2,
+		1: 1,
+		2: 1,
+	}
+	expRemaining := map[pb.ReplicaID]int{
+		0: 0,
+		1: 1,
+		2: 1,
+	}
+	for r, cmds := range p.commands {
+		if a, e := p.maxTruncatedInstanceNum[r], expTruncated[r]; a != e {
+			t.Errorf("expected truncated instance number %v for replica %v, found %v", e, r, a)
+		}
+		if a, e := cmds.Len(), expRemaining[r]; a != e {
+			t.Errorf("expected %d instances for replica %v, found %d", e, r, a)
+		}
+		if !p.hasExecuted(r, expTruncated[r]) {
+			t.Errorf("expected truncated instances of replica %v to be executed", r)
+		}
+	}
+	if p.getInstance(2, 3) != inst23 {
+		t.Errorf("expected instance 2.3 to not be truncated")
+	}
+	if a, e := p.maxTruncatedSeqNum, pb.SeqNum(4); a != e {
+		t.Errorf("expected truncated seq number %v, found %v", e, a)
+	}
+
+	// The truncation indexes should be persisted, and the truncated instances
+	// handed out to be removed from storage.
+	if a, e := makeReady(p).TruncatedInstances, expTruncated; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected truncated instances %v in Ready, found %v", e, a)
+	}
+	p.persistReady()
+	if a := makeReady(p).TruncatedInstances; a != nil {
+		t.Errorf("expected no truncated instances in Ready once persisted, found %v", a)
+	}
+	hs, _, _ := p.storage.HardState()
+	if a, e := hs.TruncatedInstanceNums, expTruncated; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected persisted truncated instance numbers %v, found %v", e, a)
+	}
+	if a, e := hs.TruncatedSeqNum, p.maxTruncatedSeqNum; a != e {
+		t.Errorf("expected persisted truncated seq number %v, found %v", e, a)
+	}
+}
+
+// TestTruncateCommandsUnapplied verifies that instances are not truncated
+// before their commands have been handed out to be applied.
+func TestTruncateCommandsUnapplied(t *testing.T) {
+	p := newTestingEPaxos()
+	inst := p.newInstance(0, 1)
+	p.commands[0].ReplaceOrInsert(inst)
+	inst.is.Command = newTestingCommand("a", "z")
+	inst.is.Status = pb.InstanceState_Committed
+	inst.transitionTo(pb.InstanceState_Executed, ReasonExecuted)
+
+	p.truncateCommands()
+	p.truncateCommands()
+	if p.hasTruncated(0, 1) {
+		t.Fatalf("expected instance with unapplied commands to not be truncated")
+	}
+
+	p.clearExecutedCommands()
+	p.truncateCommands()
+	p.truncateCommands()
+	if !p.hasTruncated(0, 1) {
+		t.Errorf("expected instance to be truncated once its commands were handed out")
+	}
+}
+
+func TestOnRequestAfterTruncation(t *testing.T) {
+	p := newTestingEPaxos()
+	executeTestingInstances(p,
+		pb.InstanceID{ReplicaID: 0, InstanceNum: 1},
+		pb.InstanceID{ReplicaID: 0, InstanceNum: 2},
+	)
+	p.truncateCommands()
+	p.truncateCommands()
+	if a := p.commands[0].Len(); a != 0 {
+		t.Fatalf("expected all instances of replica 0 to be truncated, found %d", a)
+	}
+
+	// New commands continue from the truncated instance and sequence numbers,
+	// and depend on the last truncated instance in place of all truncated
+	// instances.
+	inst := p.onRequest(newTestingCommand("a", "c"))
+	if a, e := inst.is.InstanceNum, pb.InstanceNum(3); a != e {
+		t.Errorf("expected instance number %v, found %v", e, a)
+	}
+	if a, e := inst.is.SeqNum, pb.SeqNum(5); a != e {
+		t.Errorf("expected sequence number %v, found %v", e, a)
+	}
+	expDeps := []pb.InstanceID{
+		{ReplicaID: 0, InstanceNum: 2},
+		{ReplicaID: 1, InstanceNum: 1},
+		{ReplicaID: 2, InstanceNum: 1},
+	}
+	if a, e := inst.is.Deps, expDeps; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected deps %v, found %v", e, a)
+	}
+
+	// Messages for truncated instances are ignored.
+	p.clearMsgs()
+	p.changeID(t, 1)
+	p.Step(pb.Message{
+		To:         1,
+		InstanceID: pb.InstanceID{ReplicaID: 0, InstanceNum: 2},
+		Type:       pb.WrapMessageInner(&pb.Commit{InstanceData: testingInstanceData}),
+	})
+	p.assertOutboxEmpty(t)
+	if p.getInstance(0, 2) != nil {
+		t.Errorf("expected truncated instance to not be recreated")
+	}
+}
@@ -0,0 +1,63 @@
+package epaxos
+
+import (
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+// applyConfChange applies the reconfiguration command of an executed instance
+// to the set of nodes in the EPaxos network. Reconfiguration commands
+// interfere with all other commands, so all replicas apply them in the same
+// order relative to every other command. Each one increments the
+// configuration epoch, which fences off requests sent in ballots of earlier
+// configurations.
+//
+// A ConfChange that leaves a number of nodes that the configured quorums are
+// not valid for is rejected. Every replica executes it against the same set of
+// nodes, so all of them reject it. applyConfChange returns whether the
+// ConfChange was applied.
+func (p *epaxos) applyConfChange(inst *instance) bool {
+	if inst.is.InstanceID == p.confChangeInstance {
+		// The ConfChange was already applied before a restart.
+		return true
+	}
+	cc := inst.is.Command.ConfChange
+
+	nodes := make([]pb.ReplicaID, 0, len(p.nodes)+1)
+	for _, r := range p.nodes {
+		if r != cc.ReplicaID {
+			nodes = append(nodes, r)
+		}
+	}
+	switch cc.Type {
+	case pb.ConfChange_AddNode:
+		nodes = append(nodes, cc.ReplicaID)
+	case pb.ConfChange_RemoveNode:
+	default:
+		p.logger.Panicf("unexpected ConfChange type: %v", cc.Type)
+	}
+	if err := p.quorums.Validate(len(nodes)); err != nil {
+		p.logger.Errorf("rejected %v, quorums invalid for nodes %v: %v", cc, nodes, err)
+		p.deliverFailedCommand(inst.is.Command.ID, ErrInvalidConfChange)
+		return false
+	}
+
+	if cc.Type == pb.ConfChange_AddNode {
+		p.addInstanceSpace(cc.ReplicaID)
+		// The added replica learns about the configuration and all instances
+		// executed before it joined from a snapshot, which the command leader
+		// of the ConfChange sends it.
+		if inst.is.ReplicaID == p.id && cc.ReplicaID != p.id {
+			p.maybeSendSnapshot(cc.ReplicaID)
+		}
+	} else if cc.ReplicaID == p.id {
+		p.logger.Warningf("local replica removed from EPaxos network")
+	}
+
+	p.configurations[p.epoch] = p.nodes
+	p.nodes = nodes
+	p.epoch++
+	p.confChangeInstance = inst.is.InstanceID
+	p.persistHardState()
+	p.logger.Debugf("applied %v, nodes %v in epoch %d", cc, p.nodes, p.epoch)
+	return true
+}
@@ -0,0 +1,291 @@
+package epaxos
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+func newConfChangeCommand(t pb.ConfChange_Type, r pb.ReplicaID) *pb.Command {
+	cmd := newTestingCommand("", "")
+	cmd.ConfChange = &pb.ConfChange{Type: t, ReplicaID: r}
+	return cmd
+}
+
+// TestConfigNodesFromHardState tests that a restarting replica uses the set of
+// nodes in its HardState, which may have been changed by reconfiguration
+// commands, instead of the one that it was first started with.
+func TestConfigNodesFromHardState(t *testing.T) {
+	c := &Config{ID: 1, Nodes: []pb.ReplicaID{0, 1, 2}}
+	c.Storage = NewMemoryStorage(c)
+	c.Storage.PersistHardState(pb.HardState{
+		ReplicaID: 1,
+		Nodes:     []pb.ReplicaID{0, 1, 2, 3},
+		Epoch:     1,
+	})
+	p := newEPaxos(c)
+
+	if a, e := p.nodes, []pb.ReplicaID{0, 1, 2, 3}; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected nodes %v, found %v", e, a)
+	}
+	if a, e := p.epoch, uint64(1); a != e {
+		t.Errorf("expected epoch %d, found %d", e, a)
+	}
+	if !p.hasInstanceSpace(3) {
+		t.Errorf("expected instance space for replica 3")
+	}
+
+	// The replica must be in the node set.
+	c.Storage.PersistHardState(pb.HardState{
+		ReplicaID: 1,
+		Nodes:     []pb.ReplicaID{0, 2, 3},
+	})
+	if err := c.validate(); err == nil {
+		t.Errorf("expected error for replica removed from node set")
+	}
+}
+
+func TestConfChangeInterferesWithAll(t *testing.T) {
+	p := newTestingEPaxos()
+	inst := p.onRequest(newConfChangeCommand(pb.ConfChange_AddNode, 3))
+
+	expDeps := []pb.InstanceID{
+		{ReplicaID: 0, InstanceNum: 1},
+		{ReplicaID: 0, InstanceNum: 2},
+		{ReplicaID: 1, InstanceNum: 1},
+		{ReplicaID: 1, InstanceNum: 2},
+		{ReplicaID: 2, InstanceNum: 1},
+	}
+	if a, e := inst.is.Deps, expDeps; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected deps %v, found %v", e, a)
+	}
+
+	// All later commands depend on the reconfiguration command.
+	inst2 := p.onRequest(newTestingCommand("y", "z"))
+	found := false
+	for _, dep := range inst2.is.Deps {
+		found = found || dep == inst.is.InstanceID
+	}
+	if !found {
+		t.Errorf("expected command to depend on %v, found deps %v", inst.is.InstanceID, inst2.is.Deps)
+	}
+}
+
+func TestApplyConfChange(t *testing.T) {
+	p := newTestingEPaxos()
+	inst := p.newInstance(1, 3)
+	inst.is.Command = newConfChangeCommand(pb.ConfChange_AddNode, 3)
+
+	p.applyConfChange(inst)
+	if a, e := p.nodes, []pb.ReplicaID{0, 1, 2, 3}; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected nodes %v, found %v", e, a)
+	}
+	if a, e := p.epoch, uint64(1); a != e {
+		t.Errorf("expected epoch %d, found %d", e, a)
+	}
+	if !p.hasInstanceSpace(3) {
+		t.Errorf("expected instance space for added replica")
+	}
+	if a, e := p.nodesAt(0), []pb.ReplicaID{0, 1, 2}; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected nodes %v in earlier epoch, found %v", e, a)
+	}
+
+	// Applying the same ConfChange again is a no-op.
+	p.applyConfChange(inst)
+	if a, e := p.epoch, uint64(1); a != e {
+		t.Errorf("expected epoch %d, found %d", e, a)
+	}
+
+	inst = p.newInstance(2, 2)
+	inst.is.Command = newConfChangeCommand(pb.ConfChange_RemoveNode, 1)
+	p.applyConfChange(inst)
+	if a, e := p.nodes, []pb.ReplicaID{0, 2, 3}; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected nodes %v, found %v", e, a)
+	}
+	if !p.hasInstanceSpace(1) {
+		t.Errorf("expected instance space of removed replica to be kept")
+	}
+
+	p.persistReady()
+	hs, _, _ := p.storage.HardState()
+	if a, e := hs.Nodes, p.nodes; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected persisted nodes %v, found %v", e, a)
+	}
+	if a, e := hs.Epoch, uint64(2); a != e {
+		t.Errorf("expected persisted epoch %d, found %d", e, a)
+	}
+	if a, e := hs.ConfChangeInstance, inst.is.InstanceID; a != e {
+		t.Errorf("expected persisted ConfChange instance %v, found %v", e, a)
+	}
+	expConfs := []pb.Configuration{
+		{Epoch: 0, Nodes: []pb.ReplicaID{0, 1, 2}},
+		{Epoch: 1, Nodes: []pb.ReplicaID{0, 1, 2, 3}},
+	}
+	if a := hs.Configurations; !reflect.DeepEqual(a, expConfs) {
+		t.Errorf("expected persisted configurations %v, found %v", expConfs, a)
+	}
+}
+
+// TestAddNodeSendsSnapshot tests that the command leader of a ConfChange that
+// adds a replica sends the replica a snapshot.
+func TestAddNodeSendsSnapshot(t *testing.T) {
+	p := newTestingEPaxos()
+	inst := p.newInstance(0, 3)
+	inst.is.Command = newConfChangeCommand(pb.ConfChange_AddNode, 3)
+
+	p.applyConfChange(inst)
+	reqs := p.snapshotRequests()
+	if len(reqs) != 1 || reqs[0].To != 3 {
+		t.Fatalf("expected snapshot request for added replica, found %v", reqs)
+	}
+	if a, e := reqs[0].Metadata.Nodes, p.nodes; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected snapshot of nodes %v, found %v", e, a)
+	}
+	if a, e := reqs[0].Metadata.Epoch, uint64(1); a != e {
+		t.Errorf("expected snapshot of epoch %d, found %d", e, a)
+	}
+}
+
+// TestApplyConfChangeInvalidQuorums tests that a ConfChange that leaves a
+// number of nodes that the quorums are not valid for is rejected, and that the
+// command that proposed it is failed.
+func TestApplyConfChangeInvalidQuorums(t *testing.T) {
+	p := newTestingEPaxos()
+	p.quorums = FlexibleQuorum{FastPathSize: 3, SlowPathSize: 2, PrepareSize: 2}
+	inst := p.newInstance(1, 3)
+	inst.is.Command = newConfChangeCommand(pb.ConfChange_RemoveNode, 2)
+
+	if p.applyConfChange(inst) {
+		t.Fatalf("expected ConfChange to be rejected")
+	}
+	if a, e := p.nodes, []pb.ReplicaID{0, 1, 2}; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected nodes %v, found %v", e, a)
+	}
+	if a, e := p.epoch, uint64(0); a != e {
+		t.Errorf("expected epoch %d, found %d", e, a)
+	}
+	exp := []failedCommand{{id: inst.is.Command.ID, err: ErrInvalidConfChange}}
+	if a := p.failedCmds; !reflect.DeepEqual(a, exp) {
+		t.Errorf("expected failed commands %v, found %v", exp, a)
+	}
+}
+
+// TestRejectEarlierEpoch tests that a replica rejects requests sent in ballots
+// of an earlier configuration epoch.
+func TestRejectEarlierEpoch(t *testing.T) {
+	p := newTestingEPaxos()
+	p.epoch = 1
+
+	_, instData, msg := preAcceptMsg()
+	p.Step(msg)
+
+	inst := p.getInstance(msg.InstanceID.ReplicaID, msg.InstanceID.InstanceNum)
+	inst.assertState(pb.InstanceState_None)
+	p.assertOutbox(t, pb.Message{
+		To:         1,
+		InstanceID: msg.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.NACK{Ballot: pb.Ballot{Epoch: 1}}),
+	})
+	p.clearMsgs()
+
+	// The command leader retries in the new epoch.
+	msg.Ballot = pb.Ballot{Epoch: 1}
+	p.Step(msg)
+	inst.assertState(pb.InstanceState_PreAccepted)
+	if a, e := inst.is.Command, instData.Command; a != e {
+		t.Errorf("expected command %v, found %v", e, a)
+	}
+}
+
+// TestOnNACKNewEpoch tests that a command leader retries its current phase in
+// a new configuration epoch when its messages are rejected because of their
+// epoch.
+func TestOnNACKNewEpoch(t *testing.T) {
+	p := newTestingEPaxos()
+	inst := p.onRequest(testingCmd)
+	p.clearMsgs()
+
+	b := pb.Ballot{Epoch: 1}
+	nack := pb.WrapMessage(&pb.NACK{Ballot: b})
+	nack.To = 0
+	nack.InstanceID = inst.is.InstanceID
+	p.Step(nack)
+
+	if !inst.isLeader() {
+		t.Fatalf("expected replica to remain leader of instance")
+	}
+	msg := pb.Message{
+		Ballot:     b,
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAccept{InstanceData: inst.instanceData()}),
+	}
+	p.assertOutbox(t, msg.WithDestination(1), msg.WithDestination(2))
+}
+
+// TestAddNode verifies that a replica can be added to the EPaxos network, and
+// that it catches up on the commands that it depends on.
+func TestAddNode(t *testing.T) {
+	n := newNetwork(3)
+	nodes := []pb.ReplicaID{0, 1, 2, 3}
+	n.peers[3] = newEPaxos(&Config{ID: 3, Nodes: nodes, RandSeed: 3})
+
+	cc := n.peers[0].onRequest(newConfChangeCommand(pb.ConfChange_AddNode, 3))
+	if !n.runNetworkFor(recoveryTicks, func() bool {
+		return n.count(func(p *epaxos) bool {
+			return p.hasExecuted(cc.is.ReplicaID, cc.is.InstanceNum)
+		}) >= 3
+	}) {
+		t.Fatalf("reconfiguration command never executed")
+	}
+	for r := pb.ReplicaID(0); r < 3; r++ {
+		p := n.peers[r]
+		if a, e := p.nodes, nodes; !reflect.DeepEqual(a, e) {
+			t.Errorf("peer %d: expected nodes %v, found %v", r, e, a)
+		}
+		if a, e := p.epoch, uint64(1); a != e {
+			t.Errorf("peer %d: expected epoch %d, found %d", r, e, a)
+		}
+	}
+
+	// A command proposed in the new configuration is executed on all
+	// replicas, including the new one, which has to learn about the
+	// reconfiguration command first.
+	inst := n.peers[1].onRequest(newTestingCommand("a", "z"))
+	if !n.runNetworkFor(2*recoveryTicks, func() bool {
+		return n.allHave(func(p *epaxos) bool {
+			return p.hasExecuted(inst.is.ReplicaID, inst.is.InstanceNum)
+		})
+	}) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+	if a, e := n.peers[3].epoch, uint64(1); a != e {
+		t.Errorf("expected epoch %d on new replica, found %d", e, a)
+	}
+}
+
+// TestRemoveNode verifies that a replica can be removed from the EPaxos
+// network, after which the remaining replicas make progress without it.
+func TestRemoveNode(t *testing.T) {
+	n := newNetwork(3)
+
+	cc := n.peers[0].onRequest(newConfChangeCommand(pb.ConfChange_RemoveNode, 2))
+	if !n.waitExecuteInstance(cc, false /* quorum */) {
+		t.Fatalf("reconfiguration command never executed")
+	}
+	n.crash(2)
+	for r := pb.ReplicaID(0); r < 2; r++ {
+		if a, e := n.peers[r].nodes, []pb.ReplicaID{0, 1}; !reflect.DeepEqual(a, e) {
+			t.Errorf("peer %d: expected nodes %v, found %v", r, e, a)
+		}
+	}
+
+	inst := n.peers[1].onRequest(newTestingCommand("a", "z"))
+	if !n.runNetworkFor(recoveryTicks, func() bool {
+		return n.allAliveHave(func(p *epaxos) bool {
+			return p.hasExecuted(inst.is.ReplicaID, inst.is.InstanceNum)
+		})
+	}) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+}
@@ -0,0 +1,275 @@
+package epaxos
+
+import (
+	"sort"
+
+	"github.com/cockroachdb/cockroach/pkg/util/interval"
+	"github.com/google/btree"
+
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+// conflictIndex indexes the instances in a replica's command space by the
+// spans of their commands, like the conflict map of the EPaxos paper. It
+// allows the instances that may interfere with a command to be found without
+// considering every instance in the command space.
+//
+// Each span that is accessed by an indexed command has a single entry. Only
+// the latest instance that writes the span, and the instances that read it
+// since, are returned for the entry. The latest write interferes with all
+// earlier instances that access the span, so it depends on them directly or
+// through its own dependencies, and a command never needs to depend on them
+// itself. Its sequence number was also computed from theirs, the same way
+// that a scan of the command space stops at the first write that encloses a
+// command. Earlier instances are still kept in the entry, so that they are
+// returned again if the latest write's command changes, for instance when it
+// is recovered as a no-op.
+//
+// Reconfiguration commands interfere with all commands, so they are not
+// indexed by span.
+type conflictIndex struct {
+	spans interval.Tree
+	// entries holds the entry of each span in spans.
+	entries map[conflictKey]*conflictEntry
+	// confChanges holds the instances with reconfiguration commands.
+	confChanges map[*instance]struct{}
+	// accesses holds the entries of each indexed instance, so that it can be
+	// removed when its command changes.
+	accesses map[*instance][]conflictAccess
+	// nextID is the ID of the next entry added to the index.
+	nextID uintptr
+}
+
+// conflictKey identifies the span of a conflictEntry.
+type conflictKey struct {
+	start, end string
+}
+
+// conflictEntry is the entry of a single span in a conflictIndex.
+type conflictEntry struct {
+	key conflictKey
+	rng interval.Range
+	id  uintptr
+	// writes and reads hold the instances whose commands write and read the
+	// span, ordered by instance number.
+	writes *btree.BTree
+	reads  *btree.BTree
+}
+
+// conflictAccess is an access of an indexed instance's command to the span of
+// an entry.
+type conflictAccess struct {
+	entry   *conflictEntry
+	writing bool
+}
+
+// Range implements the interval.Interface interface.
+func (ce *conflictEntry) Range() interval.Range { return ce.rng }
+
+// ID implements the interval.Interface interface.
+func (ce *conflictEntry) ID() uintptr { return ce.id }
+
+func (ce *conflictEntry) tree(writing bool) *btree.BTree {
+	if writing {
+		return ce.writes
+	}
+	return ce.reads
+}
+
+// latestWrite returns the instance with the largest instance number that
+// writes the span, other than the ignored instance, or nil if there is none.
+func (ce *conflictEntry) latestWrite(ignored pb.InstanceID) *instance {
+	var latest *instance
+	ce.writes.Descend(func(i btree.Item) bool {
+		inst := i.(*instance)
+		if inst.is.InstanceID == ignored {
+			return true
+		}
+		latest = inst
+		return false
+	})
+	return latest
+}
+
+func newConflictIndex() *conflictIndex {
+	return &conflictIndex{
+		spans:       interval.NewTree(interval.ExclusiveOverlapper),
+		entries:     make(map[conflictKey]*conflictEntry),
+		confChanges: make(map[*instance]struct{}),
+		accesses:    make(map[*instance][]conflictAccess),
+	}
+}
+
+// entry returns the entry of the span, creating it if it does not exist.
+func (ci *conflictIndex) entry(span pb.Span) (*conflictEntry, error) {
+	rng := rangeForSpan(span)
+	key := conflictKey{start: string(rng.Start), end: string(rng.End)}
+	if ce, ok := ci.entries[key]; ok {
+		return ce, nil
+	}
+	ce := &conflictEntry{
+		key:    key,
+		rng:    rng,
+		id:     ci.nextID,
+		writes: btree.New(32 /* degree */),
+		reads:  btree.New(32 /* degree */),
+	}
+	ci.nextID++
+	if err := ci.spans.Insert(ce, false /* fast */); err != nil {
+		return nil, err
+	}
+	ci.entries[key] = ce
+	return ce, nil
+}
+
+// add indexes the instance by its command. Instances without a command are
+// not indexed.
+func (ci *conflictIndex) add(inst *instance) error {
+	cmd := inst.is.Command
+	switch {
+	case cmd == nil:
+		return nil
+	case cmd.IsConfChange():
+		ci.confChanges[inst] = struct{}{}
+		return nil
+	}
+	accesses := cmd.Accesses()
+	cas := make([]conflictAccess, 0, len(accesses))
+	for _, a := range accesses {
+		ce, err := ci.entry(a.Span)
+		if err != nil {
+			return err
+		}
+		ce.tree(a.Writing).ReplaceOrInsert(inst)
+		cas = append(cas, conflictAccess{entry: ce, writing: a.Writing})
+	}
+	ci.accesses[inst] = cas
+	return nil
+}
+
+// remove removes the instance from the index.
+func (ci *conflictIndex) remove(inst *instance) error {
+	delete(ci.confChanges, inst)
+	for _, ca := range ci.accesses[inst] {
+		ce := ca.entry
+		ce.tree(ca.writing).Delete(inst)
+		if ce.writes.Len() > 0 || ce.reads.Len() > 0 {
+			continue
+		}
+		if _, ok := ci.entries[ce.key]; !ok {
+			// Already removed for an earlier access to the same span.
+			continue
+		}
+		if err := ci.spans.Delete(ce, false /* fast */); err != nil {
+			return err
+		}
+		delete(ci.entries, ce.key)
+	}
+	delete(ci.accesses, inst)
+	return nil
+}
+
+// overlapping returns the instances whose commands may interfere with the
+// command, sorted in descending order of instance number. These include all
+// instances with reconfiguration commands. The ignored instance is not
+// returned, and does not hide the instances that it would otherwise
+// supersede.
+func (ci *conflictIndex) overlapping(cmd *pb.Command, ignored pb.InstanceID) []*instance {
+	seen := make(map[*instance]struct{}, len(ci.confChanges))
+	var insts []*instance
+	addInst := func(inst *instance) {
+		if _, ok := seen[inst]; !ok {
+			seen[inst] = struct{}{}
+			insts = append(insts, inst)
+		}
+	}
+	for inst := range ci.confChanges {
+		addInst(inst)
+	}
+
+	for _, a := range cmd.Accesses() {
+		writing := a.Writing
+		ci.spans.DoMatching(func(e interval.Interface) bool {
+			ce := e.(*conflictEntry)
+			latest := ce.latestWrite(ignored)
+			if latest != nil {
+				addInst(latest)
+			}
+			if writing {
+				ce.reads.Descend(func(i btree.Item) bool {
+					read := i.(*instance)
+					if latest != nil && read.is.InstanceNum < latest.is.InstanceNum {
+						return false
+					}
+					if read.is.InstanceID != ignored {
+						addInst(read)
+					}
+					return true
+				})
+			}
+			return false
+		}, rangeForSpan(a.Span))
+	}
+
+	sort.Slice(insts, func(i, j int) bool {
+		return insts[i].is.InstanceNum > insts[j].is.InstanceNum
+	})
+	return insts
+}
+
+// writes returns all instances whose commands write a span that overlaps the
+// span, and all instances with reconfiguration commands, sorted in descending
+// order of instance number. Unlike overlapping, it does not stop at the latest
+// write of each span.
+func (ci *conflictIndex) writes(span pb.Span) []*instance {
+	seen := make(map[*instance]struct{}, len(ci.confChanges))
+	var insts []*instance
+	addInst := func(inst *instance) {
+		if _, ok := seen[inst]; !ok {
+			seen[inst] = struct{}{}
+			insts = append(insts, inst)
+		}
+	}
+	for inst := range ci.confChanges {
+		addInst(inst)
+	}
+	ci.spans.DoMatching(func(e interval.Interface) bool {
+		e.(*conflictEntry).writes.Ascend(func(i btree.Item) bool {
+			addInst(i.(*instance))
+			return true
+		})
+		return false
+	}, rangeForSpan(span))
+
+	sort.Slice(insts, func(i, j int) bool {
+		return insts[i].is.InstanceNum > insts[j].is.InstanceNum
+	})
+	return insts
+}
+
+// setCommand sets the instance's command and updates the conflict index of
+// its replica.
+func (inst *instance) setCommand(cmd *pb.Command) {
+	ci := inst.p.conflicts[inst.is.ReplicaID]
+	if err := ci.remove(inst); err != nil {
+		inst.p.logger.Panicf("removing instance %v from conflict index: %v", inst.is.InstanceID, err)
+	}
+	inst.is.Command = cmd
+	if err := ci.add(inst); err != nil {
+		inst.p.logger.Panicf("adding instance %v to conflict index: %v", inst.is.InstanceID, err)
+	}
+}
+
+// setInstanceData sets the instance's data, updating the conflict index of its
+// replica with its command.
+func (inst *instance) setInstanceData(data pb.InstanceData) {
+	inst.setCommand(data.Command)
+	inst.is.InstanceData = data
+}
+
+// unindex removes the instance from the conflict index of its replica.
+func (inst *instance) unindex() {
+	if err := inst.p.conflicts[inst.is.ReplicaID].remove(inst); err != nil {
+		inst.p.logger.Panicf("removing instance %v from conflict index: %v", inst.is.InstanceID, err)
+	}
+}
@@ -0,0 +1,160 @@
+package epaxos
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+// randTestingCommand creates a random command over a small keyspace. Batches,
+// multi-span commands and reconfiguration commands are created occasionally.
+func randTestingCommand(rng *rand.Rand) *pb.Command {
+	switch rng.Intn(20) {
+	case 0:
+		return newConfChangeCommand(pb.ConfChange_AddNode, 3)
+	case 1, 2:
+		batch := make([]pb.Command, 2+rng.Intn(3))
+		for i := range batch {
+			batch[i] = *randTestingSingleCommand(rng)
+		}
+		return &pb.Command{ID: rng.Uint64(), Batch: batch}
+	case 3, 4:
+		multi := &pb.Command{ID: rng.Uint64()}
+		for i := 1 + rng.Intn(4); i > 0; i-- {
+			cmd := randTestingSingleCommand(rng)
+			if cmd.Writing {
+				multi.WriteSpans = append(multi.WriteSpans, cmd.Span)
+			} else {
+				multi.ReadSpans = append(multi.ReadSpans, cmd.Span)
+			}
+		}
+		return multi
+	default:
+		return randTestingSingleCommand(rng)
+	}
+}
+
+func randTestingSingleCommand(rng *rand.Rand) *pb.Command {
+	const keys = "abcdefghijklmnopqrstuvwxyz"
+	start := rng.Intn(len(keys))
+	var cmd *pb.Command
+	if rng.Intn(2) == 0 {
+		cmd = newTestingCommand(keys[start:start+1], "")
+	} else {
+		end := start + 1 + rng.Intn(len(keys)-start)
+		endKey := "{"
+		if end < len(keys) {
+			endKey = keys[end : end+1]
+		}
+		cmd = newTestingCommand(keys[start:start+1], endKey)
+	}
+	cmd.Writing = rng.Intn(2) == 0
+	return cmd
+}
+
+// TestConflictIndex verifies that the dependencies and sequence numbers found
+// with the conflict index match those found by considering every instance, as
+// instances are added, change their commands, and are truncated. Each command
+// is given the sequence number that a command leader would give it, because
+// the index relies on the latest write of a span having a larger sequence
+// number than the earlier instances that access it.
+func TestConflictIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(0))
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+	setCommand := func(inst *instance, cmd *pb.Command) {
+		inst.setCommand(cmd)
+		if cmd != nil {
+			seq, _ := p.seqAndDeps(cmd, inst.is.InstanceID, false /* useIndex */)
+			inst.is.SeqNum = seq + 1
+		}
+	}
+
+	for step := 0; step < 2000; step++ {
+		r := pb.ReplicaID(rng.Intn(3))
+		switch op := rng.Intn(10); {
+		case op < 6:
+			inst := p.newInstance(r, p.maxInstanceNum(r)+1)
+			setCommand(inst, randTestingCommand(rng))
+			p.commands[r].ReplaceOrInsert(inst)
+		case op < 9:
+			if inst := p.maxInstance(r); inst != nil {
+				if rng.Intn(4) == 0 {
+					// Recovery may commit an instance as a no-op.
+					setCommand(inst, nil)
+				} else {
+					setCommand(inst, randTestingCommand(rng))
+				}
+			}
+		default:
+			if inst := p.maxInstance(r); inst != nil {
+				if upTo := inst.is.InstanceNum / 2; upTo > p.maxTruncatedInstanceNum[r] {
+					p.truncateInstances(r, upTo)
+				}
+			}
+		}
+
+		cmd := randTestingCommand(rng)
+		if cmd.IsConfChange() {
+			continue
+		}
+		// The instance whose attributes are being updated is ignored.
+		var ignored pb.InstanceID
+		if inst := p.maxInstance(r); inst != nil && rng.Intn(2) == 0 {
+			ignored = inst.is.InstanceID
+		}
+		seq, deps := p.seqAndDeps(cmd, ignored, true /* useIndex */)
+		expSeq, expDeps := p.seqAndDeps(cmd, ignored, false /* useIndex */)
+		if seq != expSeq {
+			t.Fatalf("step %d: expected sequence number %v for %v, found %v", step, expSeq, cmd, seq)
+		}
+		if !reflect.DeepEqual(deps, expDeps) {
+			t.Fatalf("step %d: expected dependencies %v for %v, found %v", step, expDeps, cmd, deps)
+		}
+	}
+}
+
+// benchmarkSeqAndDeps benchmarks determining the sequence number and
+// dependencies of a write to a single key, while each replica's command space
+// holds instances that write to random keys out of the given number of keys.
+// With a single key, every instance writes the same hot key.
+func benchmarkSeqAndDeps(b *testing.B, instances, keys int, useIndex bool) {
+	rng := rand.New(rand.NewSource(0))
+	randKey := func() string { return fmt.Sprintf("%08d", rng.Intn(keys)) }
+
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+	for r := range p.commands {
+		for i := 1; i <= instances; i++ {
+			inst := p.newInstance(r, pb.InstanceNum(i))
+			inst.setCommand(newTestingCommand(randKey(), ""))
+			p.commands[r].ReplaceOrInsert(inst)
+		}
+	}
+	cmds := make([]*pb.Command, 1024)
+	for i := range cmds {
+		cmds[i] = newTestingCommand(randKey(), "")
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.seqAndDeps(cmds[i%len(cmds)], pb.InstanceID{}, useIndex)
+	}
+}
+
+func BenchmarkSeqAndDeps(b *testing.B) {
+	for _, instances := range []int{1000, 10000} {
+		for _, keys := range []int{100000, 1} {
+			for _, useIndex := range []bool{false, true} {
+				name := "scan"
+				if useIndex {
+					name = "index"
+				}
+				b.Run(fmt.Sprintf("instances=%d/keys=%d/%s", instances, keys, name), func(b *testing.B) {
+					benchmarkSeqAndDeps(b, instances, keys, useIndex)
+				})
+			}
+		}
+	}
+}
@@ -2,7 +2,7 @@ package epaxos
 
 import (
 	"math/rand"
-	"reflect"
+	"sort"
 	"time"
 
 	"github.com/cockroachdb/cockroach/pkg/util/interval"
@@ -16,37 +16,116 @@ import (
 type Config struct {
 	// ID is the identity of the local epaxos.
 	ID pb.ReplicaID
-	// Nodes is the set of all nodes in the epaxos network.
+	// Nodes is the set of all nodes in the epaxos network when it is first
+	// started. When restarting, the set of nodes is read out of storage
+	// instead, because it may have been changed by reconfiguration commands.
 	Nodes []pb.ReplicaID
 	// Storage is the persistent storage for epaxos. epaxos reads out
 	// the previous instance state and configuration from storage when
-	// restarting.
+	// restarting. All state that needs to be persisted to it is returned in
+	// Ready, and it is up to the user of epaxos to persist it.
 	Storage Storage
+	// Quorum determines the size of the quorums used in each phase of the
+	// EPaxos protocol. If not set, ClassicQuorum will be used.
+	Quorum Quorum
+	// Thrifty, if true, makes the replica send PreAccept and Accept messages
+	// for the commands that it leads only to as many replicas as it needs
+	// replies from, instead of to all other replicas. If they do not all reply
+	// in time, the message is sent to the remaining replicas. This saves
+	// bandwidth and CPU time on replicas whose replies are not needed.
+	Thrifty bool
+
+	// The following timeouts are all in units of ticks. If a timeout is not
+	// set, a default that is suitable for a network where a message round
+	// trip takes less than a tick is used.
+	//
+	// SlowPathTimeout is the number of ticks that a command leader waits for
+	// a fast path quorum after it receives enough replies to take the slow
+	// path.
+	SlowPathTimeout int
+	// RecoveryTimeout is the number of ticks that a replica waits for an
+	// instance led by another replica to commit before it attempts to recover
+	// the instance. It is also the number of ticks that a recovering replica
+	// waits for a Prepare quorum before retrying with a larger ballot. The
+	// actual timeout is jittered by up to half of its value.
+	RecoveryTimeout int
+	// RetransmitTimeout is the number of ticks that a thrifty command leader
+	// waits for replies to a PreAccept or Accept message before it sends the
+	// message to all other replicas.
+	RetransmitTimeout int
+	// ExecutorTimeout is the number of ticks between runs of the executor.
+	// Committed instances are executed in batches on each run, instead of
+	// while processing the messages that commit them.
+	ExecutorTimeout int
+	// TruncateTimeout is the number of ticks between attempts to truncate
+	// executed instances.
+	TruncateTimeout int
+	// SnapshotTimeout is the minimum number of ticks between two snapshots
+	// sent to the same replica.
+	SnapshotTimeout int
+
 	// Logger is the logger that the epaxos state machine will use
 	// to log events. If not set, a default logger will be used.
 	Logger Logger
+	// Metrics records measurements of the events in the EPaxos protocol. If
+	// not set, measurements are discarded.
+	Metrics Metrics
+	// Observer is notified of the events in the lifecycle of instances. If not
+	// set, events are ignored.
+	Observer Observer
 	// RandSeed allows the seed used by epaxos's rand.Source to be
 	// injected, to allow for fully deterministic execution.
 	RandSeed int64
 }
 
 func (c *Config) validate() error {
-	if !inReplicaSlice(c.ID, c.Nodes) {
-		return errors.Errorf("ID not in Nodes slice")
-	}
 	if c.Storage == nil {
 		c.Storage = NewMemoryStorage(c)
-	} else if hs, ok := c.Storage.HardState(); ok {
+	} else if hs, ok, err := c.Storage.HardState(); err != nil {
+		return errors.Wrap(err, "loading HardState")
+	} else if ok {
 		if hs.ReplicaID != c.ID {
 			return errors.Errorf("ID different than in HardState")
 		}
-		if !reflect.DeepEqual(hs.Nodes, c.Nodes) {
-			return errors.Errorf("Node set different than in HardState")
+		c.Nodes = hs.Nodes
+	}
+	if !inReplicaSlice(c.ID, c.Nodes) {
+		return errors.Errorf("ID not in Nodes slice")
+	}
+	if c.Quorum == nil {
+		c.Quorum = ClassicQuorum{}
+	}
+	if err := c.Quorum.Validate(len(c.Nodes)); err != nil {
+		return err
+	}
+	for _, t := range []struct {
+		name    string
+		timeout *int
+		def     int
+	}{
+		{"SlowPathTimeout", &c.SlowPathTimeout, defaultSlowPathTimeout},
+		{"RecoveryTimeout", &c.RecoveryTimeout, defaultRecoveryTimeout},
+		{"RetransmitTimeout", &c.RetransmitTimeout, defaultRetransmitTimeout},
+		{"ExecutorTimeout", &c.ExecutorTimeout, defaultExecutorTimeout},
+		{"TruncateTimeout", &c.TruncateTimeout, defaultTruncateTimeout},
+		{"SnapshotTimeout", &c.SnapshotTimeout, defaultSnapshotTimeout},
+	} {
+		if *t.timeout < 0 {
+			return errors.Errorf("negative %s %d", t.name, *t.timeout)
+		}
+		if *t.timeout == 0 {
+			*t.timeout = t.def
 		}
 	}
 	if c.Logger == nil {
 		c.Logger = NewDefaultLogger()
 	}
+	if c.Metrics == nil {
+		c.Metrics = nopMetrics{}
+	}
+	if c.Observer == nil {
+		c.Observer = nopObserver{}
+	}
 	if c.RandSeed == 0 {
 		c.RandSeed = time.Now().UnixNano()
 	}
@@ -61,18 +140,47 @@ type epaxos struct {
 	id pb.ReplicaID
 	// nodes is the set of all nodes in the EPaxos network.
 	nodes []pb.ReplicaID
+	// epoch is the configuration epoch of the EPaxos network. Ballots from
+	// earlier epochs are rejected.
+	epoch uint64
+	// confChangeInstance is the instance of the last executed ConfChange.
+	confChangeInstance pb.InstanceID
+	// configurations holds the set of nodes of each earlier epoch. Instances
+	// whose ballots are from an earlier epoch are decided by quorums of the
+	// nodes of that epoch, so they must also be recovered with them.
+	configurations map[uint64][]pb.ReplicaID
 	// storage is a handle to the node's persistent storage.
 	storage Storage
+	// quorums determines the size of the quorums used in each phase.
+	quorums Quorum
+	// thrifty determines whether the replica only sends PreAccept and Accept
+	// messages to the replicas that it needs replies from.
+	thrifty bool
+
+	// timeouts of the replica's timers, in ticks. See Config.
+	slowPathTimeout   int
+	recoveryTimeout   int
+	retransmitTimeout int
+	executorTimeout   int
+	truncateTimeout   int
+	snapshotTimeout   int
 
 	// commands is a map from replica to an ordered tree of instance, indexed by
-	// sequence number. BTree contains *instance elements.
+	// sequence number. BTree contains *instance elements. The instance spaces
+	// of replicas that have been removed from the EPaxos network are kept.
 	commands map[pb.ReplicaID]*btree.BTree
-	// TODO reintroduce instance space truncation.
 	// maxTruncatedInstanceNum is a mapping from replica to the maximum instance
 	// number that has been truncated up to in its command space.
-	// maxTruncatedInstanceNum map[pb.ReplicaID]pb.InstanceNum
+	maxTruncatedInstanceNum map[pb.ReplicaID]pb.InstanceNum
 	// maxTruncatedSeqNum is the maximum sequence number that has been truncated.
-	// maxTruncatedSeqNum pb.SeqNum
+	maxTruncatedSeqNum pb.SeqNum
+	// truncationCandidates is a mapping from replica to the maximum instance
+	// number that was found to be executed in its command space on the last
+	// run of truncateCommands. These instances are truncated on the next run.
+	truncationCandidates map[pb.ReplicaID]pb.InstanceNum
+	// conflicts is a map from replica to the conflict index of its command
+	// space.
+	conflicts map[pb.ReplicaID]*conflictIndex
 	// rangeGroup is used to minimize dependency lists by tracking transitive
 	// dependencies.
 	rangeGroup interval.RangeGroup
@@ -80,6 +188,9 @@ type epaxos struct {
 	// executor holds execution state and handles the execution of committed
 	// instances.
 	executor executor
+	// reads holds the linearizable reads that the replica is performing,
+	// indexed by the IDs of their commands.
+	reads map[uint64]*read
 	// timers holds all current timers, which are each incremented on every call
 	// to Tick.
 	timers map[*tickingTimer]struct{}
@@ -87,80 +198,283 @@ type epaxos struct {
 	// msgs is the outbox for the paxos node, containing all messages that need
 	// to be delivered.
 	msgs []pb.Message
-	// // committedCmds is the outbox for commands that have been committed and
-	// // can be acknowledged to clients. The commands have not necessarily been
-	// // executed yet, though, so they should not be run on the state machine.
-	// committedCmds []pb.Command
+	// committedCmds is the outbox for commands that have been committed and
+	// can be acknowledged to clients. The commands have not necessarily been
+	// executed yet, though, so they should not be run on the state machine.
+	committedCmds []CommittedCommand
+	// unstableInsts is the outbox for instances whose state has changed and
+	// needs to be persisted before any messages in msgs are sent.
+	unstableInsts []*instance
+	// unstableHardState is set if the HardState has changed and needs to be
+	// persisted before any messages in msgs are sent.
+	unstableHardState bool
+	// truncatedReplicas holds the replicas whose truncation indexes have
+	// advanced since the HardState was last handed out to be persisted. Their
+	// truncated instances are handed out along with the HardState to be
+	// removed from storage.
+	truncatedReplicas map[pb.ReplicaID]struct{}
 	// executedCmds is the outbox for commands that are ready to be executed,
 	// in-order.
 	executedCmds []pb.Command
+	// unappliedInsts holds the instances whose commands are in executedCmds.
+	unappliedInsts []*instance
+	// failedCmds is the outbox for proposed commands and reads that will never
+	// be executed, along with the reason.
+	failedCmds []failedCommand
+	// snapshot is a snapshot of another replica's state machine that needs to
+	// be applied to the local state machine before any executed commands.
+	snapshot *pb.Snapshot
+	// snapshotsTo is the outbox for replicas that have fallen behind the local
+	// truncation indexes and need to be sent a snapshot.
+	snapshotsTo []pb.ReplicaID
+	// snapshotTimers throttle the snapshots sent to each replica.
+	snapshotTimers map[pb.ReplicaID]*tickingTimer
 
 	// logger is used by paxos to log event.
 	logger Logger
+	// metrics records measurements of protocol events.
+	metrics Metrics
+	// observer is notified of the events in the lifecycle of instances.
+	observer Observer
+	// ticks is the number of times that Tick has been called. It is used to
+	// measure latencies.
+	ticks int
 	// rand holds the paxos instance's local Rand object. This allows us to avoid
 	// using the synchronized global Rand object.
 	rand *rand.Rand
 }
 
+// newEPaxos creates a new epaxos state machine. It panics if the Config is
+// invalid or if the state of the node can not be read out of storage.
 func newEPaxos(c *Config) *epaxos {
-	if err := c.validate(); err != nil {
+	p, err := startEPaxos(c)
+	if err != nil {
 		panic(err.Error())
 	}
+	return p
+}
+
+// startEPaxos creates a new epaxos state machine, returning an error if the
+// Config is invalid or if the state of the node can not be read out of
+// storage.
+func startEPaxos(c *Config) (*epaxos, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
 	p := &epaxos{
-		id:         c.ID,
-		nodes:      c.Nodes,
+		id:      c.ID,
+		nodes:   c.Nodes,
+		quorums: c.Quorum,
+
+		configurations: make(map[uint64][]pb.ReplicaID),
+
+		thrifty:    c.Thrifty,
 		logger:     c.Logger,
+		metrics:    c.Metrics,
+		observer:   c.Observer,
 		commands:   make(map[pb.ReplicaID]*btree.BTree, len(c.Nodes)),
+		conflicts:  make(map[pb.ReplicaID]*conflictIndex, len(c.Nodes)),
 		rangeGroup: interval.NewRangeTree(),
 		timers:     make(map[*tickingTimer]struct{}),
+		reads:      make(map[uint64]*read),
 		rand:       rand.New(rand.NewSource(c.RandSeed)),
+
+		maxTruncatedInstanceNum: make(map[pb.ReplicaID]pb.InstanceNum, len(c.Nodes)),
+		truncationCandidates:    make(map[pb.ReplicaID]pb.InstanceNum, len(c.Nodes)),
+		truncatedReplicas:       make(map[pb.ReplicaID]struct{}),
+		snapshotTimers:          make(map[pb.ReplicaID]*tickingTimer),
+
+		slowPathTimeout:   c.SlowPathTimeout,
+		recoveryTimeout:   c.RecoveryTimeout,
+		retransmitTimeout: c.RetransmitTimeout,
+		executorTimeout:   c.ExecutorTimeout,
+		truncateTimeout:   c.TruncateTimeout,
+		snapshotTimeout:   c.SnapshotTimeout,
 	}
 	p.executor = makeExecutor(p)
 	for _, rep := range c.Nodes {
-		p.commands[rep] = btree.New(32 /* degree */)
+		p.addInstanceSpace(rep)
+	}
+	if err := p.initStorage(c); err != nil {
+		return nil, err
 	}
-	p.initStorage(c)
 	p.initTimers()
-	return p
+	return p, nil
 }
 
-func (p *epaxos) initStorage(c *Config) {
+func (p *epaxos) initStorage(c *Config) error {
 	s := c.Storage
 	p.storage = s
 
 	// Set up the node's HardState.
-	if _, ok := s.HardState(); !ok {
-		s.PersistHardState(pb.HardState{
-			ReplicaID: c.ID,
-			Nodes:     c.Nodes,
-		})
+	hs, ok, err := s.HardState()
+	if err != nil {
+		return errors.Wrap(err, "loading HardState")
+	}
+	if ok {
+		for r, i := range hs.TruncatedInstanceNums {
+			p.maxTruncatedInstanceNum[r] = i
+		}
+		p.maxTruncatedSeqNum = hs.TruncatedSeqNum
+		p.epoch = hs.Epoch
+		p.confChangeInstance = hs.ConfChangeInstance
+		p.addConfigurations(hs.Configurations)
+	} else {
+		p.persistHardState()
 	}
 
-	// Load all persisted instances.
-	insts := s.Instances()
+	// Load all persisted instances. Instances that were truncated but not
+	// yet removed from storage before a restart are removed now.
+	insts, err := s.Instances()
+	if err != nil {
+		return errors.Wrap(err, "loading instances")
+	}
+	loaded := make([]*instance, 0, len(insts))
 	for _, is := range insts {
+		if p.hasTruncated(is.ReplicaID, is.InstanceNum) {
+			continue
+		}
+		p.addInstanceSpace(is.ReplicaID)
 		inst := p.newInstanceFromState(is)
 		p.commands[is.ReplicaID].ReplaceOrInsert(inst)
-		cmdLeader := is.ReplicaID == p.id
-		if cmdLeader && !inst.isStates(pb.InstanceState_Executed) {
+		loaded = append(loaded, inst)
+	}
+
+	// Restart all instances that have not yet executed. This is performed
+	// after all instances have been loaded so that dependencies between them
+	// can be resolved.
+	for _, inst := range loaded {
+		cmdLeader := inst.is.ReplicaID == p.id && inst.isLeader()
+		switch {
+		case inst.isStates(pb.InstanceState_Executed):
+		case cmdLeader:
+			// The instance's attributes may have been updated by PreAccept
+			// replies before the restart, so resending them at the same
+			// ballot must not take the fast path. See fastPathCandidate.
+			inst.differentReplies = true
 			inst.restartTransition()
+		case inst.isStates(pb.InstanceState_Committed):
+			inst.prepareToExecute()
+		default:
+			inst.resetRecoveryTimer()
+		}
+	}
+	for r, i := range p.maxTruncatedInstanceNum {
+		if err := s.TruncateInstances(r, i); err != nil {
+			return errors.Wrap(err, "removing truncated instances")
 		}
 	}
+	return nil
+}
+
+// persistHardState marks the node's current HardState as needing to be
+// persisted.
+func (p *epaxos) persistHardState() {
+	p.unstableHardState = true
+}
+
+// hardState returns the node's current HardState.
+func (p *epaxos) hardState() pb.HardState {
+	truncated := make(map[pb.ReplicaID]pb.InstanceNum, len(p.maxTruncatedInstanceNum))
+	for r, i := range p.maxTruncatedInstanceNum {
+		truncated[r] = i
+	}
+	return pb.HardState{
+		ReplicaID:             p.id,
+		Nodes:                 p.nodes,
+		TruncatedInstanceNums: truncated,
+		TruncatedSeqNum:       p.maxTruncatedSeqNum,
+		Epoch:                 p.epoch,
+		ConfChangeInstance:    p.confChangeInstance,
+		Configurations:        p.configurationList(),
+	}
+}
+
+// persistInstance marks the instance's state as needing to be persisted.
+func (p *epaxos) persistInstance(inst *instance) {
+	if !inst.unstable {
+		inst.unstable = true
+		p.unstableInsts = append(p.unstableInsts, inst)
+	}
+}
+
+// truncateStorage hands out the replica's truncated instances to be removed
+// from storage, along with the HardState recording their truncation.
+func (p *epaxos) truncateStorage(r pb.ReplicaID) {
+	p.persistHardState()
+	p.truncatedReplicas[r] = struct{}{}
 }
 
+// truncatedInstances returns the truncation index of each replica whose
+// truncated instances need to be removed from storage.
+func (p *epaxos) truncatedInstances() map[pb.ReplicaID]pb.InstanceNum {
+	if len(p.truncatedReplicas) == 0 {
+		return nil
+	}
+	truncated := make(map[pb.ReplicaID]pb.InstanceNum, len(p.truncatedReplicas))
+	for r := range p.truncatedReplicas {
+		truncated[r] = p.maxTruncatedInstanceNum[r]
+	}
+	return truncated
+}
+
+// unstableInstanceStates returns the states of all instances that need to be
+// persisted. Instances that have since been truncated are skipped.
+func (p *epaxos) unstableInstanceStates() []pb.InstanceState {
+	if len(p.unstableInsts) == 0 {
+		return nil
+	}
+	states := make([]pb.InstanceState, 0, len(p.unstableInsts))
+	for _, inst := range p.unstableInsts {
+		if p.hasTruncated(inst.is.ReplicaID, inst.is.InstanceNum) {
+			continue
+		}
+		states = append(states, inst.is)
+	}
+	return states
+}
+
+// clearUnstable is called once all unstable state has been handed out to be
+// persisted.
+func (p *epaxos) clearUnstable() {
+	for _, inst := range p.unstableInsts {
+		inst.unstable = false
+	}
+	p.unstableInsts = nil
+	p.unstableHardState = false
+	for r := range p.truncatedReplicas {
+		delete(p.truncatedReplicas, r)
+	}
+}
+
+const (
+	// defaultExecutorTimeout is the default number of ticks between each run
+	// of the executor.
+	defaultExecutorTimeout = 1
+	// defaultTruncateTimeout is the default number of ticks between each
+	// attempt to truncate executed instances from the command spaces.
+	defaultTruncateTimeout = 100
+)
+
 // initTimers initializes all static timers for the epaxos state machine.
-// TODO allow injecting timeouts.
 func (p *epaxos) initTimers() {
-	// // The executorTimer runs the executor, attempting to execute any
-	// // committed instances.
-	// const executorTimeout = 10
-	// executorTimer := makeTickingTimer(executorTimeout, func() {
-	// 	p.executor.run()
-	// })
-	// p.registerInfiniteTimer(&executorTimer)
+	// The executorTimer runs the executor, attempting to execute any
+	// committed instances.
+	executorTimer := makeTickingTimer(p.executorTimeout, func() {
+		p.executor.run()
+		p.maybeDeliverReads()
+	})
+	p.registerInfiniteTimer(&executorTimer)
+
+	// The truncateTimer truncates executed instances from the command spaces.
+	truncateTimer := makeTickingTimer(p.truncateTimeout, func() {
+		p.truncateCommands()
+	})
+	p.registerInfiniteTimer(&truncateTimer)
 }
 
 func (p *epaxos) Tick() {
+	p.ticks++
 	for t := range p.timers {
 		t.tick()
 	}
@@ -171,6 +485,7 @@ func (p *epaxos) registerInfiniteTimer(t *tickingTimer) {
 	t.instrument(func() {
 		t.reset()
 	})
+	t.reset()
 }
 
 func (p *epaxos) registerOneTimeTimer(t *tickingTimer) {
@@ -190,22 +505,59 @@ func (p *epaxos) Request(cmd *pb.Command) {
 	p.onRequest(cmd)
 }
 
+// RequestBatch proposes the commands in a single instance. Reconfiguration
+// commands must be proposed on their own, with Request.
+func (p *epaxos) RequestBatch(cmds []pb.Command) {
+	switch len(cmds) {
+	case 0:
+	case 1:
+		p.onRequest(&cmds[0])
+	default:
+		p.onRequest(&pb.Command{Batch: cmds})
+	}
+}
+
+// Read performs a linearizable read of the command, which must be a single
+// command that does not write. The command is returned with the executed
+// commands once the read can be served by the local state machine.
+func (p *epaxos) Read(cmd *pb.Command) {
+	p.onReadRequest(cmd)
+}
+
 func (p *epaxos) Step(m pb.Message) {
 	if ok := p.validateMessage(m); !ok {
 		p.logger.Warningf("found invalid Message: %+v", m)
 		return
 	}
+	p.metrics.MessageReceived(pb.TypeName(m.Type))
+	p.observer.MessageReceived(&m)
+
+	// Reads are not part of any instance.
+	switch t := m.Type.(type) {
+	case *pb.Message_Read:
+		p.onRead(t.Read)
+		return
+	case *pb.Message_ReadReply:
+		p.onReadReply(t.ReadReply)
+		return
+	}
 
 	r := m.InstanceID.ReplicaID
 	i := m.InstanceID.InstanceNum
 	inst := p.getInstance(r, i)
 	if inst == nil {
-		// if p.hasTruncated(r, i) {
-		// 	// We've already truncated this instance, which means that it was
-		// 	// already committed. Ignore the messsage.
-		// 	p.logger.Debugf("ignoring message to truncated instance: %+v", m)
-		// 	return
-		// }
+		if p.hasTruncated(r, i) {
+			// We've already truncated this instance, which means that it was
+			// already executed. Ignore the messsage. If its sender is still
+			// trying to decide the instance, it has fallen behind and needs
+			// a snapshot to catch up.
+			p.logger.Debugf("ignoring message to truncated instance: %+v", m)
+			switch m.Type.(type) {
+			case *pb.Message_PreAccept, *pb.Message_Accept, *pb.Message_Prepare:
+				p.maybeSendSnapshot(m.Ballot.Leader(m.InstanceID))
+			}
+			return
+		}
 		if r == p.id {
 			// We should always know about our own instances.
 			p.logger.Warningf("unknown local instance number: %+v", m)
@@ -217,6 +569,27 @@ func (p *epaxos) Step(m pb.Message) {
 		p.commands[r].ReplaceOrInsert(inst)
 	}
 
+	if pb.IsReply(m.Type) {
+		// Replies are only relevant to the ballot that they were sent for.
+		if m.Ballot != inst.is.Ballot {
+			p.logger.Debugf("ignoring reply from stale ballot %v: %+v", inst.is.Ballot, m)
+			return
+		}
+	} else {
+		// Reject requests from leaders of smaller ballots than the one that
+		// we have already promised for the instance. This includes all
+		// ballots from earlier configuration epochs.
+		if m.Ballot.Epoch < p.epoch {
+			inst.promise(pb.Ballot{Epoch: p.epoch})
+		}
+		if m.Ballot.Compare(inst.is.Ballot) < 0 {
+			p.logger.Debugf("rejecting message with stale ballot, promised %v: %+v", inst.is.Ballot, m)
+			p.nack(m, inst)
+			return
+		}
+		inst.promise(m.Ballot)
+	}
+
 	switch t := m.Type.(type) {
 	case *pb.Message_PreAccept:
 		inst.onPreAccept(t.PreAccept)
@@ -230,9 +603,21 @@ func (p *epaxos) Step(m pb.Message) {
 		inst.onAcceptOK(t.AcceptOk)
 	case *pb.Message_Commit:
 		inst.onCommit(t.Commit)
+	case *pb.Message_Prepare:
+		inst.onPrepare(t.Prepare)
+	case *pb.Message_PrepareReply:
+		inst.onPrepareReply(t.PrepareReply)
+	case *pb.Message_Nack:
+		inst.onNACK(t.Nack)
 	default:
 		p.logger.Panicf("unexpected Message type: %T", t)
 	}
+
+	// Hearing from the instance's leader means that it is still making
+	// progress, so delay any attempt to recover the instance.
+	if !pb.IsReply(m.Type) {
+		inst.maybeResetRecoveryTimer()
+	}
 }
 
 func (p *epaxos) validateMessage(m pb.Message) bool {
@@ -241,32 +626,50 @@ func (p *epaxos) validateMessage(m pb.Message) bool {
 		return false
 	}
 
+	// Reads should come from a node that we're aware of.
+	switch t := m.Type.(type) {
+	case *pb.Message_Read:
+		return p.knownReplica(t.Read.From)
+	case *pb.Message_ReadReply:
+		return p.knownReplica(t.ReadReply.From)
+	}
+
+	// The instance's replica should have an instance space that we're aware
+	// of. It may have since been removed from the EPaxos network.
+	if !p.hasInstanceSpace(m.InstanceID.ReplicaID) {
+		return false
+	}
+
+	// The leader of the message's ballot sends all requests and receives all
+	// replies for the instance.
+	leader := m.Ballot.Leader(m.InstanceID)
 	if pb.IsReply(m.Type) {
-		// The instance's replica should be us.
-		if m.InstanceID.ReplicaID != p.id {
+		// The ballot's leader should be us.
+		if leader != p.id {
 			return false
 		}
 	} else {
-		// The instance's replica should be a node that we're aware of, but not us.
-		if m.InstanceID.ReplicaID == p.id {
+		// The ballot's leader should be a node that we're aware of, but not us.
+		if leader == p.id {
 			return false
 		}
-		if !p.knownReplica(m.InstanceID.ReplicaID) {
+		if !p.knownReplica(leader) {
 			return false
 		}
 	}
 
-	// TODO ballot stuff
+	// The message's ballot is validated against the ballot that we have
+	// promised for the instance in Step.
 	return true
 }
 
-// func (p *epaxos) deliverCommittedCommand(cmd pb.Command) {
-// 	p.committedCmds = append(p.committedCmds, cmd)
-// }
+func (p *epaxos) deliverCommittedCommand(id pb.InstanceID, cmd pb.Command) {
+	p.committedCmds = append(p.committedCmds, CommittedCommand{InstanceID: id, Command: cmd})
+}
 
-// func (p *epaxos) clearCommittedCommands() {
-// 	p.committedCmds = nil
-// }
+func (p *epaxos) clearCommittedCommands() {
+	p.committedCmds = nil
+}
 
 func (p *epaxos) deliverExecutedCommand(cmd pb.Command) {
 	p.executedCmds = append(p.executedCmds, cmd)
@@ -274,6 +677,24 @@ func (p *epaxos) deliverExecutedCommand(cmd pb.Command) {
 
 func (p *epaxos) clearExecutedCommands() {
 	p.executedCmds = nil
+	for _, inst := range p.unappliedInsts {
+		inst.unapplied = false
+	}
+	p.unappliedInsts = nil
+}
+
+// failedCommand is a proposed command or read that will never be executed.
+type failedCommand struct {
+	id  uint64
+	err error
+}
+
+func (p *epaxos) deliverFailedCommand(id uint64, err error) {
+	p.failedCmds = append(p.failedCmds, failedCommand{id: id, err: err})
+}
+
+func (p *epaxos) clearFailedCommands() {
+	p.failedCmds = nil
 }
 
 func (p *epaxos) knownReplica(r pb.ReplicaID) bool {
@@ -289,18 +710,57 @@ func inReplicaSlice(r pb.ReplicaID, s []pb.ReplicaID) bool {
 	return false
 }
 
+// addConfigurations adds the sets of nodes of earlier epochs.
+func (p *epaxos) addConfigurations(confs []pb.Configuration) {
+	for _, conf := range confs {
+		if conf.Epoch < p.epoch {
+			p.configurations[conf.Epoch] = conf.Nodes
+		}
+	}
+}
+
+// configurationList returns the sets of nodes of earlier epochs, in order of
+// their epochs.
+func (p *epaxos) configurationList() []pb.Configuration {
+	confs := make([]pb.Configuration, 0, len(p.configurations))
+	for epoch, nodes := range p.configurations {
+		confs = append(confs, pb.Configuration{Epoch: epoch, Nodes: nodes})
+	}
+	sort.Slice(confs, func(i, j int) bool {
+		return confs[i].Epoch < confs[j].Epoch
+	})
+	return confs
+}
+
+// nodesAt returns the set of nodes in the configuration epoch, which size the
+// quorums of ballots from that epoch. A replica that
+// joined the EPaxos network after the epoch does not know its nodes, and falls
+// back to the current set of nodes.
+func (p *epaxos) nodesAt(epoch uint64) []pb.ReplicaID {
+	if nodes, ok := p.configurations[epoch]; ok && epoch < p.epoch {
+		return nodes
+	}
+	return p.nodes
+}
+
 func (p *epaxos) F() int {
 	// N = 2F+1
 	return (len(p.nodes)+1)/2 - 1
 }
 
-func (p *epaxos) quorum(val int) bool {
+func (p *epaxos) quorum(epoch uint64, val int) bool {
 	// floor(N/2)+1
-	return val > len(p.nodes)/2
+	return val > len(p.nodesAt(epoch))/2
+}
+
+func (p *epaxos) fastQuorum(epoch uint64, val int) bool {
+	return val >= p.quorums.FastPath(len(p.nodesAt(epoch)))
+}
+
+func (p *epaxos) slowQuorum(epoch uint64, val int) bool {
+	return val >= p.quorums.SlowPath(len(p.nodesAt(epoch)))
 }
 
-func (p *epaxos) fastQuorum(val int) bool {
-	return val >= len(p.nodes)-1
-	// // (x+y-1)/y == ceil(x/y)
-	// return val > (3*len(p.nodes)+(4-1))/4
+func (p *epaxos) prepareQuorum(epoch uint64, val int) bool {
+	return val >= p.quorums.Prepare(len(p.nodesAt(epoch)))
 }
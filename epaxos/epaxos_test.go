@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/google/btree"
 
 	pb "github.com/mjolk/epx2/epaxos/epaxospb"
@@ -30,7 +31,162 @@ func TestConfig(t *testing.T) {
 	}
 }
 
+func TestConfigTimeouts(t *testing.T) {
+	c := &Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}}
+	p := newEPaxos(c)
+	for _, tc := range []struct{ a, e int }{
+		{p.slowPathTimeout, defaultSlowPathTimeout},
+		{p.recoveryTimeout, defaultRecoveryTimeout},
+		{p.retransmitTimeout, defaultRetransmitTimeout},
+		{p.executorTimeout, defaultExecutorTimeout},
+		{p.truncateTimeout, defaultTruncateTimeout},
+		{p.snapshotTimeout, defaultSnapshotTimeout},
+	} {
+		if tc.a != tc.e {
+			t.Errorf("expected default timeout %d, found %d", tc.e, tc.a)
+		}
+	}
+
+	c = &Config{
+		ID:                0,
+		Nodes:             []pb.ReplicaID{0, 1, 2},
+		SlowPathTimeout:   7,
+		RecoveryTimeout:   50,
+		RetransmitTimeout: 9,
+	}
+	p = newEPaxos(c)
+	inst := p.newInstance(1, 1)
+	for _, tc := range []struct{ a, e int }{
+		{inst.slowPathTimer.timeout, 7},
+		{inst.recoveryTimer.timeout, 50},
+		{inst.thriftyTimer.timeout, 9},
+	} {
+		if tc.a != tc.e {
+			t.Errorf("expected timer with timeout %d, found %d", tc.e, tc.a)
+		}
+	}
+
+	c = &Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}, RecoveryTimeout: -1}
+	if err := c.validate(); err == nil {
+		t.Errorf("expected error for negative timeout")
+	}
+}
+
+// TestExecutorTimeout tests that committed instances are only executed when
+// the executor timer fires.
+func TestExecutorTimeout(t *testing.T) {
+	c := &Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}, ExecutorTimeout: 3}
+	p := newEPaxos(c)
+	inst := p.onRequest(newTestingCommand("a", "z"))
+	p.Step(pb.Message{
+		To:         0,
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+	})
+	inst.assertState(pb.InstanceState_Committed)
+
+	for i := 0; i < c.ExecutorTimeout-1; i++ {
+		p.Tick()
+	}
+	inst.assertState(pb.InstanceState_Committed)
+	p.Tick()
+	inst.assertState(pb.InstanceState_Executed)
+}
+
+// TestExecuteCommittedInstancesInBatch tests that instances committed while
+// processing messages are not executed until the next run of the executor,
+// which executes all of them at once.
+func TestExecuteCommittedInstancesInBatch(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+
+	var insts []*instance
+	for i := 0; i < 3; i++ {
+		inst := p.onRequest(newTestingCommand("a", "z"))
+		p.Step(pb.Message{
+			To:         0,
+			InstanceID: inst.is.InstanceID,
+			Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+		})
+		inst.assertState(pb.InstanceState_Committed)
+		insts = append(insts, inst)
+	}
+	c := &pb.Command{Span: pb.Span{Key: pb.Key("b")}}
+	p.Step(pb.Message{
+		To:         0,
+		InstanceID: pb.InstanceID{ReplicaID: 1, InstanceNum: 1},
+		Type:       pb.WrapMessageInner(&pb.Commit{InstanceData: pb.InstanceData{Command: c}}),
+	})
+	insts = append(insts, p.getInstance(1, 1))
+	if cmds := p.executedCmds; len(cmds) != 0 {
+		t.Fatalf("expected no executed commands before the executor runs, found %v", cmds)
+	}
+
+	p.Tick()
+	for _, inst := range insts {
+		inst.assertState(pb.InstanceState_Executed)
+	}
+	if a, e := len(p.executedCmds), len(insts); a != e {
+		t.Errorf("expected %d executed commands, found %d", e, a)
+	}
+}
+
+// TestCommittedCommands tests that commands are delivered as committed as soon
+// as they commit, even if they are not able to execute yet.
+func TestCommittedCommands(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+
+	// The command depends on an instance that the replica has not heard of,
+	// so it can not execute.
+	id := pb.InstanceID{ReplicaID: 1, InstanceNum: 2}
+	c := newTestingCommand("a", "z")
+	p.Step(pb.Message{
+		To:         0,
+		InstanceID: id,
+		Type: pb.WrapMessageInner(&pb.Commit{InstanceData: pb.InstanceData{
+			Command: c,
+			Deps:    []pb.InstanceID{{ReplicaID: 2, InstanceNum: 1}},
+		}}),
+	})
+	// No-ops are not delivered.
+	p.Step(pb.Message{
+		To:         0,
+		InstanceID: pb.InstanceID{ReplicaID: 1, InstanceNum: 1},
+		Type:       pb.WrapMessageInner(&pb.Commit{}),
+	})
+	p.Tick()
+
+	exp := []CommittedCommand{{InstanceID: id, Command: *c}}
+	if a := p.committedCmds; !reflect.DeepEqual(a, exp) {
+		t.Errorf("expected committed commands %v, found %v", exp, a)
+	}
+	if a := p.executedCmds; len(a) != 0 {
+		t.Errorf("expected no executed commands, found %v", a)
+	}
+	if !makeReady(p).containsUpdates() {
+		t.Errorf("expected Ready with committed commands to contain updates")
+	}
+}
+
+// persistReady persists all state that the replica has handed out to be
+// persisted to its storage, as the user of a Node does with each Ready.
+func (p *epaxos) persistReady() {
+	rd := makeReady(p)
+	if rd.HardState != nil {
+		p.storage.PersistHardState(*rd.HardState)
+	}
+	for i := range rd.InstanceStates {
+		p.storage.PersistInstance(&rd.InstanceStates[i])
+	}
+	for r, i := range rd.TruncatedInstances {
+		p.storage.TruncateInstances(r, i)
+	}
+	p.clearUnstable()
+}
+
+// ReadMessages returns the replica's outbound messages. The state that needs
+// to be persisted before they are sent is persisted first.
 func (p *epaxos) ReadMessages() []pb.Message {
+	p.persistReady()
 	msgs := p.msgs
 	p.clearMsgs()
 	return msgs
@@ -54,17 +210,25 @@ type network struct {
 }
 
 func newNetwork(nodeCount int) network {
+	return newNetworkWithConfig(nodeCount, func(*Config) {})
+}
+
+// newNetworkWithConfig creates a network whose peers' Configs are modified by
+// the provided function before the peers are started.
+func newNetworkWithConfig(nodeCount int, configure func(*Config)) network {
 	peers := make(map[pb.ReplicaID]*epaxos, nodeCount)
 	peersSlice := make([]pb.ReplicaID, nodeCount)
 	for i := 0; i < nodeCount; i++ {
 		peersSlice[i] = pb.ReplicaID(i)
 	}
 	for _, r := range peersSlice {
-		peers[r] = newEPaxos(&Config{
+		c := &Config{
 			ID:       r,
 			Nodes:    peersSlice,
 			RandSeed: int64(r),
-		})
+		}
+		configure(c)
+		peers[r] = newEPaxos(c)
 	}
 	return network{
 		peers:    peers,
@@ -78,7 +242,7 @@ func (n *network) F() int {
 }
 
 func (n *network) quorum(val int) bool {
-	return n.peers[0].quorum(val)
+	return n.peers[0].quorum(n.peers[0].epoch, val)
 }
 
 func (n *network) setInterceptor(f func(from pb.ReplicaID, msg pb.Message)) {
@@ -91,6 +255,8 @@ func (n *network) restart(id pb.ReplicaID) {
 		ID:       p.id,
 		Nodes:    p.nodes,
 		Storage:  p.storage,
+		Quorum:   p.quorums,
+		Thrifty:  p.thrifty,
 		RandSeed: int64(id),
 	})
 }
@@ -101,6 +267,10 @@ func (n *network) crash(id pb.ReplicaID) *epaxos {
 	return p
 }
 
+func (n *network) revive(id pb.ReplicaID) {
+	delete(n.failures, n.peers[id])
+}
+
 func (n *network) crashN(c int) {
 	crashed := 0
 	for r := range n.peers {
@@ -131,6 +301,10 @@ func (n *network) dropForAll(perc float64) {
 	}
 }
 
+func (n *network) heal() {
+	n.dropm = make(map[conn]float64)
+}
+
 func (n *network) cut(one, other pb.ReplicaID) {
 	n.drop(one, other, 1.0)
 	n.drop(other, one, 1.0)
@@ -178,6 +352,31 @@ func (n *network) deliverAllMessages() {
 			dest.Step(msg)
 		}
 	}
+	n.deliverSnapshots()
+}
+
+// deliverSnapshots delivers all requested snapshots. The snapshots do not
+// carry any state machine data.
+func (n *network) deliverSnapshots() {
+	var snaps []SnapshotRequest
+	for _, p := range n.peers {
+		if n.alive(p) {
+			for _, req := range p.snapshotRequests() {
+				perc := n.dropm[conn{from: p.id, to: req.To}]
+				if perc > 0 && rand.Float64() < perc {
+					continue
+				}
+				snaps = append(snaps, req)
+			}
+			p.clearSnapshotRequests()
+		}
+	}
+	for _, req := range snaps {
+		dest := n.peers[req.To]
+		if n.alive(dest) {
+			dest.applySnapshot(pb.Snapshot{Metadata: req.Metadata})
+		}
+	}
 }
 
 func (n *network) clearAllMessages() {
@@ -204,6 +403,22 @@ func (n *network) allHave(pred func(*epaxos) bool) bool {
 	return len(n.peers) == n.count(pred)
 }
 
+// applyExecutedCommands hands out the executed commands of all live replicas
+// to be applied, which allows their instances to be truncated.
+func (n *network) applyExecutedCommands() {
+	for _, p := range n.peers {
+		if n.alive(p) {
+			p.ExecutableCommands()
+		}
+	}
+}
+
+func (n *network) allAliveHave(pred func(*epaxos) bool) bool {
+	return n.count(n.alive) == n.count(func(p *epaxos) bool {
+		return n.alive(p) && pred(p)
+	})
+}
+
 // runNetwork waits until the given goal for an epaxos node has been
 // completed. If quorum is true, it will wait until the goal is completed
 // on a quorum of nodes. If it is true, it will wait until the goal is
@@ -215,10 +430,18 @@ func (n *network) runNetwork(goal func(p *epaxos) bool, quorum bool) bool {
 	}
 
 	const maxTicks = 10
+	return n.runNetworkFor(maxTicks, func() bool {
+		return waitUntil(goal)
+	})
+}
+
+// runNetworkFor runs the network for up to maxTicks ticks, waiting until the
+// provided condition is satisfied.
+func (n *network) runNetworkFor(maxTicks int, cond func() bool) bool {
 	for i := 0; i < maxTicks; i++ {
 		n.tickAll()
 		n.deliverAllMessages()
-		if waitUntil(goal) {
+		if cond() {
 			return true
 		}
 	}
@@ -261,6 +484,56 @@ func TestExecuteCommandsNoFailures(t *testing.T) {
 	}
 }
 
+// TestExecuteBatchedCommands verifies that the commands in a batch are all
+// executed, in order, on each replica.
+func TestExecuteBatchedCommands(t *testing.T) {
+	n := newNetwork(3)
+
+	batch := []pb.Command{
+		*newTestingCommand("a", "b"),
+		*newTestingCommand("c", "d"),
+		*newTestingReadCommand("a", "z"),
+	}
+	n.peers[0].RequestBatch(batch)
+	inst := n.peers[0].maxInstance(0)
+	if !n.waitExecuteInstance(inst, false /* quorum */) {
+		t.Fatalf("batch execution failed, instance %+v never installed", inst)
+	}
+	for r, peer := range n.peers {
+		if a := peer.ExecutableCommands(); !reflect.DeepEqual(a, batch) {
+			t.Errorf("expected replica %v to execute %v, found %v", r, batch, a)
+		}
+	}
+}
+
+// TestExecuteCommandsCustomTimeouts verifies that commands are executed, and
+// recovered if necessary, when the protocol timeouts are configured.
+func TestExecuteCommandsCustomTimeouts(t *testing.T) {
+	n := newNetworkWithConfig(5, func(c *Config) {
+		c.SlowPathTimeout = 1
+		c.RecoveryTimeout = 30
+		c.ExecutorTimeout = 2
+	})
+
+	for _, peer := range n.peers {
+		inst := peer.onRequest(newTestingCommand("a", "z"))
+		if !n.waitExecuteInstance(inst, true /* quorum */) {
+			t.Fatalf("command execution failed, instance %+v never installed", inst)
+		}
+	}
+
+	inst := n.peers[0].onRequest(newTestingCommand("a", "z"))
+	n.deliverAllMessages()
+	n.crash(0)
+	if !n.runNetworkFor(5*30, func() bool {
+		return n.allAliveHave(func(p *epaxos) bool {
+			return p.hasExecuted(inst.is.ReplicaID, inst.is.InstanceNum)
+		})
+	}) {
+		t.Fatalf("instance %+v never recovered", inst.is.InstanceID)
+	}
+}
+
 // TestExecuteCommandsNoFailures verifies that each replica can propose a
 // command and that the command will be executed, in the case where there
 // are F or fewer failures.
@@ -433,3 +706,131 @@ func TestExecuteCommandsCrashAfterAccept(t *testing.T) {
 		t.Fatalf("command execution failed, instance %+v never installed", instAfterRestart)
 	}
 }
+
+// TestAcceptorStateSurvivesRestart tests that the state that a replica
+// promises to the leader of an instance when replying to it is persisted
+// before the reply is sent, so that it is not forgotten if the replica
+// restarts.
+func TestAcceptorStateSurvivesRestart(t *testing.T) {
+	cmd := newTestingCommand("a", "z")
+	data := pb.InstanceData{
+		Command: cmd,
+		SeqNum:  5,
+		Deps:    []pb.InstanceID{{ReplicaID: 2, InstanceNum: 3}},
+	}
+	testCases := []struct {
+		msg    proto.Message
+		status pb.InstanceState_Status
+	}{
+		{&pb.PreAccept{InstanceData: data}, pb.InstanceState_PreAccepted},
+		{&pb.Accept{InstanceData: data}, pb.InstanceState_Accepted},
+		{&pb.Commit{InstanceData: data}, pb.InstanceState_Committed},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.status.String(), func(t *testing.T) {
+			n := newNetwork(3)
+			n.peers[1].Step(pb.Message{
+				To:         1,
+				InstanceID: pb.InstanceID{ReplicaID: 0, InstanceNum: 1},
+				Type:       pb.WrapMessageInner(tc.msg),
+			})
+			// Send any reply, then crash.
+			n.peers[1].ReadMessages()
+			n.restart(1)
+
+			inst := n.peers[1].getInstance(0, 1)
+			if inst == nil {
+				t.Fatalf("expected instance to survive restart")
+			}
+			inst.assertState(tc.status)
+			if a, e := inst.instanceData(), data; !reflect.DeepEqual(a, e) {
+				t.Errorf("expected instance data %v after restart, found %v", e, a)
+			}
+		})
+	}
+}
+
+// TestExecuteCommandsCrashAcceptorsAfterPreAccept tests that a command that
+// was pre-accepted by a quorum is recovered after its command leader crashes,
+// even if all other replicas restart after replying to its PreAccept.
+func TestExecuteCommandsCrashAcceptorsAfterPreAccept(t *testing.T) {
+	n := newNetwork(3)
+
+	cmd := newTestingCommand("a", "z")
+	n.peers[0].onRequest(cmd)
+	n.deliverAllMessages()
+
+	// Crash the command leader before it hears any replies, and restart all
+	// other replicas after they reply.
+	n.crash(0)
+	for _, r := range []pb.ReplicaID{1, 2} {
+		if msgs := n.peers[r].ReadMessages(); len(msgs) != 1 {
+			t.Fatalf("expected PreAccept reply from replica %d, found %v", r, msgs)
+		}
+		n.restart(r)
+	}
+
+	committed := func(p *epaxos) bool {
+		inst := p.getInstance(0, 1)
+		return inst != nil && inst.isStates(pb.InstanceState_Committed, pb.InstanceState_Executed)
+	}
+	if !n.runNetworkFor(4*defaultRecoveryTimeout, func() bool { return n.allAliveHave(committed) }) {
+		t.Fatalf("instance never recovered")
+	}
+	for _, r := range []pb.ReplicaID{1, 2} {
+		if a := n.peers[r].getInstance(0, 1).is.Command; !reflect.DeepEqual(a, cmd) {
+			t.Errorf("replica %d: expected command %v to be recovered, found %v", r, cmd, a)
+		}
+	}
+}
+
+// TestTruncateExecutedInstances verifies that executed instances are
+// eventually truncated on all replicas, and that the truncation survives a
+// restart.
+func TestTruncateExecutedInstances(t *testing.T) {
+	n := newNetwork(3)
+
+	var insts []*instance
+	for _, p := range n.peers {
+		inst := p.onRequest(newTestingCommand("a", "z"))
+		if !n.waitExecuteInstance(inst, false /* quorum */) {
+			t.Fatalf("command execution failed, instance %+v never installed", inst)
+		}
+		insts = append(insts, inst)
+	}
+
+	truncated := func(p *epaxos) bool {
+		for _, inst := range insts {
+			id := inst.is.InstanceID
+			if !p.hasTruncated(id.ReplicaID, id.InstanceNum) {
+				return false
+			}
+		}
+		return true
+	}
+	// Instances are only truncated once their commands have been handed out
+	// to be applied.
+	if !n.runNetworkFor(2*defaultTruncateTimeout, func() bool {
+		n.applyExecutedCommands()
+		return n.allHave(truncated)
+	}) {
+		t.Fatalf("executed instances never truncated")
+	}
+	for r, p := range n.peers {
+		if insts, _ := p.storage.Instances(); len(insts) != 0 {
+			t.Errorf("peer %d: expected truncated instances to be removed from storage, found %v", r, insts)
+		}
+	}
+
+	n.restart(1)
+	if !truncated(n.peers[1]) {
+		t.Fatalf("expected truncation to survive a restart")
+	}
+	inst := n.peers[1].onRequest(newTestingCommand("a", "z"))
+	if a, e := inst.is.InstanceNum, pb.InstanceNum(2); a != e {
+		t.Errorf("expected instance number %v after restart, found %v", e, a)
+	}
+	if !n.waitExecuteInstance(inst, false /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+}
@@ -3,6 +3,7 @@ package epaxospb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 )
 
@@ -50,13 +51,133 @@ func (s Span) String() string {
 	return fmt.Sprintf("[%s-%s)", s.Key, s.EndKey)
 }
 
-// Interferes returns whether the two Commands interfere.
+// Access is a Span that a Command reads or writes.
+type Access struct {
+	Span    Span
+	Writing bool
+}
+
+// Interferes returns whether the two Accesses interfere. Accesses interfere if
+// their Spans overlap and at least one of them writes.
+func (a Access) Interferes(o Access) bool {
+	return (a.Writing || o.Writing) && a.Span.Overlaps(o.Span)
+}
+
+// Interferes returns whether the two Commands interfere. Reconfiguration
+// commands interfere with all other commands. Other commands interfere if any
+// of their Accesses interfere, so batches interfere with all commands that any
+// of their commands interfere with.
 func (c Command) Interferes(o Command) bool {
-	return (c.Writing || o.Writing) && c.Span.Overlaps(o.Span)
+	if c.IsConfChange() || o.IsConfChange() {
+		return true
+	}
+	oAccesses := o.Accesses()
+	for _, a := range c.Accesses() {
+		for _, oa := range oAccesses {
+			if a.Interferes(oa) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsConfChange returns whether the Command is a reconfiguration command.
+func (c Command) IsConfChange() bool {
+	return c.ConfChange != nil
+}
+
+// IsBatch returns whether the Command is a batch of other Commands.
+func (c Command) IsBatch() bool {
+	return len(c.Batch) > 0
+}
+
+// IsConditional returns whether the Command is a write whose outcome depends
+// on the value of its key when it executes.
+func (c Command) IsConditional() bool {
+	return c.Op == Command_CompareAndSwap || c.Op == Command_PutIfAbsent
+}
+
+// HasSession returns whether the Command was proposed for a request in a
+// client session.
+func (c Command) HasSession() bool {
+	return c.Session.ClientID != 0
+}
+
+// sessionKeyPrefix prefixes all session keys. Applications whose keys start
+// with it may see commands interfere with client sessions, which is safe but
+// orders the commands needlessly.
+var sessionKeyPrefix = Key("\xff\xffsession/")
+
+// SessionKey returns the key that all Commands in the client's session write.
+func SessionKey(clientID uint64) Key {
+	key := make(Key, len(sessionKeyPrefix)+8)
+	n := copy(key, sessionKeyPrefix)
+	binary.BigEndian.PutUint64(key[n:], clientID)
+	return key
+}
+
+// IsMultiSpan returns whether the Command reads and writes several Spans.
+func (c Command) IsMultiSpan() bool {
+	return len(c.ReadSpans) > 0 || len(c.WriteSpans) > 0
+}
+
+// Accesses returns the Spans that the Command reads and writes. A batch
+// accesses the Spans of all of its commands. Reconfiguration commands access
+// no Spans. Commands in a client session also write the client's SessionKey.
+func (c Command) Accesses() []Access {
+	var as []Access
+	switch {
+	case c.IsConfChange():
+		return nil
+	case c.IsBatch():
+		for _, cc := range c.Batch {
+			as = append(as, cc.Accesses()...)
+		}
+		return as
+	case c.IsMultiSpan():
+		as = make([]Access, 0, len(c.ReadSpans)+len(c.WriteSpans)+1)
+		for _, s := range c.ReadSpans {
+			as = append(as, Access{Span: s})
+		}
+		for _, s := range c.WriteSpans {
+			as = append(as, Access{Span: s, Writing: true})
+		}
+	case c.Op != Command_ExpireSession:
+		as = []Access{{Span: c.Span, Writing: c.Writing}}
+	}
+	if c.HasSession() {
+		as = append(as, Access{Span: Span{Key: SessionKey(c.Session.ClientID)}, Writing: true})
+	}
+	return as
+}
+
+// Commands returns the Commands in the batch, or the Command itself if it is
+// not a batch.
+func (c Command) Commands() []Command {
+	if c.IsBatch() {
+		return c.Batch
+	}
+	return []Command{c}
 }
 
 // String returns a string-formatted version of the Command.
 func (c Command) String() string {
+	if c.IsBatch() {
+		return fmt.Sprintf("batch%v", c.Batch)
+	}
+	if c.IsConfChange() {
+		return fmt.Sprintf("{%d %s %d}", c.ID, c.ConfChange.Type, c.ConfChange.ReplicaID)
+	}
+	if c.IsMultiSpan() {
+		return fmt.Sprintf("{%d reading %v writing %v}", c.ID, c.ReadSpans, c.WriteSpans)
+	}
+	if c.IsConditional() {
+		return fmt.Sprintf("{%d %s %s: %q -> %q}", c.ID, c.Op, c.Span, c.ExpectedValue, c.Data)
+	}
+	if c.Op == Command_ExpireSession {
+		return fmt.Sprintf("{%d %s %d at %d}", c.ID, c.Op, c.Session.ClientID, c.Session.SeqNum)
+	}
 	prefix := "reading"
 	data := ""
 	if c.Writing {
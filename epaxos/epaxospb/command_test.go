@@ -1,6 +1,7 @@
 package epaxospb
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -84,6 +85,20 @@ func TestCommandInterferes(t *testing.T) {
 	wAtoC := Command{Writing: true, Span: sAtoC}
 	rBtoD := Command{Writing: false, Span: sBtoD}
 	wBtoD := Command{Writing: true, Span: sBtoD}
+	cc := Command{ConfChange: &ConfChange{Type: ConfChange_AddNode, ReplicaID: 3}}
+	bRAwD := Command{Batch: []Command{rA, wD}}
+	bRArD := Command{Batch: []Command{rA, rD}}
+	mRAwD := Command{ReadSpans: []Span{sA}, WriteSpans: []Span{sD}}
+	mRArD := Command{ReadSpans: []Span{sA, sD}}
+	// The span of a multi-span command is unused.
+	mRAwBtoD := Command{Writing: true, Span: sBtoD, ReadSpans: []Span{sA}}
+	bMRAwD := Command{Batch: []Command{mRAwD}}
+	// Commands in the same client session interfere.
+	s1wA := Command{Writing: true, Span: sA, Session: ClientSession{ClientID: 1, SeqNum: 1}}
+	s1wD := Command{Writing: true, Span: sD, Session: ClientSession{ClientID: 1, SeqNum: 2}}
+	s2wD := Command{Writing: true, Span: sD, Session: ClientSession{ClientID: 2, SeqNum: 1}}
+	s1exp := Command{Op: Command_ExpireSession, Session: ClientSession{ClientID: 1, SeqNum: 2}}
+	bS1wA := Command{Batch: []Command{rD, s1wA}}
 
 	testData := []struct {
 		c1, c2     Command
@@ -105,6 +120,49 @@ func TestCommandInterferes(t *testing.T) {
 		{wA, wBtoD, false},
 		{wA, rAtoC, true},
 		{wA, wAtoC, true},
+		{cc, rA, true},
+		{cc, wD, true},
+		{cc, rBtoD, true},
+		{cc, cc, true},
+		{bRAwD, rA, false},
+		{bRAwD, wA, true},
+		{bRAwD, rD, true},
+		{bRAwD, rBtoD, false},
+		{bRAwD, rAtoC, false},
+		{bRAwD, wAtoC, true},
+		{bRAwD, cc, true},
+		{bRArD, wBtoD, false},
+		{bRArD, wAtoC, true},
+		{bRArD, rAtoC, false},
+		{bRAwD, bRArD, true},
+		{bRArD, bRArD, false},
+		{mRAwD, rA, false},
+		{mRAwD, wA, true},
+		{mRAwD, rD, true},
+		{mRAwD, rBtoD, false},
+		{mRAwD, wAtoC, true},
+		{mRAwD, cc, true},
+		{mRArD, wBtoD, false},
+		{mRArD, wD, true},
+		{mRArD, rAtoC, false},
+		{mRAwD, mRArD, true},
+		{mRArD, mRArD, false},
+		{mRAwD, bRArD, true},
+		{mRArD, bRArD, false},
+		{mRAwBtoD, rBtoD, false},
+		{mRAwBtoD, wA, true},
+		{bMRAwD, rD, true},
+		{bMRAwD, rA, false},
+		{s1wA, s1wD, true},
+		{s1wA, s2wD, false},
+		{s1wA, rD, false},
+		{s1wD, s2wD, true},
+		{s1exp, s1wA, true},
+		{s1exp, s2wD, false},
+		{s1exp, rA, false},
+		{bS1wA, s1wD, true},
+		{bS1wA, s2wD, true},
+		{bS1wA, wAtoC, true},
 	}
 	for i, test := range testData {
 		for _, swap := range []bool{false, true} {
@@ -118,3 +176,88 @@ func TestCommandInterferes(t *testing.T) {
 		}
 	}
 }
+
+func TestCommandIsConditional(t *testing.T) {
+	testData := []struct {
+		c           Command
+		conditional bool
+	}{
+		{Command{Writing: false}, false},
+		{Command{Writing: true}, false},
+		{Command{Writing: true, Op: Command_CompareAndSwap}, true},
+		{Command{Writing: true, Op: Command_PutIfAbsent}, true},
+		{Command{Op: Command_ExpireSession}, false},
+	}
+	for i, test := range testData {
+		if a := test.c.IsConditional(); a != test.conditional {
+			t.Errorf("%d: expected conditional %t for %v, found %t", i, test.conditional, test.c, a)
+		}
+	}
+}
+
+func TestCommandHasSession(t *testing.T) {
+	if (Command{Writing: true}).HasSession() {
+		t.Errorf("expected command without a client ID to have no session")
+	}
+	c := Command{Writing: true, Session: ClientSession{ClientID: 1}}
+	if !c.HasSession() {
+		t.Errorf("expected command with a client ID to have a session")
+	}
+}
+
+func TestCommandCommands(t *testing.T) {
+	wA := Command{ID: 1, Writing: true, Span: Span{Key: []byte("a")}}
+	rD := Command{ID: 2, Writing: false, Span: Span{Key: []byte("d")}}
+	if cmds := wA.Commands(); len(cmds) != 1 || cmds[0].ID != wA.ID {
+		t.Errorf("expected command to contain only itself, found %v", cmds)
+	}
+	b := Command{Batch: []Command{wA, rD}}
+	if !b.IsBatch() {
+		t.Errorf("expected batch")
+	}
+	if cmds := b.Commands(); len(cmds) != 2 || cmds[0].ID != wA.ID || cmds[1].ID != rD.ID {
+		t.Errorf("expected batch to contain its commands, found %v", cmds)
+	}
+}
+
+func TestCommandAccesses(t *testing.T) {
+	sA := Span{Key: []byte("a")}
+	sD := Span{Key: []byte("d")}
+	wA := Command{Writing: true, Span: sA}
+	rD := Command{Writing: false, Span: sD}
+	m := Command{Writing: true, Span: sD, ReadSpans: []Span{sD}, WriteSpans: []Span{sA}}
+	cc := Command{ConfChange: &ConfChange{Type: ConfChange_AddNode, ReplicaID: 3}}
+	session := ClientSession{ClientID: 1, SeqNum: 2}
+	wSession := Access{Span: Span{Key: SessionKey(1)}, Writing: true}
+	sWA := Command{Writing: true, Span: sA, Session: session}
+	sM := Command{WriteSpans: []Span{sA}, Session: session}
+	exp := Command{Op: Command_ExpireSession, Session: session}
+
+	testData := []struct {
+		c        Command
+		multi    bool
+		accesses []Access
+	}{
+		{wA, false, []Access{{Span: sA, Writing: true}}},
+		{rD, false, []Access{{Span: sD}}},
+		{m, true, []Access{{Span: sD}, {Span: sA, Writing: true}}},
+		{Command{Batch: []Command{wA, m}}, false, []Access{
+			{Span: sA, Writing: true}, {Span: sD}, {Span: sA, Writing: true},
+		}},
+		{cc, false, nil},
+		{sWA, false, []Access{{Span: sA, Writing: true}, wSession}},
+		{sM, true, []Access{{Span: sA, Writing: true}, wSession}},
+		{exp, false, []Access{wSession}},
+		{Command{Batch: []Command{rD, sWA}}, false, []Access{
+			{Span: sD}, {Span: sA, Writing: true}, wSession,
+		}},
+	}
+	for i, test := range testData {
+		if a := test.c.IsMultiSpan(); a != test.multi {
+			t.Errorf("%d: expected multi-span %t for %v, found %t", i, test.multi, test.c, a)
+		}
+		if a := test.c.Accesses(); !reflect.DeepEqual(a, test.accesses) {
+			t.Errorf("%d: expected accesses %v for %v, found %v", i, test.accesses, test.c, a)
+		}
+	}
+}
@@ -9,7 +9,9 @@
 
 	It has these top-level messages:
 		Span
+		ClientSession
 		Command
+		ConfChange
 		InstanceID
 		InstanceData
 		PreAccept
@@ -18,10 +20,18 @@
 		Accept
 		AcceptOK
 		Commit
+		Prepare
+		PrepareReply
+		NACK
+		Read
+		ReadReply
 		Ballot
 		Message
 		InstanceState
 		HardState
+		Configuration
+		SnapshotMetadata
+		Snapshot
 */
 package epaxospb
 
@@ -43,6 +53,63 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.GoGoProtoPackageIsVersion2 // please upgrade the proto package
 
+// Op is the operation that a command performs on its span.
+type Command_Op int32
+
+const (
+	// Plain commands read their span or, if they are writing, blindly
+	// write data to it.
+	Command_Plain Command_Op = 0
+	// CompareAndSwap commands write data to their key if it holds
+	// expected_value.
+	Command_CompareAndSwap Command_Op = 1
+	// PutIfAbsent commands write data to their key if it holds no value.
+	Command_PutIfAbsent Command_Op = 2
+	// ExpireSession commands end their client session if the latest
+	// request of the session is the one with their seq_num. Their span
+	// and data are unused.
+	Command_ExpireSession Command_Op = 3
+)
+
+var Command_Op_name = map[int32]string{
+	0: "Plain",
+	1: "CompareAndSwap",
+	2: "PutIfAbsent",
+	3: "ExpireSession",
+}
+var Command_Op_value = map[string]int32{
+	"Plain":          0,
+	"CompareAndSwap": 1,
+	"PutIfAbsent":    2,
+	"ExpireSession":  3,
+}
+
+func (x Command_Op) String() string {
+	return proto.EnumName(Command_Op_name, int32(x))
+}
+func (Command_Op) EnumDescriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{2, 0} }
+
+type ConfChange_Type int32
+
+const (
+	ConfChange_AddNode    ConfChange_Type = 0
+	ConfChange_RemoveNode ConfChange_Type = 1
+)
+
+var ConfChange_Type_name = map[int32]string{
+	0: "AddNode",
+	1: "RemoveNode",
+}
+var ConfChange_Type_value = map[string]int32{
+	"AddNode":    0,
+	"RemoveNode": 1,
+}
+
+func (x ConfChange_Type) String() string {
+	return proto.EnumName(ConfChange_Type_name, int32(x))
+}
+func (ConfChange_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{3, 0} }
+
 type InstanceState_Status int32
 
 const (
@@ -72,7 +139,7 @@ func (x InstanceState_Status) String() string {
 	return proto.EnumName(InstanceState_Status_name, int32(x))
 }
 func (InstanceState_Status) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptorEpaxos, []int{12, 0}
+	return fileDescriptorEpaxos, []int{19, 0}
 }
 
 // Span represents a span of Keys that a Command operates on.
@@ -105,16 +172,78 @@ func (m *Span) GetEndKey() Key {
 	return nil
 }
 
+// ClientSession identifies a client's request across retries, so that the
+// state machine can apply it at most once.
+type ClientSession struct {
+	// client_id identifies the client. Commands with a zero client_id are not
+	// part of a session.
+	ClientID uint64 `protobuf:"varint,1,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	// seq_num orders the client's requests. All attempts of a request carry
+	// the same seq_num.
+	SeqNum uint64 `protobuf:"varint,2,opt,name=seq_num,json=seqNum,proto3" json:"seq_num,omitempty"`
+}
+
+func (m *ClientSession) Reset()                    { *m = ClientSession{} }
+func (m *ClientSession) String() string            { return proto.CompactTextString(m) }
+func (*ClientSession) ProtoMessage()               {}
+func (*ClientSession) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{1} }
+
+func (m *ClientSession) GetClientID() uint64 {
+	if m != nil {
+		return m.ClientID
+	}
+	return 0
+}
+
+func (m *ClientSession) GetSeqNum() uint64 {
+	if m != nil {
+		return m.SeqNum
+	}
+	return 0
+}
+
 type Command struct {
 	ID      uint64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
 	Span    Span   `protobuf:"bytes,2,opt,name=span" json:"span"`
 	Writing bool   `protobuf:"varint,3,opt,name=writing,proto3" json:"writing,omitempty"`
 	Data    []byte `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	// conf_change, if set, makes the command a reconfiguration command, which
+	// interferes with all other commands.
+	ConfChange *ConfChange `protobuf:"bytes,5,opt,name=conf_change,json=confChange" json:"conf_change,omitempty"`
+	// batch, if set, makes the command a batch of other commands that are
+	// proposed in a single instance. A batch interferes with every command
+	// that one of its commands interferes with, and its other fields are
+	// unused. Batches never contain reconfiguration commands or batches.
+	Batch []Command `protobuf:"bytes,6,rep,name=batch" json:"batch"`
+	// op is the command's operation. Commands with conditional operations
+	// must be writing. Their outcome depends on the value of their key, so
+	// they are evaluated by the state machine when they execute. Interfering
+	// commands execute in the same order on every replica, so all replicas
+	// reach the same outcome.
+	Op Command_Op `protobuf:"varint,7,opt,name=op,proto3,enum=epaxospb.Command_Op" json:"op,omitempty"`
+	// expected_value is the value that a CompareAndSwap command expects its
+	// key to hold.
+	ExpectedValue []byte `protobuf:"bytes,8,opt,name=expected_value,json=expectedValue,proto3" json:"expected_value,omitempty"`
+	// read_spans and write_spans, if either is set, make the command a
+	// multi-span command, which reads and writes all of the spans atomically
+	// at a single point in the execution order. A multi-span command
+	// interferes with every command that accesses one of its spans, unless
+	// both only read it. Its span and writing fields are unused, and data is
+	// interpreted by the state machine.
+	ReadSpans  []Span `protobuf:"bytes,9,rep,name=read_spans,json=readSpans" json:"read_spans"`
+	WriteSpans []Span `protobuf:"bytes,10,rep,name=write_spans,json=writeSpans" json:"write_spans"`
+	// session identifies the client request that the command was proposed
+	// for. A client that retries a request after an error may cause several
+	// commands with the same session to be proposed, and the state machine
+	// uses it to apply only the first of them and return its result for all.
+	// All commands with the same client_id write the client's session key, so
+	// they interfere and execute in the same order on every replica.
+	Session ClientSession `protobuf:"bytes,11,opt,name=session" json:"session"`
 }
 
 func (m *Command) Reset()                    { *m = Command{} }
 func (*Command) ProtoMessage()               {}
-func (*Command) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{1} }
+func (*Command) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{2} }
 
 func (m *Command) GetID() uint64 {
 	if m != nil {
@@ -144,6 +273,90 @@ func (m *Command) GetData() []byte {
 	return nil
 }
 
+func (m *Command) GetConfChange() *ConfChange {
+	if m != nil {
+		return m.ConfChange
+	}
+	return nil
+}
+
+func (m *Command) GetBatch() []Command {
+	if m != nil {
+		return m.Batch
+	}
+	return nil
+}
+
+func (m *Command) GetOp() Command_Op {
+	if m != nil {
+		return m.Op
+	}
+	return Command_Plain
+}
+
+func (m *Command) GetExpectedValue() []byte {
+	if m != nil {
+		return m.ExpectedValue
+	}
+	return nil
+}
+
+func (m *Command) GetReadSpans() []Span {
+	if m != nil {
+		return m.ReadSpans
+	}
+	return nil
+}
+
+func (m *Command) GetWriteSpans() []Span {
+	if m != nil {
+		return m.WriteSpans
+	}
+	return nil
+}
+
+func (m *Command) GetSession() ClientSession {
+	if m != nil {
+		return m.Session
+	}
+	return ClientSession{}
+}
+
+// ConfChange is a change to the set of nodes in the EPaxos network.
+type ConfChange struct {
+	Type      ConfChange_Type `protobuf:"varint,1,opt,name=type,proto3,enum=epaxospb.ConfChange_Type" json:"type,omitempty"`
+	ReplicaID ReplicaID       `protobuf:"varint,2,opt,name=replica_id,json=replicaId,proto3,casttype=ReplicaID" json:"replica_id,omitempty"`
+	// context is opaque to EPaxos. It can be used by the application to pass
+	// along information about the node, like its address.
+	Context []byte `protobuf:"bytes,3,opt,name=context,proto3" json:"context,omitempty"`
+}
+
+func (m *ConfChange) Reset()                    { *m = ConfChange{} }
+func (m *ConfChange) String() string            { return proto.CompactTextString(m) }
+func (*ConfChange) ProtoMessage()               {}
+func (*ConfChange) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{3} }
+
+func (m *ConfChange) GetType() ConfChange_Type {
+	if m != nil {
+		return m.Type
+	}
+	return ConfChange_AddNode
+}
+
+func (m *ConfChange) GetReplicaID() ReplicaID {
+	if m != nil {
+		return m.ReplicaID
+	}
+	return 0
+}
+
+func (m *ConfChange) GetContext() []byte {
+	if m != nil {
+		return m.Context
+	}
+	return nil
+}
+
 type InstanceID struct {
 	ReplicaID   ReplicaID   `protobuf:"varint,1,opt,name=replica_id,json=replicaId,proto3,casttype=ReplicaID" json:"replica_id,omitempty"`
 	InstanceNum InstanceNum `protobuf:"varint,2,opt,name=instance_num,json=instanceNum,proto3,casttype=InstanceNum" json:"instance_num,omitempty"`
@@ -152,7 +365,7 @@ type InstanceID struct {
 func (m *InstanceID) Reset()                    { *m = InstanceID{} }
 func (m *InstanceID) String() string            { return proto.CompactTextString(m) }
 func (*InstanceID) ProtoMessage()               {}
-func (*InstanceID) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{2} }
+func (*InstanceID) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{4} }
 
 func (m *InstanceID) GetReplicaID() ReplicaID {
 	if m != nil {
@@ -177,7 +390,7 @@ type InstanceData struct {
 func (m *InstanceData) Reset()                    { *m = InstanceData{} }
 func (m *InstanceData) String() string            { return proto.CompactTextString(m) }
 func (*InstanceData) ProtoMessage()               {}
-func (*InstanceData) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{3} }
+func (*InstanceData) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{5} }
 
 func (m *InstanceData) GetCommand() *Command {
 	if m != nil {
@@ -207,7 +420,7 @@ type PreAccept struct {
 func (m *PreAccept) Reset()                    { *m = PreAccept{} }
 func (m *PreAccept) String() string            { return proto.CompactTextString(m) }
 func (*PreAccept) ProtoMessage()               {}
-func (*PreAccept) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{4} }
+func (*PreAccept) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{6} }
 
 // PreAcceptOK is used to respond to a PreAccept message is cases where the
 // remote replica has no new information about the proposed command.
@@ -217,7 +430,7 @@ type PreAcceptOK struct {
 func (m *PreAcceptOK) Reset()                    { *m = PreAcceptOK{} }
 func (m *PreAcceptOK) String() string            { return proto.CompactTextString(m) }
 func (*PreAcceptOK) ProtoMessage()               {}
-func (*PreAcceptOK) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{5} }
+func (*PreAcceptOK) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{7} }
 
 // PreAcceptReply is used to respond to a PreAccept message in cases whe the
 // remote replica has new information about the proposed command. This new
@@ -230,7 +443,7 @@ type PreAcceptReply struct {
 func (m *PreAcceptReply) Reset()                    { *m = PreAcceptReply{} }
 func (m *PreAcceptReply) String() string            { return proto.CompactTextString(m) }
 func (*PreAcceptReply) ProtoMessage()               {}
-func (*PreAcceptReply) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{6} }
+func (*PreAcceptReply) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{8} }
 
 func (m *PreAcceptReply) GetUpdatedSeqNum() SeqNum {
 	if m != nil {
@@ -253,7 +466,7 @@ type Accept struct {
 func (m *Accept) Reset()                    { *m = Accept{} }
 func (m *Accept) String() string            { return proto.CompactTextString(m) }
 func (*Accept) ProtoMessage()               {}
-func (*Accept) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{7} }
+func (*Accept) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{9} }
 
 type AcceptOK struct {
 }
@@ -261,7 +474,7 @@ type AcceptOK struct {
 func (m *AcceptOK) Reset()                    { *m = AcceptOK{} }
 func (m *AcceptOK) String() string            { return proto.CompactTextString(m) }
 func (*AcceptOK) ProtoMessage()               {}
-func (*AcceptOK) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{8} }
+func (*AcceptOK) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{10} }
 
 type Commit struct {
 	InstanceData `protobuf:"bytes,1,opt,name=data,embedded=data" json:"data"`
@@ -270,7 +483,167 @@ type Commit struct {
 func (m *Commit) Reset()                    { *m = Commit{} }
 func (m *Commit) String() string            { return proto.CompactTextString(m) }
 func (*Commit) ProtoMessage()               {}
-func (*Commit) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{9} }
+func (*Commit) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{11} }
+
+// Prepare is sent by a replica that is taking over leadership of an instance,
+// typically because the instance's command leader is suspected to have failed.
+type Prepare struct {
+}
+
+func (m *Prepare) Reset()                    { *m = Prepare{} }
+func (m *Prepare) String() string            { return proto.CompactTextString(m) }
+func (*Prepare) ProtoMessage()               {}
+func (*Prepare) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{12} }
+
+// PrepareReply is used to respond to a Prepare message with the state that the
+// replica has accepted for the instance.
+type PrepareReply struct {
+	// from is the replica that sent the reply.
+	From         ReplicaID            `protobuf:"varint,1,opt,name=from,proto3,casttype=ReplicaID" json:"from,omitempty"`
+	Status       InstanceState_Status `protobuf:"varint,2,opt,name=status,proto3,enum=epaxospb.InstanceState_Status" json:"status,omitempty"`
+	InstanceData `protobuf:"bytes,3,opt,name=data,embedded=data" json:"data"`
+	// accepted_ballot is the ballot at which the replica accepted the data.
+	AcceptedBallot Ballot `protobuf:"bytes,4,opt,name=accepted_ballot,json=acceptedBallot" json:"accepted_ballot"`
+	// pre_accepted_unchanged is set if the replica pre-accepted the data that
+	// the instance's leader proposed without changing it.
+	PreAcceptedUnchanged bool `protobuf:"varint,5,opt,name=pre_accepted_unchanged,json=preAcceptedUnchanged,proto3" json:"pre_accepted_unchanged,omitempty"`
+}
+
+func (m *PrepareReply) Reset()                    { *m = PrepareReply{} }
+func (m *PrepareReply) String() string            { return proto.CompactTextString(m) }
+func (*PrepareReply) ProtoMessage()               {}
+func (*PrepareReply) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{13} }
+
+func (m *PrepareReply) GetFrom() ReplicaID {
+	if m != nil {
+		return m.From
+	}
+	return 0
+}
+
+func (m *PrepareReply) GetStatus() InstanceState_Status {
+	if m != nil {
+		return m.Status
+	}
+	return InstanceState_None
+}
+
+func (m *PrepareReply) GetAcceptedBallot() Ballot {
+	if m != nil {
+		return m.AcceptedBallot
+	}
+	return Ballot{}
+}
+
+func (m *PrepareReply) GetPreAcceptedUnchanged() bool {
+	if m != nil {
+		return m.PreAcceptedUnchanged
+	}
+	return false
+}
+
+// NACK is used to reject a message that carries a smaller ballot than one the
+// replica has already promised for the instance. It informs the message's
+// sender of the larger ballot.
+type NACK struct {
+	Ballot Ballot `protobuf:"bytes,1,opt,name=ballot" json:"ballot"`
+}
+
+func (m *NACK) Reset()                    { *m = NACK{} }
+func (m *NACK) String() string            { return proto.CompactTextString(m) }
+func (*NACK) ProtoMessage()               {}
+func (*NACK) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{14} }
+
+func (m *NACK) GetBallot() Ballot {
+	if m != nil {
+		return m.Ballot
+	}
+	return Ballot{}
+}
+
+// Read is sent by a replica that performs a linearizable read of a span
+// without proposing it in an instance. It is not part of any instance.
+type Read struct {
+	// read_id identifies the read on the replica performing it.
+	ReadID uint64 `protobuf:"varint,1,opt,name=read_id,json=readId,proto3" json:"read_id,omitempty"`
+	// from is the replica performing the read.
+	From ReplicaID `protobuf:"varint,2,opt,name=from,proto3,casttype=ReplicaID" json:"from,omitempty"`
+	Span Span      `protobuf:"bytes,3,opt,name=span" json:"span"`
+}
+
+func (m *Read) Reset()                    { *m = Read{} }
+func (m *Read) String() string            { return proto.CompactTextString(m) }
+func (*Read) ProtoMessage()               {}
+func (*Read) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{15} }
+
+func (m *Read) GetReadID() uint64 {
+	if m != nil {
+		return m.ReadID
+	}
+	return 0
+}
+
+func (m *Read) GetFrom() ReplicaID {
+	if m != nil {
+		return m.From
+	}
+	return 0
+}
+
+func (m *Read) GetSpan() Span {
+	if m != nil {
+		return m.Span
+	}
+	return Span{}
+}
+
+// ReadReply is used to respond to a Read message with the instances that the
+// replica knows of that may have to execute before the read.
+type ReadReply struct {
+	ReadID uint64 `protobuf:"varint,1,opt,name=read_id,json=readId,proto3" json:"read_id,omitempty"`
+	// from is the replica that sent the reply.
+	From ReplicaID `protobuf:"varint,2,opt,name=from,proto3,casttype=ReplicaID" json:"from,omitempty"`
+	// deps are the instances in the replica's command spaces whose commands
+	// interfere with the read.
+	Deps []InstanceID `protobuf:"bytes,3,rep,name=deps" json:"deps"`
+	// truncated_instance_nums is a mapping from ReplicaID to the InstanceNum
+	// truncation index of the replica. Truncated instances are not included
+	// in deps.
+	TruncatedInstanceNums map[ReplicaID]InstanceNum `protobuf:"bytes,4,rep,name=truncated_instance_nums,json=truncatedInstanceNums,castkey=ReplicaID,castvalue=InstanceNum" json:"truncated_instance_nums,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+}
+
+func (m *ReadReply) Reset()                    { *m = ReadReply{} }
+func (m *ReadReply) String() string            { return proto.CompactTextString(m) }
+func (*ReadReply) ProtoMessage()               {}
+func (*ReadReply) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{16} }
+
+func (m *ReadReply) GetReadID() uint64 {
+	if m != nil {
+		return m.ReadID
+	}
+	return 0
+}
+
+func (m *ReadReply) GetFrom() ReplicaID {
+	if m != nil {
+		return m.From
+	}
+	return 0
+}
+
+func (m *ReadReply) GetDeps() []InstanceID {
+	if m != nil {
+		return m.Deps
+	}
+	return nil
+}
+
+func (m *ReadReply) GetTruncatedInstanceNums() map[ReplicaID]InstanceNum {
+	if m != nil {
+		return m.TruncatedInstanceNums
+	}
+	return nil
+}
 
 // Ballot is a ballot number that ensures message freshness.
 type Ballot struct {
@@ -282,7 +655,7 @@ type Ballot struct {
 func (m *Ballot) Reset()                    { *m = Ballot{} }
 func (m *Ballot) String() string            { return proto.CompactTextString(m) }
 func (*Ballot) ProtoMessage()               {}
-func (*Ballot) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{10} }
+func (*Ballot) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{17} }
 
 func (m *Ballot) GetEpoch() uint64 {
 	if m != nil {
@@ -321,13 +694,18 @@ type Message struct {
 	//	*Message_Accept
 	//	*Message_AcceptOk
 	//	*Message_Commit
+	//	*Message_Prepare
+	//	*Message_PrepareReply
+	//	*Message_Nack
+	//	*Message_Read
+	//	*Message_ReadReply
 	Type isMessage_Type `protobuf_oneof:"type"`
 }
 
 func (m *Message) Reset()                    { *m = Message{} }
 func (m *Message) String() string            { return proto.CompactTextString(m) }
 func (*Message) ProtoMessage()               {}
-func (*Message) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{11} }
+func (*Message) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{18} }
 
 type isMessage_Type interface {
 	isMessage_Type()
@@ -353,6 +731,21 @@ type Message_AcceptOk struct {
 type Message_Commit struct {
 	Commit *Commit `protobuf:"bytes,9,opt,name=commit,oneof"`
 }
+type Message_Prepare struct {
+	Prepare *Prepare `protobuf:"bytes,10,opt,name=prepare,oneof"`
+}
+type Message_PrepareReply struct {
+	PrepareReply *PrepareReply `protobuf:"bytes,11,opt,name=prepare_reply,json=prepareReply,oneof"`
+}
+type Message_Nack struct {
+	Nack *NACK `protobuf:"bytes,12,opt,name=nack,oneof"`
+}
+type Message_Read struct {
+	Read *Read `protobuf:"bytes,13,opt,name=read,oneof"`
+}
+type Message_ReadReply struct {
+	ReadReply *ReadReply `protobuf:"bytes,14,opt,name=read_reply,json=readReply,oneof"`
+}
 
 func (*Message_PreAccept) isMessage_Type()      {}
 func (*Message_PreAcceptOk) isMessage_Type()    {}
@@ -360,6 +753,11 @@ func (*Message_PreAcceptReply) isMessage_Type() {}
 func (*Message_Accept) isMessage_Type()         {}
 func (*Message_AcceptOk) isMessage_Type()       {}
 func (*Message_Commit) isMessage_Type()         {}
+func (*Message_Prepare) isMessage_Type()        {}
+func (*Message_PrepareReply) isMessage_Type()   {}
+func (*Message_Nack) isMessage_Type()           {}
+func (*Message_Read) isMessage_Type()           {}
+func (*Message_ReadReply) isMessage_Type()      {}
 
 func (m *Message) GetType() isMessage_Type {
 	if m != nil {
@@ -431,6 +829,41 @@ func (m *Message) GetCommit() *Commit {
 	return nil
 }
 
+func (m *Message) GetPrepare() *Prepare {
+	if x, ok := m.GetType().(*Message_Prepare); ok {
+		return x.Prepare
+	}
+	return nil
+}
+
+func (m *Message) GetPrepareReply() *PrepareReply {
+	if x, ok := m.GetType().(*Message_PrepareReply); ok {
+		return x.PrepareReply
+	}
+	return nil
+}
+
+func (m *Message) GetNack() *NACK {
+	if x, ok := m.GetType().(*Message_Nack); ok {
+		return x.Nack
+	}
+	return nil
+}
+
+func (m *Message) GetRead() *Read {
+	if x, ok := m.GetType().(*Message_Read); ok {
+		return x.Read
+	}
+	return nil
+}
+
+func (m *Message) GetReadReply() *ReadReply {
+	if x, ok := m.GetType().(*Message_ReadReply); ok {
+		return x.ReadReply
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Message) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _Message_OneofMarshaler, _Message_OneofUnmarshaler, _Message_OneofSizer, []interface{}{
@@ -440,6 +873,11 @@ func (*Message) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error
 		(*Message_Accept)(nil),
 		(*Message_AcceptOk)(nil),
 		(*Message_Commit)(nil),
+		(*Message_Prepare)(nil),
+		(*Message_PrepareReply)(nil),
+		(*Message_Nack)(nil),
+		(*Message_Read)(nil),
+		(*Message_ReadReply)(nil),
 	}
 }
 
@@ -477,6 +915,31 @@ func _Message_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Commit); err != nil {
 			return err
 		}
+	case *Message_Prepare:
+		_ = b.EncodeVarint(10<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Prepare); err != nil {
+			return err
+		}
+	case *Message_PrepareReply:
+		_ = b.EncodeVarint(11<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.PrepareReply); err != nil {
+			return err
+		}
+	case *Message_Nack:
+		_ = b.EncodeVarint(12<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Nack); err != nil {
+			return err
+		}
+	case *Message_Read:
+		_ = b.EncodeVarint(13<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Read); err != nil {
+			return err
+		}
+	case *Message_ReadReply:
+		_ = b.EncodeVarint(14<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.ReadReply); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("Message.Type has unexpected type %T", x)
@@ -535,6 +998,46 @@ func _Message_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer
 		err := b.DecodeMessage(msg)
 		m.Type = &Message_Commit{msg}
 		return true, err
+	case 10: // type.prepare
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Prepare)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_Prepare{msg}
+		return true, err
+	case 11: // type.prepare_reply
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(PrepareReply)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_PrepareReply{msg}
+		return true, err
+	case 12: // type.nack
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(NACK)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_Nack{msg}
+		return true, err
+	case 13: // type.read
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Read)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_Read{msg}
+		return true, err
+	case 14: // type.read_reply
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ReadReply)
+		err := b.DecodeMessage(msg)
+		m.Type = &Message_ReadReply{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -574,6 +1077,31 @@ func _Message_OneofSizer(msg proto.Message) (n int) {
 		n += proto.SizeVarint(9<<3 | proto.WireBytes)
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *Message_Prepare:
+		s := proto.Size(x.Prepare)
+		n += proto.SizeVarint(10<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Message_PrepareReply:
+		s := proto.Size(x.PrepareReply)
+		n += proto.SizeVarint(11<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Message_Nack:
+		s := proto.Size(x.Nack)
+		n += proto.SizeVarint(12<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Message_Read:
+		s := proto.Size(x.Read)
+		n += proto.SizeVarint(13<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Message_ReadReply:
+		s := proto.Size(x.ReadReply)
+		n += proto.SizeVarint(14<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -585,13 +1113,22 @@ type InstanceState struct {
 	InstanceID   `protobuf:"bytes,1,opt,name=meta,embedded=meta" json:"meta"`
 	Status       InstanceState_Status `protobuf:"varint,2,opt,name=status,proto3,enum=epaxospb.InstanceState_Status" json:"status,omitempty"`
 	InstanceData `protobuf:"bytes,3,opt,name=data,embedded=data" json:"data"`
-	Ballot       *Ballot `protobuf:"bytes,4,opt,name=ballot" json:"ballot,omitempty"`
+	// ballot is the largest ballot that the replica has seen for the instance.
+	// The replica will not participate in any smaller ballots.
+	Ballot Ballot `protobuf:"bytes,4,opt,name=ballot" json:"ballot"`
+	// accepted_ballot is the ballot at which the instance's status and data
+	// were last updated.
+	AcceptedBallot Ballot `protobuf:"bytes,5,opt,name=accepted_ballot,json=acceptedBallot" json:"accepted_ballot"`
+	// pre_accepted_unchanged is set if the replica pre-accepted the data that
+	// the instance's leader proposed without changing it. Only such replicas
+	// can be part of the leader's fast path quorum.
+	PreAcceptedUnchanged bool `protobuf:"varint,6,opt,name=pre_accepted_unchanged,json=preAcceptedUnchanged,proto3" json:"pre_accepted_unchanged,omitempty"`
 }
 
 func (m *InstanceState) Reset()                    { *m = InstanceState{} }
 func (m *InstanceState) String() string            { return proto.CompactTextString(m) }
 func (*InstanceState) ProtoMessage()               {}
-func (*InstanceState) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{12} }
+func (*InstanceState) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{19} }
 
 func (m *InstanceState) GetStatus() InstanceState_Status {
 	if m != nil {
@@ -600,11 +1137,25 @@ func (m *InstanceState) GetStatus() InstanceState_Status {
 	return InstanceState_None
 }
 
-func (m *InstanceState) GetBallot() *Ballot {
+func (m *InstanceState) GetBallot() Ballot {
 	if m != nil {
 		return m.Ballot
 	}
-	return nil
+	return Ballot{}
+}
+
+func (m *InstanceState) GetAcceptedBallot() Ballot {
+	if m != nil {
+		return m.AcceptedBallot
+	}
+	return Ballot{}
+}
+
+func (m *InstanceState) GetPreAcceptedUnchanged() bool {
+	if m != nil {
+		return m.PreAcceptedUnchanged
+	}
+	return false
 }
 
 type HardState struct {
@@ -612,12 +1163,27 @@ type HardState struct {
 	ReplicaID ReplicaID `protobuf:"varint,1,opt,name=replica_id,json=replicaId,proto3,casttype=ReplicaID" json:"replica_id,omitempty"`
 	// nodes is the set of all nodes in the EPaxos network.
 	Nodes []ReplicaID `protobuf:"varint,2,rep,packed,name=nodes,casttype=ReplicaID" json:"nodes,omitempty"`
+	// truncated_instance_nums is a mapping from ReplicaID to the current
+	// InstanceNum truncation index. All instances in the replica's instance
+	// space up to and including the index have been executed and truncated.
+	TruncatedInstanceNums map[ReplicaID]InstanceNum `protobuf:"bytes,3,rep,name=truncated_instance_nums,json=truncatedInstanceNums,castkey=ReplicaID,castvalue=InstanceNum" json:"truncated_instance_nums,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	// truncated_seq_num is the largest sequence number that has been
+	// truncated on this node.
+	TruncatedSeqNum SeqNum `protobuf:"varint,4,opt,name=truncated_seq_num,json=truncatedSeqNum,proto3,casttype=SeqNum" json:"truncated_seq_num,omitempty"`
+	// epoch is the configuration epoch of the EPaxos network. It is
+	// incremented whenever a ConfChange is executed.
+	Epoch uint64 `protobuf:"varint,5,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	// conf_change_instance is the instance of the last executed ConfChange.
+	ConfChangeInstance InstanceID `protobuf:"bytes,6,opt,name=conf_change_instance,json=confChangeInstance" json:"conf_change_instance"`
+	// configurations holds the set of nodes of each earlier configuration
+	// epoch, in order of their epochs.
+	Configurations []Configuration `protobuf:"bytes,7,rep,name=configurations" json:"configurations"`
 }
 
 func (m *HardState) Reset()                    { *m = HardState{} }
 func (m *HardState) String() string            { return proto.CompactTextString(m) }
 func (*HardState) ProtoMessage()               {}
-func (*HardState) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{13} }
+func (*HardState) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{20} }
 
 func (m *HardState) GetReplicaID() ReplicaID {
 	if m != nil {
@@ -633,21 +1199,191 @@ func (m *HardState) GetNodes() []ReplicaID {
 	return nil
 }
 
-func init() {
-	proto.RegisterType((*Span)(nil), "epaxospb.Span")
-	proto.RegisterType((*Command)(nil), "epaxospb.Command")
-	proto.RegisterType((*InstanceID)(nil), "epaxospb.InstanceID")
-	proto.RegisterType((*InstanceData)(nil), "epaxospb.InstanceData")
-	proto.RegisterType((*PreAccept)(nil), "epaxospb.PreAccept")
-	proto.RegisterType((*PreAcceptOK)(nil), "epaxospb.PreAcceptOK")
-	proto.RegisterType((*PreAcceptReply)(nil), "epaxospb.PreAcceptReply")
-	proto.RegisterType((*Accept)(nil), "epaxospb.Accept")
+func (m *HardState) GetTruncatedInstanceNums() map[ReplicaID]InstanceNum {
+	if m != nil {
+		return m.TruncatedInstanceNums
+	}
+	return nil
+}
+
+func (m *HardState) GetTruncatedSeqNum() SeqNum {
+	if m != nil {
+		return m.TruncatedSeqNum
+	}
+	return 0
+}
+
+func (m *HardState) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *HardState) GetConfChangeInstance() InstanceID {
+	if m != nil {
+		return m.ConfChangeInstance
+	}
+	return InstanceID{}
+}
+
+func (m *HardState) GetConfigurations() []Configuration {
+	if m != nil {
+		return m.Configurations
+	}
+	return nil
+}
+
+// Configuration is the set of nodes of the EPaxos network in a configuration
+// epoch. The quorums of a ballot are sized by the nodes of the ballot's epoch.
+type Configuration struct {
+	Epoch uint64      `protobuf:"varint,1,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Nodes []ReplicaID `protobuf:"varint,2,rep,packed,name=nodes,casttype=ReplicaID" json:"nodes,omitempty"`
+}
+
+func (m *Configuration) Reset()                    { *m = Configuration{} }
+func (m *Configuration) String() string            { return proto.CompactTextString(m) }
+func (*Configuration) ProtoMessage()               {}
+func (*Configuration) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{21} }
+
+func (m *Configuration) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *Configuration) GetNodes() []ReplicaID {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+// SnapshotMetadata describes the executed instances that are reflected in a
+// snapshot of a replica's state machine.
+type SnapshotMetadata struct {
+	// executed_instance_nums is a mapping from ReplicaID to the InstanceNum up
+	// to and including which all instances in the replica's instance space
+	// are reflected in the snapshot.
+	ExecutedInstanceNums map[ReplicaID]InstanceNum `protobuf:"bytes,1,rep,name=executed_instance_nums,json=executedInstanceNums,castkey=ReplicaID,castvalue=InstanceNum" json:"executed_instance_nums,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	// executed_instances holds all other instances that are reflected in the
+	// snapshot.
+	ExecutedInstances []InstanceState `protobuf:"bytes,2,rep,name=executed_instances,json=executedInstances" json:"executed_instances"`
+	// max_seq_num is the largest sequence number of all instances that are
+	// reflected in the snapshot.
+	MaxSeqNum SeqNum `protobuf:"varint,3,opt,name=max_seq_num,json=maxSeqNum,proto3,casttype=SeqNum" json:"max_seq_num,omitempty"`
+	// nodes is the set of all nodes in the EPaxos network once all instances
+	// reflected in the snapshot have been executed.
+	Nodes []ReplicaID `protobuf:"varint,4,rep,packed,name=nodes,casttype=ReplicaID" json:"nodes,omitempty"`
+	// epoch is the configuration epoch of the EPaxos network once all
+	// instances reflected in the snapshot have been executed.
+	Epoch uint64 `protobuf:"varint,5,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	// configurations holds the set of nodes of each configuration epoch
+	// before epoch.
+	Configurations []Configuration `protobuf:"bytes,6,rep,name=configurations" json:"configurations"`
+}
+
+func (m *SnapshotMetadata) Reset()                    { *m = SnapshotMetadata{} }
+func (m *SnapshotMetadata) String() string            { return proto.CompactTextString(m) }
+func (*SnapshotMetadata) ProtoMessage()               {}
+func (*SnapshotMetadata) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{22} }
+
+func (m *SnapshotMetadata) GetExecutedInstanceNums() map[ReplicaID]InstanceNum {
+	if m != nil {
+		return m.ExecutedInstanceNums
+	}
+	return nil
+}
+
+func (m *SnapshotMetadata) GetExecutedInstances() []InstanceState {
+	if m != nil {
+		return m.ExecutedInstances
+	}
+	return nil
+}
+
+func (m *SnapshotMetadata) GetMaxSeqNum() SeqNum {
+	if m != nil {
+		return m.MaxSeqNum
+	}
+	return 0
+}
+
+func (m *SnapshotMetadata) GetNodes() []ReplicaID {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+func (m *SnapshotMetadata) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *SnapshotMetadata) GetConfigurations() []Configuration {
+	if m != nil {
+		return m.Configurations
+	}
+	return nil
+}
+
+// Snapshot is a point-in-time snapshot of a replica's state machine.
+type Snapshot struct {
+	Metadata SnapshotMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata"`
+	// data is the application's encoding of its state machine.
+	Data []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *Snapshot) Reset()                    { *m = Snapshot{} }
+func (m *Snapshot) String() string            { return proto.CompactTextString(m) }
+func (*Snapshot) ProtoMessage()               {}
+func (*Snapshot) Descriptor() ([]byte, []int) { return fileDescriptorEpaxos, []int{23} }
+
+func (m *Snapshot) GetMetadata() SnapshotMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return SnapshotMetadata{}
+}
+
+func (m *Snapshot) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Span)(nil), "epaxospb.Span")
+	proto.RegisterType((*ClientSession)(nil), "epaxospb.ClientSession")
+	proto.RegisterType((*Command)(nil), "epaxospb.Command")
+	proto.RegisterType((*ConfChange)(nil), "epaxospb.ConfChange")
+	proto.RegisterType((*InstanceID)(nil), "epaxospb.InstanceID")
+	proto.RegisterType((*InstanceData)(nil), "epaxospb.InstanceData")
+	proto.RegisterType((*PreAccept)(nil), "epaxospb.PreAccept")
+	proto.RegisterType((*PreAcceptOK)(nil), "epaxospb.PreAcceptOK")
+	proto.RegisterType((*PreAcceptReply)(nil), "epaxospb.PreAcceptReply")
+	proto.RegisterType((*Accept)(nil), "epaxospb.Accept")
 	proto.RegisterType((*AcceptOK)(nil), "epaxospb.AcceptOK")
 	proto.RegisterType((*Commit)(nil), "epaxospb.Commit")
+	proto.RegisterType((*Prepare)(nil), "epaxospb.Prepare")
+	proto.RegisterType((*PrepareReply)(nil), "epaxospb.PrepareReply")
+	proto.RegisterType((*NACK)(nil), "epaxospb.NACK")
+	proto.RegisterType((*Read)(nil), "epaxospb.Read")
+	proto.RegisterType((*ReadReply)(nil), "epaxospb.ReadReply")
 	proto.RegisterType((*Ballot)(nil), "epaxospb.Ballot")
 	proto.RegisterType((*Message)(nil), "epaxospb.Message")
 	proto.RegisterType((*InstanceState)(nil), "epaxospb.InstanceState")
 	proto.RegisterType((*HardState)(nil), "epaxospb.HardState")
+	proto.RegisterType((*Configuration)(nil), "epaxospb.Configuration")
+	proto.RegisterType((*SnapshotMetadata)(nil), "epaxospb.SnapshotMetadata")
+	proto.RegisterType((*Snapshot)(nil), "epaxospb.Snapshot")
+	proto.RegisterEnum("epaxospb.Command_Op", Command_Op_name, Command_Op_value)
+	proto.RegisterEnum("epaxospb.ConfChange_Type", ConfChange_Type_name, ConfChange_Type_value)
 	proto.RegisterEnum("epaxospb.InstanceState_Status", InstanceState_Status_name, InstanceState_Status_value)
 }
 func (m *Span) Marshal() (dAtA []byte, err error) {
@@ -680,6 +1416,34 @@ func (m *Span) MarshalTo(dAtA []byte) (int, error) {
 	return i, nil
 }
 
+func (m *ClientSession) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ClientSession) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.ClientID != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.ClientID))
+	}
+	if m.SeqNum != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.SeqNum))
+	}
+	return i, nil
+}
+
 func (m *Command) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -724,6 +1488,105 @@ func (m *Command) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintEpaxos(dAtA, i, uint64(len(m.Data)))
 		i += copy(dAtA[i:], m.Data)
 	}
+	if m.ConfChange != nil {
+		dAtA[i] = 0x2a
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.ConfChange.Size()))
+		n2, err := m.ConfChange.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n2
+	}
+	if len(m.Batch) > 0 {
+		for _, msg := range m.Batch {
+			dAtA[i] = 0x32
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.Op != 0 {
+		dAtA[i] = 0x38
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Op))
+	}
+	if len(m.ExpectedValue) > 0 {
+		dAtA[i] = 0x42
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(len(m.ExpectedValue)))
+		i += copy(dAtA[i:], m.ExpectedValue)
+	}
+	if len(m.ReadSpans) > 0 {
+		for _, msg := range m.ReadSpans {
+			dAtA[i] = 0x4a
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if len(m.WriteSpans) > 0 {
+		for _, msg := range m.WriteSpans {
+			dAtA[i] = 0x52
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	dAtA[i] = 0x5a
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.Session.Size()))
+	n3, err := m.Session.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n3
+	return i, nil
+}
+
+func (m *ConfChange) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ConfChange) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Type != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Type))
+	}
+	if m.ReplicaID != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.ReplicaID))
+	}
+	if len(m.Context) > 0 {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(len(m.Context)))
+		i += copy(dAtA[i:], m.Context)
+	}
 	return i, nil
 }
 
@@ -774,11 +1637,11 @@ func (m *InstanceData) MarshalTo(dAtA []byte) (int, error) {
 		dAtA[i] = 0xa
 		i++
 		i = encodeVarintEpaxos(dAtA, i, uint64(m.Command.Size()))
-		n2, err := m.Command.MarshalTo(dAtA[i:])
+		n4, err := m.Command.MarshalTo(dAtA[i:])
 		if err != nil {
 			return 0, err
 		}
-		i += n2
+		i += n4
 	}
 	if m.SeqNum != 0 {
 		dAtA[i] = 0x10
@@ -818,11 +1681,11 @@ func (m *PreAccept) MarshalTo(dAtA []byte) (int, error) {
 	dAtA[i] = 0xa
 	i++
 	i = encodeVarintEpaxos(dAtA, i, uint64(m.InstanceData.Size()))
-	n3, err := m.InstanceData.MarshalTo(dAtA[i:])
+	n5, err := m.InstanceData.MarshalTo(dAtA[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n3
+	i += n5
 	return i, nil
 }
 
@@ -897,11 +1760,11 @@ func (m *Accept) MarshalTo(dAtA []byte) (int, error) {
 	dAtA[i] = 0xa
 	i++
 	i = encodeVarintEpaxos(dAtA, i, uint64(m.InstanceData.Size()))
-	n4, err := m.InstanceData.MarshalTo(dAtA[i:])
+	n6, err := m.InstanceData.MarshalTo(dAtA[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n4
+	i += n6
 	return i, nil
 }
 
@@ -941,15 +1804,15 @@ func (m *Commit) MarshalTo(dAtA []byte) (int, error) {
 	dAtA[i] = 0xa
 	i++
 	i = encodeVarintEpaxos(dAtA, i, uint64(m.InstanceData.Size()))
-	n5, err := m.InstanceData.MarshalTo(dAtA[i:])
+	n7, err := m.InstanceData.MarshalTo(dAtA[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n5
+	i += n7
 	return i, nil
 }
 
-func (m *Ballot) Marshal() (dAtA []byte, err error) {
+func (m *Prepare) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalTo(dAtA)
@@ -959,30 +1822,15 @@ func (m *Ballot) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Ballot) MarshalTo(dAtA []byte) (int, error) {
+func (m *Prepare) MarshalTo(dAtA []byte) (int, error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	if m.Epoch != 0 {
-		dAtA[i] = 0x8
-		i++
-		i = encodeVarintEpaxos(dAtA, i, uint64(m.Epoch))
-	}
-	if m.Number != 0 {
-		dAtA[i] = 0x10
-		i++
-		i = encodeVarintEpaxos(dAtA, i, uint64(m.Number))
-	}
-	if m.ReplicaID != 0 {
-		dAtA[i] = 0x18
-		i++
-		i = encodeVarintEpaxos(dAtA, i, uint64(m.ReplicaID))
-	}
 	return i, nil
 }
 
-func (m *Message) Marshal() (dAtA []byte, err error) {
+func (m *PrepareReply) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalTo(dAtA)
@@ -992,55 +1840,259 @@ func (m *Message) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *Message) MarshalTo(dAtA []byte) (int, error) {
+func (m *PrepareReply) MarshalTo(dAtA []byte) (int, error) {
 	var i int
 	_ = i
 	var l int
 	_ = l
-	if m.To != 0 {
+	if m.From != 0 {
 		dAtA[i] = 0x8
 		i++
-		i = encodeVarintEpaxos(dAtA, i, uint64(m.To))
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.From))
 	}
-	dAtA[i] = 0x12
+	if m.Status != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Status))
+	}
+	dAtA[i] = 0x1a
 	i++
-	i = encodeVarintEpaxos(dAtA, i, uint64(m.Ballot.Size()))
-	n6, err := m.Ballot.MarshalTo(dAtA[i:])
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.InstanceData.Size()))
+	n8, err := m.InstanceData.MarshalTo(dAtA[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n6
-	dAtA[i] = 0x1a
+	i += n8
+	dAtA[i] = 0x22
 	i++
-	i = encodeVarintEpaxos(dAtA, i, uint64(m.InstanceID.Size()))
-	n7, err := m.InstanceID.MarshalTo(dAtA[i:])
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.AcceptedBallot.Size()))
+	n9, err := m.AcceptedBallot.MarshalTo(dAtA[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n7
-	if m.Type != nil {
-		nn8, err := m.Type.MarshalTo(dAtA[i:])
-		if err != nil {
-			return 0, err
+	i += n9
+	if m.PreAcceptedUnchanged {
+		dAtA[i] = 0x28
+		i++
+		if m.PreAcceptedUnchanged {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
-		i += nn8
+		i++
 	}
 	return i, nil
 }
 
-func (m *Message_PreAccept) MarshalTo(dAtA []byte) (int, error) {
-	i := 0
-	if m.PreAccept != nil {
-		dAtA[i] = 0x22
-		i++
-		i = encodeVarintEpaxos(dAtA, i, uint64(m.PreAccept.Size()))
-		n9, err := m.PreAccept.MarshalTo(dAtA[i:])
-		if err != nil {
-			return 0, err
-		}
-		i += n9
+func (m *NACK) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
 	}
-	return i, nil
+	return dAtA[:n], nil
+}
+
+func (m *NACK) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	dAtA[i] = 0xa
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.Ballot.Size()))
+	n10, err := m.Ballot.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n10
+	return i, nil
+}
+
+func (m *Read) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Read) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.ReadID != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.ReadID))
+	}
+	if m.From != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.From))
+	}
+	dAtA[i] = 0x1a
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.Span.Size()))
+	n11, err := m.Span.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n11
+	return i, nil
+}
+
+func (m *ReadReply) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ReadReply) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.ReadID != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.ReadID))
+	}
+	if m.From != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.From))
+	}
+	if len(m.Deps) > 0 {
+		for _, msg := range m.Deps {
+			dAtA[i] = 0x1a
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if len(m.TruncatedInstanceNums) > 0 {
+		for k, _ := range m.TruncatedInstanceNums {
+			dAtA[i] = 0x22
+			i++
+			v := m.TruncatedInstanceNums[k]
+			mapSize := 1 + sovEpaxos(uint64(k)) + 1 + sovEpaxos(uint64(v))
+			i = encodeVarintEpaxos(dAtA, i, uint64(mapSize))
+			dAtA[i] = 0x8
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(k))
+			dAtA[i] = 0x10
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(v))
+		}
+	}
+	return i, nil
+}
+
+func (m *Ballot) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Ballot) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Epoch != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Epoch))
+	}
+	if m.Number != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Number))
+	}
+	if m.ReplicaID != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.ReplicaID))
+	}
+	return i, nil
+}
+
+func (m *Message) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.To != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.To))
+	}
+	dAtA[i] = 0x12
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.Ballot.Size()))
+	n12, err := m.Ballot.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n12
+	dAtA[i] = 0x1a
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.InstanceID.Size()))
+	n13, err := m.InstanceID.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n13
+	if m.Type != nil {
+		nn14, err := m.Type.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += nn14
+	}
+	return i, nil
+}
+
+func (m *Message_PreAccept) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.PreAccept != nil {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.PreAccept.Size()))
+		n15, err := m.PreAccept.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n15
+	}
+	return i, nil
 }
 func (m *Message_PreAcceptOk) MarshalTo(dAtA []byte) (int, error) {
 	i := 0
@@ -1048,11 +2100,11 @@ func (m *Message_PreAcceptOk) MarshalTo(dAtA []byte) (int, error) {
 		dAtA[i] = 0x2a
 		i++
 		i = encodeVarintEpaxos(dAtA, i, uint64(m.PreAcceptOk.Size()))
-		n10, err := m.PreAcceptOk.MarshalTo(dAtA[i:])
+		n16, err := m.PreAcceptOk.MarshalTo(dAtA[i:])
 		if err != nil {
 			return 0, err
 		}
-		i += n10
+		i += n16
 	}
 	return i, nil
 }
@@ -1062,11 +2114,11 @@ func (m *Message_PreAcceptReply) MarshalTo(dAtA []byte) (int, error) {
 		dAtA[i] = 0x32
 		i++
 		i = encodeVarintEpaxos(dAtA, i, uint64(m.PreAcceptReply.Size()))
-		n11, err := m.PreAcceptReply.MarshalTo(dAtA[i:])
+		n17, err := m.PreAcceptReply.MarshalTo(dAtA[i:])
 		if err != nil {
 			return 0, err
 		}
-		i += n11
+		i += n17
 	}
 	return i, nil
 }
@@ -1076,11 +2128,11 @@ func (m *Message_Accept) MarshalTo(dAtA []byte) (int, error) {
 		dAtA[i] = 0x3a
 		i++
 		i = encodeVarintEpaxos(dAtA, i, uint64(m.Accept.Size()))
-		n12, err := m.Accept.MarshalTo(dAtA[i:])
+		n18, err := m.Accept.MarshalTo(dAtA[i:])
 		if err != nil {
 			return 0, err
 		}
-		i += n12
+		i += n18
 	}
 	return i, nil
 }
@@ -1090,11 +2142,11 @@ func (m *Message_AcceptOk) MarshalTo(dAtA []byte) (int, error) {
 		dAtA[i] = 0x42
 		i++
 		i = encodeVarintEpaxos(dAtA, i, uint64(m.AcceptOk.Size()))
-		n13, err := m.AcceptOk.MarshalTo(dAtA[i:])
+		n19, err := m.AcceptOk.MarshalTo(dAtA[i:])
 		if err != nil {
 			return 0, err
 		}
-		i += n13
+		i += n19
 	}
 	return i, nil
 }
@@ -1104,11 +2156,81 @@ func (m *Message_Commit) MarshalTo(dAtA []byte) (int, error) {
 		dAtA[i] = 0x4a
 		i++
 		i = encodeVarintEpaxos(dAtA, i, uint64(m.Commit.Size()))
-		n14, err := m.Commit.MarshalTo(dAtA[i:])
+		n20, err := m.Commit.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n20
+	}
+	return i, nil
+}
+func (m *Message_Prepare) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.Prepare != nil {
+		dAtA[i] = 0x52
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Prepare.Size()))
+		n21, err := m.Prepare.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n21
+	}
+	return i, nil
+}
+func (m *Message_PrepareReply) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.PrepareReply != nil {
+		dAtA[i] = 0x5a
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.PrepareReply.Size()))
+		n22, err := m.PrepareReply.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n22
+	}
+	return i, nil
+}
+func (m *Message_Nack) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.Nack != nil {
+		dAtA[i] = 0x62
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Nack.Size()))
+		n23, err := m.Nack.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n23
+	}
+	return i, nil
+}
+func (m *Message_Read) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.Read != nil {
+		dAtA[i] = 0x6a
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Read.Size()))
+		n24, err := m.Read.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n24
+	}
+	return i, nil
+}
+func (m *Message_ReadReply) MarshalTo(dAtA []byte) (int, error) {
+	i := 0
+	if m.ReadReply != nil {
+		dAtA[i] = 0x72
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.ReadReply.Size()))
+		n25, err := m.ReadReply.MarshalTo(dAtA[i:])
 		if err != nil {
 			return 0, err
 		}
-		i += n14
+		i += n25
 	}
 	return i, nil
 }
@@ -1130,11 +2252,11 @@ func (m *InstanceState) MarshalTo(dAtA []byte) (int, error) {
 	dAtA[i] = 0xa
 	i++
 	i = encodeVarintEpaxos(dAtA, i, uint64(m.InstanceID.Size()))
-	n15, err := m.InstanceID.MarshalTo(dAtA[i:])
+	n26, err := m.InstanceID.MarshalTo(dAtA[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n15
+	i += n26
 	if m.Status != 0 {
 		dAtA[i] = 0x10
 		i++
@@ -1143,20 +2265,36 @@ func (m *InstanceState) MarshalTo(dAtA []byte) (int, error) {
 	dAtA[i] = 0x1a
 	i++
 	i = encodeVarintEpaxos(dAtA, i, uint64(m.InstanceData.Size()))
-	n16, err := m.InstanceData.MarshalTo(dAtA[i:])
+	n27, err := m.InstanceData.MarshalTo(dAtA[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n16
-	if m.Ballot != nil {
-		dAtA[i] = 0x22
+	i += n27
+	dAtA[i] = 0x22
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.Ballot.Size()))
+	n28, err := m.Ballot.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n28
+	dAtA[i] = 0x2a
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.AcceptedBallot.Size()))
+	n29, err := m.AcceptedBallot.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n29
+	if m.PreAcceptedUnchanged {
+		dAtA[i] = 0x30
 		i++
-		i = encodeVarintEpaxos(dAtA, i, uint64(m.Ballot.Size()))
-		n17, err := m.Ballot.MarshalTo(dAtA[i:])
-		if err != nil {
-			return 0, err
+		if m.PreAcceptedUnchanged {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
 		}
-		i += n17
+		i++
 	}
 	return i, nil
 }
@@ -1182,32 +2320,233 @@ func (m *HardState) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintEpaxos(dAtA, i, uint64(m.ReplicaID))
 	}
 	if len(m.Nodes) > 0 {
-		dAtA19 := make([]byte, len(m.Nodes)*10)
-		var j18 int
+		dAtA31 := make([]byte, len(m.Nodes)*10)
+		var j30 int
 		for _, num := range m.Nodes {
 			for num >= 1<<7 {
-				dAtA19[j18] = uint8(uint64(num)&0x7f | 0x80)
+				dAtA31[j30] = uint8(uint64(num)&0x7f | 0x80)
 				num >>= 7
-				j18++
+				j30++
 			}
-			dAtA19[j18] = uint8(num)
-			j18++
+			dAtA31[j30] = uint8(num)
+			j30++
 		}
 		dAtA[i] = 0x12
 		i++
-		i = encodeVarintEpaxos(dAtA, i, uint64(j18))
-		i += copy(dAtA[i:], dAtA19[:j18])
+		i = encodeVarintEpaxos(dAtA, i, uint64(j30))
+		i += copy(dAtA[i:], dAtA31[:j30])
+	}
+	if len(m.TruncatedInstanceNums) > 0 {
+		for k, _ := range m.TruncatedInstanceNums {
+			dAtA[i] = 0x1a
+			i++
+			v := m.TruncatedInstanceNums[k]
+			mapSize := 1 + sovEpaxos(uint64(k)) + 1 + sovEpaxos(uint64(v))
+			i = encodeVarintEpaxos(dAtA, i, uint64(mapSize))
+			dAtA[i] = 0x8
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(k))
+			dAtA[i] = 0x10
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(v))
+		}
+	}
+	if m.TruncatedSeqNum != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.TruncatedSeqNum))
+	}
+	if m.Epoch != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Epoch))
+	}
+	dAtA[i] = 0x32
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.ConfChangeInstance.Size()))
+	n32, err := m.ConfChangeInstance.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n32
+	if len(m.Configurations) > 0 {
+		for _, msg := range m.Configurations {
+			dAtA[i] = 0x3a
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
 	}
 	return i, nil
 }
 
-func encodeFixed64Epaxos(dAtA []byte, offset int, v uint64) int {
-	dAtA[offset] = uint8(v)
-	dAtA[offset+1] = uint8(v >> 8)
-	dAtA[offset+2] = uint8(v >> 16)
-	dAtA[offset+3] = uint8(v >> 24)
-	dAtA[offset+4] = uint8(v >> 32)
-	dAtA[offset+5] = uint8(v >> 40)
+func (m *Configuration) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Configuration) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Epoch != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Epoch))
+	}
+	if len(m.Nodes) > 0 {
+		dAtA34 := make([]byte, len(m.Nodes)*10)
+		var j33 int
+		for _, num := range m.Nodes {
+			for num >= 1<<7 {
+				dAtA34[j33] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j33++
+			}
+			dAtA34[j33] = uint8(num)
+			j33++
+		}
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(j33))
+		i += copy(dAtA[i:], dAtA34[:j33])
+	}
+	return i, nil
+}
+
+func (m *SnapshotMetadata) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SnapshotMetadata) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.ExecutedInstanceNums) > 0 {
+		for k, _ := range m.ExecutedInstanceNums {
+			dAtA[i] = 0xa
+			i++
+			v := m.ExecutedInstanceNums[k]
+			mapSize := 1 + sovEpaxos(uint64(k)) + 1 + sovEpaxos(uint64(v))
+			i = encodeVarintEpaxos(dAtA, i, uint64(mapSize))
+			dAtA[i] = 0x8
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(k))
+			dAtA[i] = 0x10
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(v))
+		}
+	}
+	if len(m.ExecutedInstances) > 0 {
+		for _, msg := range m.ExecutedInstances {
+			dAtA[i] = 0x12
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.MaxSeqNum != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.MaxSeqNum))
+	}
+	if len(m.Nodes) > 0 {
+		dAtA36 := make([]byte, len(m.Nodes)*10)
+		var j35 int
+		for _, num := range m.Nodes {
+			for num >= 1<<7 {
+				dAtA36[j35] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j35++
+			}
+			dAtA36[j35] = uint8(num)
+			j35++
+		}
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(j35))
+		i += copy(dAtA[i:], dAtA36[:j35])
+	}
+	if m.Epoch != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(m.Epoch))
+	}
+	if len(m.Configurations) > 0 {
+		for _, msg := range m.Configurations {
+			dAtA[i] = 0x32
+			i++
+			i = encodeVarintEpaxos(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	return i, nil
+}
+
+func (m *Snapshot) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Snapshot) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	dAtA[i] = 0xa
+	i++
+	i = encodeVarintEpaxos(dAtA, i, uint64(m.Metadata.Size()))
+	n37, err := m.Metadata.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n37
+	if len(m.Data) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintEpaxos(dAtA, i, uint64(len(m.Data)))
+		i += copy(dAtA[i:], m.Data)
+	}
+	return i, nil
+}
+
+func encodeFixed64Epaxos(dAtA []byte, offset int, v uint64) int {
+	dAtA[offset] = uint8(v)
+	dAtA[offset+1] = uint8(v >> 8)
+	dAtA[offset+2] = uint8(v >> 16)
+	dAtA[offset+3] = uint8(v >> 24)
+	dAtA[offset+4] = uint8(v >> 32)
+	dAtA[offset+5] = uint8(v >> 40)
 	dAtA[offset+6] = uint8(v >> 48)
 	dAtA[offset+7] = uint8(v >> 56)
 	return offset + 8
@@ -1242,6 +2581,18 @@ func (m *Span) Size() (n int) {
 	return n
 }
 
+func (m *ClientSession) Size() (n int) {
+	var l int
+	_ = l
+	if m.ClientID != 0 {
+		n += 1 + sovEpaxos(uint64(m.ClientID))
+	}
+	if m.SeqNum != 0 {
+		n += 1 + sovEpaxos(uint64(m.SeqNum))
+	}
+	return n
+}
+
 func (m *Command) Size() (n int) {
 	var l int
 	_ = l
@@ -1257,6 +2608,53 @@ func (m *Command) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovEpaxos(uint64(l))
 	}
+	if m.ConfChange != nil {
+		l = m.ConfChange.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	if len(m.Batch) > 0 {
+		for _, e := range m.Batch {
+			l = e.Size()
+			n += 1 + l + sovEpaxos(uint64(l))
+		}
+	}
+	if m.Op != 0 {
+		n += 1 + sovEpaxos(uint64(m.Op))
+	}
+	l = len(m.ExpectedValue)
+	if l > 0 {
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	if len(m.ReadSpans) > 0 {
+		for _, e := range m.ReadSpans {
+			l = e.Size()
+			n += 1 + l + sovEpaxos(uint64(l))
+		}
+	}
+	if len(m.WriteSpans) > 0 {
+		for _, e := range m.WriteSpans {
+			l = e.Size()
+			n += 1 + l + sovEpaxos(uint64(l))
+		}
+	}
+	l = m.Session.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	return n
+}
+
+func (m *ConfChange) Size() (n int) {
+	var l int
+	_ = l
+	if m.Type != 0 {
+		n += 1 + sovEpaxos(uint64(m.Type))
+	}
+	if m.ReplicaID != 0 {
+		n += 1 + sovEpaxos(uint64(m.ReplicaID))
+	}
+	l = len(m.Context)
+	if l > 0 {
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
 	return n
 }
 
@@ -1342,6 +2740,79 @@ func (m *Commit) Size() (n int) {
 	return n
 }
 
+func (m *Prepare) Size() (n int) {
+	var l int
+	_ = l
+	return n
+}
+
+func (m *PrepareReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.From != 0 {
+		n += 1 + sovEpaxos(uint64(m.From))
+	}
+	if m.Status != 0 {
+		n += 1 + sovEpaxos(uint64(m.Status))
+	}
+	l = m.InstanceData.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	l = m.AcceptedBallot.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	if m.PreAcceptedUnchanged {
+		n += 2
+	}
+	return n
+}
+
+func (m *NACK) Size() (n int) {
+	var l int
+	_ = l
+	l = m.Ballot.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	return n
+}
+
+func (m *Read) Size() (n int) {
+	var l int
+	_ = l
+	if m.ReadID != 0 {
+		n += 1 + sovEpaxos(uint64(m.ReadID))
+	}
+	if m.From != 0 {
+		n += 1 + sovEpaxos(uint64(m.From))
+	}
+	l = m.Span.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	return n
+}
+
+func (m *ReadReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.ReadID != 0 {
+		n += 1 + sovEpaxos(uint64(m.ReadID))
+	}
+	if m.From != 0 {
+		n += 1 + sovEpaxos(uint64(m.From))
+	}
+	if len(m.Deps) > 0 {
+		for _, e := range m.Deps {
+			l = e.Size()
+			n += 1 + l + sovEpaxos(uint64(l))
+		}
+	}
+	if len(m.TruncatedInstanceNums) > 0 {
+		for k, v := range m.TruncatedInstanceNums {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + sovEpaxos(uint64(k)) + 1 + sovEpaxos(uint64(v))
+			n += mapEntrySize + 1 + sovEpaxos(uint64(mapEntrySize))
+		}
+	}
+	return n
+}
+
 func (m *Ballot) Size() (n int) {
 	var l int
 	_ = l
@@ -1427,6 +2898,51 @@ func (m *Message_Commit) Size() (n int) {
 	}
 	return n
 }
+func (m *Message_Prepare) Size() (n int) {
+	var l int
+	_ = l
+	if m.Prepare != nil {
+		l = m.Prepare.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_PrepareReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.PrepareReply != nil {
+		l = m.PrepareReply.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_Nack) Size() (n int) {
+	var l int
+	_ = l
+	if m.Nack != nil {
+		l = m.Nack.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_Read) Size() (n int) {
+	var l int
+	_ = l
+	if m.Read != nil {
+		l = m.Read.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
+func (m *Message_ReadReply) Size() (n int) {
+	var l int
+	_ = l
+	if m.ReadReply != nil {
+		l = m.ReadReply.Size()
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
+	return n
+}
 func (m *InstanceState) Size() (n int) {
 	var l int
 	_ = l
@@ -1437,9 +2953,12 @@ func (m *InstanceState) Size() (n int) {
 	}
 	l = m.InstanceData.Size()
 	n += 1 + l + sovEpaxos(uint64(l))
-	if m.Ballot != nil {
-		l = m.Ballot.Size()
-		n += 1 + l + sovEpaxos(uint64(l))
+	l = m.Ballot.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	l = m.AcceptedBallot.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	if m.PreAcceptedUnchanged {
+		n += 2
 	}
 	return n
 }
@@ -1457,6 +2976,95 @@ func (m *HardState) Size() (n int) {
 		}
 		n += 1 + sovEpaxos(uint64(l)) + l
 	}
+	if len(m.TruncatedInstanceNums) > 0 {
+		for k, v := range m.TruncatedInstanceNums {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + sovEpaxos(uint64(k)) + 1 + sovEpaxos(uint64(v))
+			n += mapEntrySize + 1 + sovEpaxos(uint64(mapEntrySize))
+		}
+	}
+	if m.TruncatedSeqNum != 0 {
+		n += 1 + sovEpaxos(uint64(m.TruncatedSeqNum))
+	}
+	if m.Epoch != 0 {
+		n += 1 + sovEpaxos(uint64(m.Epoch))
+	}
+	l = m.ConfChangeInstance.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	if len(m.Configurations) > 0 {
+		for _, e := range m.Configurations {
+			l = e.Size()
+			n += 1 + l + sovEpaxos(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *Configuration) Size() (n int) {
+	var l int
+	_ = l
+	if m.Epoch != 0 {
+		n += 1 + sovEpaxos(uint64(m.Epoch))
+	}
+	if len(m.Nodes) > 0 {
+		l = 0
+		for _, e := range m.Nodes {
+			l += sovEpaxos(uint64(e))
+		}
+		n += 1 + sovEpaxos(uint64(l)) + l
+	}
+	return n
+}
+
+func (m *SnapshotMetadata) Size() (n int) {
+	var l int
+	_ = l
+	if len(m.ExecutedInstanceNums) > 0 {
+		for k, v := range m.ExecutedInstanceNums {
+			_ = k
+			_ = v
+			mapEntrySize := 1 + sovEpaxos(uint64(k)) + 1 + sovEpaxos(uint64(v))
+			n += mapEntrySize + 1 + sovEpaxos(uint64(mapEntrySize))
+		}
+	}
+	if len(m.ExecutedInstances) > 0 {
+		for _, e := range m.ExecutedInstances {
+			l = e.Size()
+			n += 1 + l + sovEpaxos(uint64(l))
+		}
+	}
+	if m.MaxSeqNum != 0 {
+		n += 1 + sovEpaxos(uint64(m.MaxSeqNum))
+	}
+	if len(m.Nodes) > 0 {
+		l = 0
+		for _, e := range m.Nodes {
+			l += sovEpaxos(uint64(e))
+		}
+		n += 1 + sovEpaxos(uint64(l)) + l
+	}
+	if m.Epoch != 0 {
+		n += 1 + sovEpaxos(uint64(m.Epoch))
+	}
+	if len(m.Configurations) > 0 {
+		for _, e := range m.Configurations {
+			l = e.Size()
+			n += 1 + l + sovEpaxos(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *Snapshot) Size() (n int) {
+	var l int
+	_ = l
+	l = m.Metadata.Size()
+	n += 1 + l + sovEpaxos(uint64(l))
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovEpaxos(uint64(l))
+	}
 	return n
 }
 
@@ -1585,7 +3193,7 @@ func (m *Span) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Command) Unmarshal(dAtA []byte) error {
+func (m *ClientSession) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1608,17 +3216,17 @@ func (m *Command) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Command: wiretype end group for non-group")
+			return fmt.Errorf("proto: ClientSession: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Command: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: ClientSession: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ClientID", wireType)
 			}
-			m.ID = 0
+			m.ClientID = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -1628,66 +3236,16 @@ func (m *Command) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ID |= (uint64(b) & 0x7F) << shift
+				m.ClientID |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Span", wireType)
-			}
-			var msglen int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowEpaxos
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			if msglen < 0 {
-				return ErrInvalidLengthEpaxos
-			}
-			postIndex := iNdEx + msglen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			if err := m.Span.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
-		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Writing", wireType)
-			}
-			var v int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowEpaxos
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				v |= (int(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-			m.Writing = bool(v != 0)
-		case 4:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field SeqNum", wireType)
 			}
-			var byteLen int
+			m.SeqNum = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -1697,23 +3255,11 @@ func (m *Command) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				m.SeqNum |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
-				return ErrInvalidLengthEpaxos
-			}
-			postIndex := iNdEx + byteLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
-			if m.Data == nil {
-				m.Data = []byte{}
-			}
-			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipEpaxos(dAtA[iNdEx:])
@@ -1735,7 +3281,7 @@ func (m *Command) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *InstanceID) Unmarshal(dAtA []byte) error {
+func (m *Command) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -1758,17 +3304,17 @@ func (m *InstanceID) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: InstanceID: wiretype end group for non-group")
+			return fmt.Errorf("proto: Command: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: InstanceID: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Command: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
 			}
-			m.ReplicaID = 0
+			m.ID = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -1778,16 +3324,16 @@ func (m *InstanceID) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ReplicaID |= (ReplicaID(b) & 0x7F) << shift
+				m.ID |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field InstanceNum", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Span", wireType)
 			}
-			m.InstanceNum = 0
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -1797,66 +3343,47 @@ func (m *InstanceID) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.InstanceNum |= (InstanceNum(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		default:
-			iNdEx = preIndex
-			skippy, err := skipEpaxos(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthEpaxos
 			}
-			if (iNdEx + skippy) > l {
+			postIndex := iNdEx + msglen
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *InstanceData) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowEpaxos
+			if err := m.Span.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Writing", wireType)
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: InstanceData: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: InstanceData: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			m.Writing = bool(v != 0)
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Command", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
 			}
-			var msglen int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -1866,30 +3393,28 @@ func (m *InstanceData) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				byteLen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthEpaxos
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + byteLen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Command == nil {
-				m.Command = &Command{}
-			}
-			if err := m.Command.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
 			}
 			iNdEx = postIndex
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SeqNum", wireType)
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ConfChange", wireType)
 			}
-			m.SeqNum = 0
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -1899,14 +3424,28 @@ func (m *InstanceData) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.SeqNum |= (SeqNum(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 3:
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.ConfChange == nil {
+				m.ConfChange = &ConfChange{}
+			}
+			if err := m.ConfChange.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 6:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Deps", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Batch", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -1930,64 +3469,64 @@ func (m *InstanceData) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Deps = append(m.Deps, InstanceID{})
-			if err := m.Deps[len(m.Deps)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.Batch = append(m.Batch, Command{})
+			if err := m.Batch[len(m.Batch)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipEpaxos(dAtA[iNdEx:])
-			if err != nil {
-				return err
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Op", wireType)
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthEpaxos
+			m.Op = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Op |= (Command_Op(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ExpectedValue", wireType)
 			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *PreAccept) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowEpaxos
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			if iNdEx >= l {
+			if byteLen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
+			m.ExpectedValue = append(m.ExpectedValue[:0], dAtA[iNdEx:postIndex]...)
+			if m.ExpectedValue == nil {
+				m.ExpectedValue = []byte{}
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: PreAccept: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: PreAccept: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			iNdEx = postIndex
+		case 9:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field InstanceData", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadSpans", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2011,60 +3550,72 @@ func (m *PreAccept) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if err := m.InstanceData.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.ReadSpans = append(m.ReadSpans, Span{})
+			if err := m.ReadSpans[len(m.ReadSpans)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipEpaxos(dAtA[iNdEx:])
-			if err != nil {
-				return err
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field WriteSpans", wireType)
 			}
-			if skippy < 0 {
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
 				return ErrInvalidLengthEpaxos
 			}
-			if (iNdEx + skippy) > l {
+			postIndex := iNdEx + msglen
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *PreAcceptOK) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowEpaxos
+			m.WriteSpans = append(m.WriteSpans, Span{})
+			if err := m.WriteSpans[len(m.WriteSpans)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
-			if iNdEx >= l {
+			iNdEx = postIndex
+		case 11:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Session", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
+			if err := m.Session.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: PreAcceptOK: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: PreAcceptOK: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipEpaxos(dAtA[iNdEx:])
@@ -2086,7 +3637,7 @@ func (m *PreAcceptOK) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *PreAcceptReply) Unmarshal(dAtA []byte) error {
+func (m *ConfChange) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -2109,17 +3660,17 @@ func (m *PreAcceptReply) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: PreAcceptReply: wiretype end group for non-group")
+			return fmt.Errorf("proto: ConfChange: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: PreAcceptReply: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: ConfChange: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UpdatedSeqNum", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
 			}
-			m.UpdatedSeqNum = 0
+			m.Type = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -2129,16 +3680,35 @@ func (m *PreAcceptReply) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.UpdatedSeqNum |= (SeqNum(b) & 0x7F) << shift
+				m.Type |= (ConfChange_Type(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
+			}
+			m.ReplicaID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReplicaID |= (ReplicaID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field UpdatedDeps", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Context", wireType)
 			}
-			var msglen int
+			var byteLen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -2148,21 +3718,21 @@ func (m *PreAcceptReply) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				byteLen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			if byteLen < 0 {
 				return ErrInvalidLengthEpaxos
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + byteLen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.UpdatedDeps = append(m.UpdatedDeps, InstanceID{})
-			if err := m.UpdatedDeps[len(m.UpdatedDeps)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.Context = append(m.Context[:0], dAtA[iNdEx:postIndex]...)
+			if m.Context == nil {
+				m.Context = []byte{}
 			}
 			iNdEx = postIndex
 		default:
@@ -2186,7 +3756,7 @@ func (m *PreAcceptReply) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Accept) Unmarshal(dAtA []byte) error {
+func (m *InstanceID) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -2209,17 +3779,17 @@ func (m *Accept) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Accept: wiretype end group for non-group")
+			return fmt.Errorf("proto: InstanceID: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Accept: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: InstanceID: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field InstanceData", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
 			}
-			var msglen int
+			m.ReplicaID = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -2229,22 +3799,30 @@ func (m *Accept) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				m.ReplicaID |= (ReplicaID(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthEpaxos
-			}
-			postIndex := iNdEx + msglen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceNum", wireType)
 			}
-			if err := m.InstanceData.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.InstanceNum = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.InstanceNum |= (InstanceNum(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipEpaxos(dAtA[iNdEx:])
@@ -2266,7 +3844,7 @@ func (m *Accept) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *AcceptOK) Unmarshal(dAtA []byte) error {
+func (m *InstanceData) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -2289,16 +3867,99 @@ func (m *AcceptOK) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: AcceptOK: wiretype end group for non-group")
+			return fmt.Errorf("proto: InstanceData: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: AcceptOK: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: InstanceData: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
-		default:
-			iNdEx = preIndex
-			skippy, err := skipEpaxos(dAtA[iNdEx:])
-			if err != nil {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Command", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Command == nil {
+				m.Command = &Command{}
+			}
+			if err := m.Command.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SeqNum", wireType)
+			}
+			m.SeqNum = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SeqNum |= (SeqNum(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Deps", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Deps = append(m.Deps, InstanceID{})
+			if err := m.Deps[len(m.Deps)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
 				return err
 			}
 			if skippy < 0 {
@@ -2316,7 +3977,7 @@ func (m *AcceptOK) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Commit) Unmarshal(dAtA []byte) error {
+func (m *PreAccept) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -2339,10 +4000,10 @@ func (m *Commit) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Commit: wiretype end group for non-group")
+			return fmt.Errorf("proto: PreAccept: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Commit: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: PreAccept: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
@@ -2396,7 +4057,7 @@ func (m *Commit) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Ballot) Unmarshal(dAtA []byte) error {
+func (m *PreAcceptOK) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -2419,69 +4080,12 @@ func (m *Ballot) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Ballot: wiretype end group for non-group")
+			return fmt.Errorf("proto: PreAcceptOK: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Ballot: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: PreAcceptOK: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
-		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
-			}
-			m.Epoch = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowEpaxos
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Epoch |= (uint64(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Number", wireType)
-			}
-			m.Number = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowEpaxos
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.Number |= (uint64(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
-		case 3:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
-			}
-			m.ReplicaID = 0
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowEpaxos
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				m.ReplicaID |= (ReplicaID(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
-			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipEpaxos(dAtA[iNdEx:])
@@ -2503,7 +4107,7 @@ func (m *Ballot) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *Message) Unmarshal(dAtA []byte) error {
+func (m *PreAcceptReply) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -2526,17 +4130,17 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Message: wiretype end group for non-group")
+			return fmt.Errorf("proto: PreAcceptReply: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Message: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: PreAcceptReply: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field To", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field UpdatedSeqNum", wireType)
 			}
-			m.To = 0
+			m.UpdatedSeqNum = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -2546,14 +4150,14 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.To |= (ReplicaID(b) & 0x7F) << shift
+				m.UpdatedSeqNum |= (SeqNum(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Ballot", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field UpdatedDeps", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2577,43 +4181,64 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if err := m.Ballot.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.UpdatedDeps = append(m.UpdatedDeps, InstanceID{})
+			if err := m.UpdatedDeps[len(m.UpdatedDeps)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		case 3:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field InstanceID", wireType)
-			}
-			var msglen int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
-					return ErrIntOverflowEpaxos
-				}
-				if iNdEx >= l {
-					return io.ErrUnexpectedEOF
-				}
-				b := dAtA[iNdEx]
-				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
-				if b < 0x80 {
-					break
-				}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
 			}
-			if msglen < 0 {
+			if skippy < 0 {
 				return ErrInvalidLengthEpaxos
 			}
-			postIndex := iNdEx + msglen
-			if postIndex > l {
+			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
-			if err := m.InstanceID.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Accept) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
 			}
-			iNdEx = postIndex
-		case 4:
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Accept: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Accept: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field PreAccept", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceData", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2637,19 +4262,1802 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			v := &PreAccept{}
-			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			if err := m.InstanceData.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
-			m.Type = &Message_PreAccept{v}
 			iNdEx = postIndex
-		case 5:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field PreAcceptOk", wireType)
-			}
-			var msglen int
-			for shift := uint(0); ; shift += 7 {
-				if shift >= 64 {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *AcceptOK) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: AcceptOK: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: AcceptOK: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Commit) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Commit: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Commit: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceData", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.InstanceData.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Prepare) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Prepare: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Prepare: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *PrepareReply) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PrepareReply: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PrepareReply: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field From", wireType)
+			}
+			m.From = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.From |= (ReplicaID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
+			}
+			m.Status = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Status |= (InstanceState_Status(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceData", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.InstanceData.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AcceptedBallot", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.AcceptedBallot.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PreAcceptedUnchanged", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.PreAcceptedUnchanged = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *NACK) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: NACK: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: NACK: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ballot", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Ballot.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Read) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Read: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Read: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadID", wireType)
+			}
+			m.ReadID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReadID |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field From", wireType)
+			}
+			m.From = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.From |= (ReplicaID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Span", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Span.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ReadReply) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ReadReply: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ReadReply: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadID", wireType)
+			}
+			m.ReadID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReadID |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field From", wireType)
+			}
+			m.From = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.From |= (ReplicaID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Deps", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Deps = append(m.Deps, InstanceID{})
+			if err := m.Deps[len(m.Deps)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TruncatedInstanceNums", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.TruncatedInstanceNums == nil {
+				m.TruncatedInstanceNums = make(map[ReplicaID]InstanceNum)
+			}
+			var mapkey uint64
+			var mapvalue uint64
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowEpaxos
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= (uint64(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowEpaxos
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapkey |= (uint64(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+				} else if fieldNum == 2 {
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowEpaxos
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapvalue |= (uint64(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipEpaxos(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthEpaxos
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.TruncatedInstanceNums[ReplicaID(mapkey)] = ((InstanceNum)(mapvalue))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Ballot) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Ballot: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Ballot: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
+			}
+			m.Epoch = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Epoch |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Number", wireType)
+			}
+			m.Number = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Number |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
+			}
+			m.ReplicaID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReplicaID |= (ReplicaID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Message: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Message: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field To", wireType)
+			}
+			m.To = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.To |= (ReplicaID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ballot", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Ballot.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceID", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.InstanceID.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PreAccept", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &PreAccept{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Type = &Message_PreAccept{v}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PreAcceptOk", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &PreAcceptOK{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Type = &Message_PreAcceptOk{v}
+			iNdEx = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PreAcceptReply", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &PreAcceptReply{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Type = &Message_PreAcceptReply{v}
+			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Accept", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &Accept{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Type = &Message_Accept{v}
+			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AcceptOk", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &AcceptOK{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Type = &Message_AcceptOk{v}
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Commit", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &Commit{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Type = &Message_Commit{v}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Prepare", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &Prepare{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Type = &Message_Prepare{v}
+			iNdEx = postIndex
+		case 11:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PrepareReply", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &PrepareReply{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Type = &Message_PrepareReply{v}
+			iNdEx = postIndex
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Nack", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &NACK{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Type = &Message_Nack{v}
+			iNdEx = postIndex
+		case 13:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Read", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &Read{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Type = &Message_Read{v}
+			iNdEx = postIndex
+		case 14:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReadReply", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &ReadReply{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Type = &Message_ReadReply{v}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *InstanceState) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: InstanceState: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: InstanceState: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceID", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.InstanceID.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
+			}
+			m.Status = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Status |= (InstanceState_Status(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceData", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.InstanceData.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ballot", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Ballot.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AcceptedBallot", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.AcceptedBallot.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PreAcceptedUnchanged", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.PreAcceptedUnchanged = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *HardState) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: HardState: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: HardState: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
+			}
+			m.ReplicaID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReplicaID |= (ReplicaID(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType == 0 {
+				var v ReplicaID
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowEpaxos
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= (ReplicaID(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.Nodes = append(m.Nodes, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowEpaxos
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= (int(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthEpaxos
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				for iNdEx < postIndex {
+					var v ReplicaID
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowEpaxos
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= (ReplicaID(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.Nodes = append(m.Nodes, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Nodes", wireType)
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TruncatedInstanceNums", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.TruncatedInstanceNums == nil {
+				m.TruncatedInstanceNums = make(map[ReplicaID]InstanceNum)
+			}
+			var mapkey uint64
+			var mapvalue uint64
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowEpaxos
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= (uint64(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowEpaxos
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapkey |= (uint64(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+				} else if fieldNum == 2 {
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowEpaxos
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapvalue |= (uint64(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipEpaxos(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthEpaxos
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
+			}
+			m.TruncatedInstanceNums[ReplicaID(mapkey)] = ((InstanceNum)(mapvalue))
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TruncatedSeqNum", wireType)
+			}
+			m.TruncatedSeqNum = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
 					return ErrIntOverflowEpaxos
 				}
 				if iNdEx >= l {
@@ -2657,27 +6065,33 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				m.TruncatedSeqNum |= (SeqNum(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthEpaxos
-			}
-			postIndex := iNdEx + msglen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
 			}
-			v := &PreAcceptOK{}
-			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			m.Epoch = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Epoch |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			m.Type = &Message_PreAcceptOk{v}
-			iNdEx = postIndex
 		case 6:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field PreAcceptReply", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ConfChangeInstance", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2701,15 +6115,13 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			v := &PreAcceptReply{}
-			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			if err := m.ConfChangeInstance.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
-			m.Type = &Message_PreAcceptReply{v}
 			iNdEx = postIndex
 		case 7:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Accept", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Configurations", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2733,17 +6145,66 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			v := &Accept{}
-			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.Configurations = append(m.Configurations, Configuration{})
+			if err := m.Configurations[len(m.Configurations)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
-			m.Type = &Message_Accept{v}
 			iNdEx = postIndex
-		case 8:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field AcceptOk", wireType)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
 			}
-			var msglen int
+			if skippy < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Configuration) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Configuration: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Configuration: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
+			}
+			m.Epoch = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -2753,27 +6214,126 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				m.Epoch |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+		case 2:
+			if wireType == 0 {
+				var v ReplicaID
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowEpaxos
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= (ReplicaID(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.Nodes = append(m.Nodes, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowEpaxos
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= (int(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthEpaxos
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				for iNdEx < postIndex {
+					var v ReplicaID
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowEpaxos
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= (ReplicaID(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.Nodes = append(m.Nodes, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Nodes", wireType)
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEpaxos(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
 				return ErrInvalidLengthEpaxos
 			}
-			postIndex := iNdEx + msglen
-			if postIndex > l {
+			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
-			v := &AcceptOK{}
-			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SnapshotMetadata) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEpaxos
 			}
-			m.Type = &Message_AcceptOk{v}
-			iNdEx = postIndex
-		case 9:
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SnapshotMetadata: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SnapshotMetadata: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Commit", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ExecutedInstanceNums", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2797,65 +6357,79 @@ func (m *Message) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			v := &Commit{}
-			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
+			if m.ExecutedInstanceNums == nil {
+				m.ExecutedInstanceNums = make(map[ReplicaID]InstanceNum)
+			}
+			var mapkey uint64
+			var mapvalue uint64
+			for iNdEx < postIndex {
+				entryPreIndex := iNdEx
+				var wire uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowEpaxos
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					wire |= (uint64(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				fieldNum := int32(wire >> 3)
+				if fieldNum == 1 {
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowEpaxos
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapkey |= (uint64(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+				} else if fieldNum == 2 {
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowEpaxos
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						mapvalue |= (uint64(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+				} else {
+					iNdEx = entryPreIndex
+					skippy, err := skipEpaxos(dAtA[iNdEx:])
+					if err != nil {
+						return err
+					}
+					if skippy < 0 {
+						return ErrInvalidLengthEpaxos
+					}
+					if (iNdEx + skippy) > postIndex {
+						return io.ErrUnexpectedEOF
+					}
+					iNdEx += skippy
+				}
 			}
-			m.Type = &Message_Commit{v}
+			m.ExecutedInstanceNums[ReplicaID(mapkey)] = ((InstanceNum)(mapvalue))
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipEpaxos(dAtA[iNdEx:])
-			if err != nil {
-				return err
-			}
-			if skippy < 0 {
-				return ErrInvalidLengthEpaxos
-			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
-			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *InstanceState) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowEpaxos
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
-			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
-			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: InstanceState: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: InstanceState: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+		case 2:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field InstanceID", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ExecutedInstances", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2879,15 +6453,16 @@ func (m *InstanceState) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if err := m.InstanceID.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.ExecutedInstances = append(m.ExecutedInstances, InstanceState{})
+			if err := m.ExecutedInstances[len(m.ExecutedInstances)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		case 2:
+		case 3:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxSeqNum", wireType)
 			}
-			m.Status = 0
+			m.MaxSeqNum = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -2897,16 +6472,78 @@ func (m *InstanceState) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Status |= (InstanceState_Status(b) & 0x7F) << shift
+				m.MaxSeqNum |= (SeqNum(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 3:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field InstanceData", wireType)
+		case 4:
+			if wireType == 0 {
+				var v ReplicaID
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowEpaxos
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= (ReplicaID(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.Nodes = append(m.Nodes, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowEpaxos
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= (int(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthEpaxos
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				for iNdEx < postIndex {
+					var v ReplicaID
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowEpaxos
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= (ReplicaID(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.Nodes = append(m.Nodes, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Nodes", wireType)
 			}
-			var msglen int
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
+			}
+			m.Epoch = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -2916,25 +6553,14 @@ func (m *InstanceState) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				m.Epoch |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
-				return ErrInvalidLengthEpaxos
-			}
-			postIndex := iNdEx + msglen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			if err := m.InstanceData.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
-			iNdEx = postIndex
-		case 4:
+		case 6:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Ballot", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Configurations", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -2958,10 +6584,8 @@ func (m *InstanceState) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Ballot == nil {
-				m.Ballot = &Ballot{}
-			}
-			if err := m.Ballot.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.Configurations = append(m.Configurations, Configuration{})
+			if err := m.Configurations[len(m.Configurations)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
@@ -2986,7 +6610,7 @@ func (m *InstanceState) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *HardState) Unmarshal(dAtA []byte) error {
+func (m *Snapshot) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -3009,17 +6633,17 @@ func (m *HardState) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: HardState: wiretype end group for non-group")
+			return fmt.Errorf("proto: Snapshot: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: HardState: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Snapshot: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Metadata", wireType)
 			}
-			m.ReplicaID = 0
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowEpaxos
@@ -3029,73 +6653,53 @@ func (m *HardState) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ReplicaID |= (ReplicaID(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
+			if msglen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Metadata.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
 		case 2:
-			if wireType == 0 {
-				var v ReplicaID
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowEpaxos
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					v |= (ReplicaID(b) & 0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				m.Nodes = append(m.Nodes, v)
-			} else if wireType == 2 {
-				var packedLen int
-				for shift := uint(0); ; shift += 7 {
-					if shift >= 64 {
-						return ErrIntOverflowEpaxos
-					}
-					if iNdEx >= l {
-						return io.ErrUnexpectedEOF
-					}
-					b := dAtA[iNdEx]
-					iNdEx++
-					packedLen |= (int(b) & 0x7F) << shift
-					if b < 0x80 {
-						break
-					}
-				}
-				if packedLen < 0 {
-					return ErrInvalidLengthEpaxos
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEpaxos
 				}
-				postIndex := iNdEx + packedLen
-				if postIndex > l {
+				if iNdEx >= l {
 					return io.ErrUnexpectedEOF
 				}
-				for iNdEx < postIndex {
-					var v ReplicaID
-					for shift := uint(0); ; shift += 7 {
-						if shift >= 64 {
-							return ErrIntOverflowEpaxos
-						}
-						if iNdEx >= l {
-							return io.ErrUnexpectedEOF
-						}
-						b := dAtA[iNdEx]
-						iNdEx++
-						v |= (ReplicaID(b) & 0x7F) << shift
-						if b < 0x80 {
-							break
-						}
-					}
-					m.Nodes = append(m.Nodes, v)
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
 				}
-			} else {
-				return fmt.Errorf("proto: wrong wireType = %d for field Nodes", wireType)
 			}
+			if byteLen < 0 {
+				return ErrInvalidLengthEpaxos
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipEpaxos(dAtA[iNdEx:])
@@ -3225,59 +6829,110 @@ var (
 func init() { proto.RegisterFile("epaxos.proto", fileDescriptorEpaxos) }
 
 var fileDescriptorEpaxos = []byte{
-	// 850 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x55, 0xcb, 0x8e, 0xe3, 0x44,
-	0x14, 0x8d, 0x1f, 0x5d, 0x49, 0xae, 0x93, 0xee, 0x70, 0x19, 0x5a, 0x66, 0x24, 0xe2, 0x96, 0x67,
-	0x13, 0x0d, 0x22, 0x23, 0xc2, 0x08, 0x09, 0x10, 0x23, 0x4d, 0x08, 0x52, 0x47, 0x2d, 0xba, 0x51,
-	0xf5, 0x07, 0x44, 0x15, 0xbb, 0xc8, 0x58, 0xdd, 0xb1, 0xdd, 0x71, 0x45, 0x4c, 0xc4, 0x82, 0x05,
-	0x3f, 0x30, 0x4b, 0x96, 0xec, 0xf9, 0x91, 0x59, 0xce, 0x17, 0x58, 0x28, 0x7c, 0x00, 0xfb, 0x5e,
-	0xa1, 0xaa, 0xf2, 0x23, 0xfd, 0x40, 0x68, 0x66, 0x56, 0xc9, 0x75, 0x9d, 0x73, 0xeb, 0xdc, 0x53,
-	0xa7, 0x6c, 0xe8, 0xf0, 0x94, 0xbd, 0x4c, 0xb2, 0x61, 0xba, 0x4a, 0x44, 0x82, 0x2d, 0x5d, 0xa5,
-	0xf3, 0x87, 0x9f, 0x2d, 0x22, 0xf1, 0x62, 0x3d, 0x1f, 0x06, 0xc9, 0xf2, 0xc9, 0x22, 0x59, 0x24,
-	0x4f, 0x14, 0x60, 0xbe, 0xfe, 0x49, 0x55, 0xaa, 0x50, 0xff, 0x34, 0xd1, 0x9f, 0x82, 0x7d, 0x9e,
-	0xb2, 0x18, 0x3f, 0x06, 0xeb, 0x82, 0x6f, 0x5c, 0xe3, 0xc8, 0x18, 0x74, 0xc6, 0xcd, 0xeb, 0xdc,
-	0xb3, 0x4e, 0xf8, 0x86, 0xca, 0x67, 0x78, 0x04, 0x4d, 0x1e, 0x87, 0x33, 0xb9, 0x6c, 0xde, 0x5c,
-	0x26, 0x3c, 0x0e, 0x4f, 0xf8, 0xe6, 0x6b, 0xfb, 0xf7, 0x3f, 0xbc, 0x86, 0xff, 0x2b, 0x34, 0xbf,
-	0x4b, 0x96, 0x4b, 0x16, 0x87, 0x78, 0x08, 0x66, 0x14, 0xaa, 0x66, 0xf6, 0x98, 0x6c, 0x73, 0xcf,
-	0x9c, 0x4e, 0xa8, 0x19, 0x85, 0x38, 0x00, 0x3b, 0x4b, 0x59, 0xac, 0xfa, 0x38, 0xa3, 0xfd, 0x61,
-	0xa9, 0x7a, 0x28, 0x35, 0x8c, 0xed, 0xd7, 0xb9, 0xd7, 0xa0, 0x0a, 0x81, 0x2e, 0x34, 0x7f, 0x5e,
-	0x45, 0x22, 0x8a, 0x17, 0xae, 0x75, 0x64, 0x0c, 0x5a, 0xb4, 0x2c, 0x11, 0xc1, 0x0e, 0x99, 0x60,
-	0xae, 0x2d, 0xb5, 0x50, 0xf5, 0xbf, 0x10, 0xf0, 0x0b, 0xc0, 0x34, 0xce, 0x04, 0x8b, 0x03, 0x3e,
-	0x9d, 0xe0, 0x57, 0x00, 0x2b, 0x9e, 0x5e, 0x46, 0x01, 0x9b, 0x55, 0x5a, 0x1e, 0x6e, 0x73, 0xaf,
-	0x4d, 0xf5, 0xd3, 0xe9, 0xe4, 0x7a, 0xb7, 0xa0, 0xed, 0x02, 0x3d, 0x0d, 0x71, 0x04, 0x9d, 0xa8,
-	0x68, 0x34, 0x8b, 0xd7, 0x4b, 0x25, 0xd7, 0x1e, 0x1f, 0x5c, 0xe7, 0x9e, 0x53, 0x6e, 0x70, 0xba,
-	0x5e, 0x52, 0x27, 0xaa, 0x0b, 0xff, 0x95, 0x01, 0x9d, 0x72, 0x71, 0xc2, 0x04, 0xc3, 0x4f, 0xa1,
-	0x19, 0x68, 0x3b, 0xd4, 0xe6, 0xce, 0xe8, 0x83, 0x7a, 0xdc, 0xc2, 0x27, 0x5a, 0x22, 0xf0, 0x11,
-	0x34, 0x33, 0x7e, 0xb5, 0xb3, 0x19, 0x5c, 0xe7, 0x1e, 0x39, 0xe7, 0x57, 0x72, 0x1f, 0x92, 0xa9,
-	0x5f, 0x1c, 0x82, 0x1d, 0xf2, 0x34, 0x73, 0xad, 0x23, 0x6b, 0xe0, 0x8c, 0x1e, 0xd4, 0xed, 0xea,
-	0xa9, 0x4b, 0x0f, 0x25, 0xce, 0x7f, 0x0e, 0xed, 0x1f, 0x57, 0xfc, 0x79, 0x10, 0xf0, 0x54, 0xe0,
-	0xd3, 0xc2, 0x36, 0xad, 0xe5, 0xf0, 0x2e, 0x59, 0x8a, 0x1e, 0xb7, 0x24, 0xfd, 0x4d, 0xee, 0x19,
-	0xda, 0x58, 0xbf, 0x0b, 0x4e, 0xd5, 0xe2, 0xec, 0xc4, 0xff, 0xcd, 0x80, 0xfd, 0xaa, 0x96, 0xd6,
-	0x6d, 0x70, 0x04, 0x07, 0xeb, 0x34, 0x64, 0x82, 0x87, 0xb3, 0x72, 0x02, 0xe3, 0xce, 0x04, 0xdd,
-	0x02, 0xa2, 0x4b, 0xfc, 0x16, 0x3a, 0x25, 0x47, 0x0d, 0x64, 0xfe, 0xef, 0x40, 0x4e, 0x81, 0x9f,
-	0xc8, 0xb9, 0x9e, 0x01, 0x79, 0xaf, 0xa1, 0x00, 0x5a, 0xd5, 0x44, 0xcf, 0x80, 0xc8, 0xc3, 0x88,
-	0xde, 0xb5, 0xd7, 0x15, 0x90, 0x31, 0xbb, 0xbc, 0x4c, 0x04, 0x3e, 0x80, 0x3d, 0x9e, 0x26, 0xc1,
-	0x0b, 0x3d, 0x3e, 0xd5, 0x05, 0x1e, 0x02, 0x89, 0xd7, 0xcb, 0x39, 0x5f, 0xe9, 0x73, 0xa5, 0x45,
-	0x75, 0x2b, 0x9d, 0xd6, 0x5b, 0xa4, 0xd3, 0xff, 0xc7, 0x82, 0xe6, 0x0f, 0x3c, 0xcb, 0xd8, 0x82,
-	0xe3, 0x27, 0x60, 0x8a, 0xa4, 0x30, 0xbc, 0x7b, 0x93, 0x61, 0x8a, 0x04, 0x87, 0x40, 0xe6, 0x4a,
-	0x5d, 0x71, 0xe3, 0x7a, 0xf5, 0x54, 0x5a, 0x75, 0x61, 0x6f, 0x81, 0xc2, 0x29, 0x54, 0x99, 0x2e,
-	0x65, 0xfd, 0xd7, 0xb9, 0xa0, 0x24, 0x6e, 0x73, 0x6f, 0xe7, 0xca, 0x51, 0x28, 0xc9, 0xd3, 0x10,
-	0x9f, 0x02, 0xa4, 0x2b, 0x3e, 0x63, 0xca, 0x68, 0x75, 0x59, 0x9d, 0xd1, 0x87, 0x75, 0xa7, 0x2a,
-	0x45, 0xc7, 0x0d, 0xda, 0x4e, 0xab, 0x94, 0x7e, 0x03, 0xdd, 0x9a, 0x35, 0x4b, 0x2e, 0xdc, 0x3d,
-	0x45, 0xfc, 0xe8, 0x1e, 0xe2, 0xd9, 0xc9, 0x71, 0x83, 0x3a, 0x15, 0xf5, 0xec, 0x02, 0x27, 0xd0,
-	0xdb, 0x21, 0x4b, 0xc3, 0x36, 0x2e, 0x51, 0x7c, 0xf7, 0x1e, 0xbe, 0x8a, 0xef, 0x71, 0x83, 0xee,
-	0xa7, 0x37, 0x03, 0xfd, 0x18, 0x48, 0x21, 0xba, 0x79, 0xdb, 0xb3, 0x4a, 0x71, 0x81, 0xc0, 0xcf,
-	0xa1, 0x5d, 0x4b, 0x6d, 0x29, 0x38, 0xde, 0x86, 0x2b, 0x9d, 0x2d, 0x56, 0x8a, 0x7c, 0x0c, 0x24,
-	0x50, 0x81, 0x73, 0xdb, 0xb7, 0xdb, 0xeb, 0x20, 0xca, 0xf6, 0x1a, 0x31, 0x26, 0x60, 0x8b, 0x4d,
-	0xca, 0xfd, 0x3f, 0x4d, 0xe8, 0x96, 0x36, 0x9f, 0x0b, 0x26, 0x38, 0x8e, 0xc0, 0x5e, 0xf2, 0x2a,
-	0xac, 0xf7, 0x9f, 0xd0, 0x4e, 0x54, 0x25, 0x16, 0xbf, 0x04, 0x92, 0x09, 0x26, 0xd6, 0x99, 0x0a,
-	0xc3, 0xfe, 0xa8, 0x7f, 0x97, 0xa5, 0x9a, 0x0f, 0xcf, 0x15, 0x8a, 0x16, 0xe8, 0xea, 0x62, 0x58,
-	0x6f, 0x73, 0x31, 0x70, 0x50, 0x45, 0xcf, 0xbe, 0x3f, 0x7a, 0x65, 0xe8, 0xfc, 0x53, 0x20, 0x7a,
-	0x47, 0x6c, 0x81, 0x7d, 0x9a, 0xc4, 0xbc, 0xd7, 0xc0, 0x83, 0x9d, 0xf7, 0x0e, 0x0f, 0x7b, 0x06,
-	0x76, 0xca, 0x3b, 0xcb, 0xc3, 0x9e, 0x89, 0x5d, 0x68, 0x6b, 0xb3, 0x64, 0x69, 0xc9, 0xc5, 0xef,
-	0x5f, 0xf2, 0x60, 0x2d, 0x2b, 0xdb, 0xbf, 0x80, 0xf6, 0x31, 0x5b, 0x85, 0xda, 0xa8, 0xf7, 0xf8,
-	0x0a, 0x3c, 0x82, 0xbd, 0x38, 0x09, 0xb9, 0x7e, 0x3d, 0xdd, 0xb9, 0x5e, 0x7a, 0x6d, 0xdc, 0x7b,
-	0xbd, 0xed, 0x1b, 0x6f, 0xb6, 0x7d, 0xe3, 0xaf, 0x6d, 0xdf, 0x78, 0xf5, 0x77, 0xbf, 0x31, 0x27,
-	0xea, 0xc3, 0xfa, 0xc5, 0xbf, 0x01, 0x00, 0x00, 0xff, 0xff, 0x8a, 0x35, 0x07, 0x98, 0xa1, 0x07,
-	0x00, 0x00,
+	// 1674 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x58, 0x4b, 0x6f, 0xdb, 0x56,
+	0x16, 0x16, 0x1f, 0xa2, 0xa4, 0xa3, 0x87, 0xe5, 0x3b, 0x8e, 0xcd, 0x18, 0x33, 0x96, 0x87, 0xc9,
+	0x00, 0x9e, 0x0c, 0xa2, 0x60, 0x14, 0x4f, 0x66, 0x26, 0x33, 0x49, 0x61, 0x59, 0x46, 0xa4, 0x3a,
+	0xb1, 0x0d, 0x2a, 0xed, 0xaa, 0x80, 0x40, 0x91, 0xd7, 0x36, 0x61, 0x8b, 0x64, 0x44, 0x2a, 0x91,
+	0xd0, 0x5d, 0xdb, 0x45, 0xd1, 0x55, 0x56, 0x45, 0x77, 0xcd, 0xb2, 0x7f, 0xa0, 0xff, 0x21, 0xcb,
+	0x2c, 0xba, 0x56, 0x0a, 0xf5, 0x5f, 0x78, 0x55, 0xdc, 0x7b, 0xf9, 0x32, 0x25, 0x39, 0x71, 0x62,
+	0x74, 0x65, 0x9e, 0x7b, 0xcf, 0x39, 0xf7, 0x3c, 0xbe, 0xf3, 0x90, 0xa1, 0x80, 0x1d, 0x6d, 0x68,
+	0xbb, 0x55, 0xa7, 0x6f, 0x7b, 0x36, 0xca, 0x32, 0xca, 0xe9, 0xae, 0xde, 0x3e, 0x32, 0xbd, 0xe3,
+	0x41, 0xb7, 0xaa, 0xdb, 0xbd, 0x3b, 0x47, 0xf6, 0x91, 0x7d, 0x87, 0x32, 0x74, 0x07, 0x87, 0x94,
+	0xa2, 0x04, 0xfd, 0x62, 0x82, 0x4a, 0x0b, 0xc4, 0xb6, 0xa3, 0x59, 0xe8, 0x3a, 0x08, 0x27, 0x78,
+	0x24, 0x73, 0xeb, 0xdc, 0x46, 0xa1, 0x9e, 0x39, 0x1b, 0x57, 0x84, 0x5d, 0x3c, 0x52, 0xc9, 0x19,
+	0x5a, 0x87, 0x0c, 0xb6, 0x8c, 0x0e, 0xb9, 0xe6, 0xcf, 0x5f, 0x4b, 0xd8, 0x32, 0x76, 0xf1, 0xe8,
+	0xbe, 0xf8, 0xc3, 0xab, 0x4a, 0x4a, 0x69, 0x43, 0x71, 0xfb, 0xd4, 0xc4, 0x96, 0xd7, 0xc6, 0xae,
+	0x6b, 0xda, 0x16, 0xfa, 0x3b, 0xe4, 0x74, 0x7a, 0xd0, 0x31, 0x0d, 0xaa, 0x59, 0xac, 0x17, 0x26,
+	0xe3, 0x4a, 0x96, 0x71, 0xb5, 0x1a, 0x6a, 0x96, 0x5d, 0xb7, 0x0c, 0xb4, 0x02, 0x19, 0x17, 0x3f,
+	0xeb, 0x58, 0x83, 0x1e, 0x7d, 0x43, 0x54, 0x25, 0x17, 0x3f, 0xdb, 0x1b, 0xf4, 0x94, 0x57, 0x22,
+	0x64, 0xb6, 0xed, 0x5e, 0x4f, 0xb3, 0x0c, 0xb4, 0x0c, 0x7c, 0xa8, 0x48, 0x9a, 0x8c, 0x2b, 0x7c,
+	0xab, 0xa1, 0xf2, 0xa6, 0x81, 0x36, 0x40, 0x74, 0x1d, 0xcd, 0xa2, 0x92, 0xf9, 0x5a, 0xa9, 0x1a,
+	0xc4, 0xa2, 0x4a, 0x3c, 0xab, 0x8b, 0xaf, 0xc7, 0x95, 0x94, 0x4a, 0x39, 0x90, 0x0c, 0x99, 0x17,
+	0x7d, 0xd3, 0x33, 0xad, 0x23, 0x59, 0x58, 0xe7, 0x36, 0xb2, 0x6a, 0x40, 0x22, 0x04, 0xa2, 0xa1,
+	0x79, 0x9a, 0x2c, 0x12, 0x0f, 0x55, 0xfa, 0x8d, 0xfe, 0x05, 0x79, 0xdd, 0xb6, 0x0e, 0x3b, 0xfa,
+	0xb1, 0x66, 0x1d, 0x61, 0x39, 0x4d, 0xd5, 0x2f, 0x45, 0xea, 0xb7, 0x6d, 0xeb, 0x70, 0x9b, 0xde,
+	0xa9, 0xa0, 0x87, 0xdf, 0xe8, 0x36, 0xa4, 0xbb, 0x9a, 0xa7, 0x1f, 0xcb, 0xd2, 0xba, 0xb0, 0x91,
+	0xaf, 0x2d, 0xc6, 0x05, 0xa8, 0x23, 0xbe, 0x49, 0x8c, 0x0b, 0xdd, 0x04, 0xde, 0x76, 0xe4, 0xcc,
+	0x3a, 0xb7, 0x51, 0xaa, 0x2d, 0x4d, 0xf1, 0x56, 0xf7, 0x1d, 0x95, 0xb7, 0x1d, 0xf4, 0x37, 0x28,
+	0xe1, 0xa1, 0x83, 0x75, 0x0f, 0x1b, 0x9d, 0xe7, 0xda, 0xe9, 0x00, 0xcb, 0x59, 0x6a, 0x69, 0x31,
+	0x38, 0xfd, 0x9c, 0x1c, 0xa2, 0xbb, 0x00, 0x7d, 0xac, 0x19, 0x1d, 0xe2, 0xad, 0x2b, 0xe7, 0xd6,
+	0x85, 0xb9, 0x01, 0xc9, 0x11, 0x3e, 0x42, 0xbb, 0xc4, 0x4f, 0x12, 0x06, 0xec, 0x4b, 0xc1, 0x05,
+	0x52, 0x40, 0x19, 0x99, 0xd8, 0xbf, 0x49, 0xce, 0x68, 0xa6, 0xe5, 0x3c, 0x0d, 0xcd, 0x4a, 0xcc,
+	0xfa, 0x38, 0x10, 0x7c, 0xd9, 0x80, 0x5b, 0x79, 0x04, 0xfc, 0xbe, 0x83, 0x72, 0x90, 0x3e, 0x38,
+	0xd5, 0x4c, 0xab, 0x9c, 0x42, 0x08, 0x4a, 0xdb, 0x76, 0xcf, 0xd1, 0xfa, 0x78, 0xcb, 0x32, 0xda,
+	0x2f, 0x34, 0xa7, 0xcc, 0xa1, 0x05, 0xc8, 0x1f, 0x0c, 0xbc, 0xd6, 0xe1, 0x56, 0xd7, 0xc5, 0x96,
+	0x57, 0xe6, 0xd1, 0x22, 0x14, 0x77, 0x86, 0x8e, 0xd9, 0xc7, 0xbe, 0xd6, 0xb2, 0xe0, 0xe3, 0xee,
+	0x67, 0x0e, 0x60, 0x3b, 0x1e, 0x7e, 0xd1, 0x1b, 0x39, 0x98, 0xe2, 0xa4, 0x54, 0xbb, 0x3e, 0x2b,
+	0x5d, 0xd5, 0xa7, 0x23, 0x07, 0xab, 0x94, 0x0d, 0xfd, 0x97, 0x44, 0xcc, 0x39, 0x35, 0x75, 0x8d,
+	0xa0, 0x94, 0x82, 0xaf, 0xbe, 0x3a, 0x19, 0x57, 0x72, 0x2a, 0x3b, 0x6d, 0x35, 0xce, 0xe2, 0x04,
+	0x89, 0x1b, 0xfb, 0x34, 0x08, 0x9a, 0x74, 0xdb, 0xf2, 0xf0, 0xd0, 0xa3, 0x68, 0x2a, 0xa8, 0x01,
+	0xa9, 0xdc, 0x00, 0x91, 0x3c, 0x81, 0xf2, 0x90, 0xd9, 0x32, 0x8c, 0x3d, 0xdb, 0xc0, 0xe5, 0x14,
+	0x2a, 0x01, 0xa8, 0xb8, 0x67, 0x3f, 0xc7, 0x94, 0xe6, 0x94, 0x2f, 0x01, 0x5a, 0x96, 0xeb, 0x69,
+	0x96, 0x8e, 0x5b, 0x8d, 0x84, 0x1d, 0xdc, 0x65, 0xec, 0xa8, 0x41, 0xc1, 0xf4, 0x15, 0x45, 0x15,
+	0x54, 0x5f, 0x38, 0x1b, 0x57, 0xf2, 0xc1, 0x03, 0x7b, 0x83, 0x9e, 0x9a, 0x37, 0x23, 0x42, 0x79,
+	0xc9, 0x41, 0x21, 0xb8, 0x6c, 0x10, 0xb0, 0xff, 0x83, 0x38, 0x43, 0x21, 0x47, 0x1f, 0x9f, 0x85,
+	0x5b, 0x35, 0xe0, 0x40, 0x37, 0x12, 0xe5, 0x5a, 0x87, 0xb3, 0x71, 0x45, 0x6a, 0xd3, 0x92, 0x0d,
+	0x4a, 0x17, 0x55, 0x41, 0x34, 0xb0, 0xe3, 0xca, 0x02, 0xc5, 0x53, 0x0c, 0xda, 0x91, 0xd7, 0x41,
+	0x71, 0x12, 0x3e, 0x65, 0x0b, 0x72, 0x07, 0x7d, 0xbc, 0xa5, 0xeb, 0xd8, 0xf1, 0xd0, 0xa6, 0x5f,
+	0x8f, 0xcc, 0x96, 0xe5, 0x69, 0x61, 0x62, 0x74, 0x3d, 0x4b, 0xc4, 0xdf, 0x8c, 0x2b, 0x1c, 0xab,
+	0x58, 0xa5, 0x08, 0xf9, 0x50, 0xc5, 0xfe, 0xae, 0xf2, 0x35, 0x07, 0xa5, 0x90, 0x26, 0xa1, 0x1b,
+	0xa1, 0x1a, 0x2c, 0x0c, 0x1c, 0x43, 0x23, 0x65, 0x14, 0x78, 0xc0, 0x4d, 0x79, 0x50, 0xf4, 0x59,
+	0x18, 0x89, 0x1e, 0x40, 0x21, 0x90, 0xa1, 0x0e, 0xf1, 0xef, 0x74, 0x28, 0xef, 0xf3, 0x37, 0x88,
+	0x5f, 0x0f, 0x41, 0xfa, 0x28, 0xa7, 0x00, 0xb2, 0xa1, 0x47, 0x0f, 0x41, 0x22, 0xc9, 0x30, 0x3f,
+	0x54, 0x57, 0x0e, 0x32, 0x07, 0x7d, 0x4c, 0x2a, 0x4d, 0xf9, 0x9e, 0x87, 0x82, 0xff, 0xcd, 0x42,
+	0xf3, 0x57, 0x10, 0x0f, 0xfb, 0x76, 0x10, 0x8f, 0xe2, 0x79, 0xb8, 0xd1, 0x2b, 0x74, 0x0f, 0x24,
+	0xd7, 0xd3, 0xbc, 0x81, 0x4b, 0xd3, 0x5e, 0xaa, 0xad, 0x4d, 0x3f, 0xdb, 0xf6, 0x34, 0x0f, 0x57,
+	0xdb, 0x94, 0x4b, 0xf5, 0xb9, 0x43, 0x63, 0x85, 0xcb, 0x18, 0x8b, 0x3e, 0x81, 0x05, 0x8d, 0x3a,
+	0x8e, 0x8d, 0x4e, 0x57, 0x3b, 0x3d, 0xb5, 0x3d, 0xda, 0x9e, 0xf3, 0xb5, 0x72, 0xa4, 0xa0, 0x4e,
+	0xcf, 0xfd, 0xb0, 0x97, 0x02, 0x76, 0x76, 0x8a, 0x36, 0x61, 0xd9, 0xe9, 0xe3, 0x4e, 0xa8, 0x64,
+	0x60, 0xb1, 0x56, 0x6e, 0xd0, 0x5e, 0x9e, 0x55, 0x97, 0x9c, 0x00, 0x1c, 0xd8, 0xf8, 0x2c, 0xb8,
+	0x53, 0xee, 0x81, 0xb8, 0xb7, 0xb5, 0xbd, 0x8b, 0xaa, 0x20, 0xf9, 0xaf, 0x72, 0x17, 0xbe, 0xea,
+	0x73, 0x29, 0x43, 0x10, 0x55, 0xac, 0xd1, 0xe2, 0xa0, 0x3d, 0x38, 0x2c, 0x63, 0x98, 0x8c, 0x2b,
+	0x12, 0xb9, 0x6a, 0x35, 0x54, 0x89, 0x5c, 0xb5, 0x8c, 0x30, 0xd8, 0xfc, 0xfc, 0x60, 0x07, 0x63,
+	0x4d, 0x78, 0xd7, 0x58, 0x53, 0x7e, 0xe1, 0x21, 0x47, 0xf4, 0xb3, 0x3c, 0x5e, 0xd5, 0xfb, 0x97,
+	0xac, 0x5f, 0xf4, 0x0d, 0x07, 0x2b, 0x5e, 0x7f, 0x60, 0xe9, 0xb4, 0x52, 0xe2, 0x1d, 0xc9, 0x95,
+	0x45, 0xaa, 0xa3, 0x1a, 0xe9, 0x08, 0xcd, 0xad, 0x3e, 0x0d, 0x44, 0x62, 0xbd, 0xca, 0xdd, 0xb1,
+	0xbc, 0xfe, 0xa8, 0xfe, 0xe7, 0xaf, 0xde, 0xc6, 0xcc, 0xfa, 0xee, 0xed, 0xf9, 0x7e, 0x76, 0xcd,
+	0x9b, 0x25, 0xb9, 0xda, 0x84, 0xd5, 0xf9, 0x2a, 0x51, 0x39, 0xda, 0x73, 0x44, 0xb6, 0xde, 0x2c,
+	0x41, 0x9a, 0x0d, 0x54, 0xb6, 0x78, 0x30, 0xe2, 0x3e, 0xff, 0x1f, 0x4e, 0x79, 0x06, 0x92, 0x0f,
+	0xa4, 0x25, 0x48, 0x63, 0xc7, 0xd6, 0x8f, 0x7d, 0x39, 0x46, 0xa0, 0x65, 0x90, 0xac, 0x41, 0xaf,
+	0x8b, 0xfb, 0xc1, 0xce, 0xc2, 0xa8, 0x44, 0x2b, 0x17, 0x2e, 0xd1, 0xca, 0x95, 0xb7, 0x69, 0xc8,
+	0x3c, 0xc1, 0xae, 0xab, 0x1d, 0x61, 0xf4, 0x17, 0xe0, 0x3d, 0x7b, 0x76, 0x35, 0xf2, 0x9e, 0x1d,
+	0x83, 0x27, 0xff, 0x3e, 0xf0, 0x44, 0x2d, 0x08, 0x07, 0x40, 0x60, 0xd6, 0xbc, 0xac, 0x22, 0x22,
+	0x38, 0x19, 0x57, 0x62, 0xf3, 0x49, 0x85, 0x40, 0xb8, 0x65, 0xa0, 0x4d, 0x80, 0xa8, 0xae, 0xfc,
+	0x9a, 0xfc, 0x53, 0xa4, 0x29, 0x6c, 0xb9, 0xcd, 0x94, 0x9a, 0x0b, 0x4b, 0x0c, 0xfd, 0x0f, 0x8a,
+	0x91, 0x54, 0xc7, 0x3e, 0xf1, 0x17, 0xaa, 0x6b, 0x33, 0x04, 0xf7, 0x77, 0x9b, 0x29, 0x35, 0x1f,
+	0x8a, 0xee, 0x9f, 0xa0, 0x06, 0x94, 0x63, 0xc2, 0x24, 0x60, 0x23, 0x59, 0xa2, 0xf2, 0xf2, 0x0c,
+	0x79, 0x8a, 0xac, 0x66, 0x4a, 0x2d, 0x39, 0xe7, 0x4e, 0xd0, 0x2d, 0x90, 0x7c, 0xa3, 0x33, 0xc9,
+	0x98, 0x85, 0x16, 0xfb, 0x1c, 0xe8, 0x9f, 0x90, 0x8b, 0x4c, 0xcd, 0x52, 0x76, 0x94, 0x64, 0xa7,
+	0x76, 0x66, 0xb5, 0xc0, 0xc8, 0x5b, 0x20, 0xe9, 0xb4, 0x3b, 0xcb, 0xb9, 0xa4, 0x7a, 0xd6, 0xb5,
+	0x89, 0x7a, 0xc6, 0x81, 0x6e, 0x43, 0xc6, 0x61, 0xdd, 0x57, 0x86, 0xe4, 0xbc, 0xf5, 0xdb, 0x72,
+	0x33, 0xa5, 0x06, 0x3c, 0xe8, 0x01, 0x14, 0xfd, 0x4f, 0xdf, 0xf9, 0x7c, 0xb2, 0x95, 0xc6, 0x7b,
+	0x79, 0x33, 0xa5, 0x16, 0x9c, 0x18, 0x8d, 0x6e, 0x82, 0x68, 0x69, 0xfa, 0x89, 0x5c, 0x48, 0xf6,
+	0x12, 0xd2, 0xe9, 0x9a, 0x29, 0x95, 0xde, 0x12, 0x2e, 0xd2, 0x1e, 0xe4, 0x62, 0x92, 0x8b, 0x54,
+	0x2b, 0xe1, 0x22, 0xb7, 0x24, 0xfb, 0xe4, 0xaf, 0x6f, 0x47, 0x29, 0x99, 0xfd, 0xb0, 0xb2, 0x9b,
+	0xfe, 0x92, 0x49, 0x89, 0xba, 0xc4, 0xd6, 0x32, 0xe5, 0x27, 0x01, 0x8a, 0xe7, 0x66, 0x05, 0xaa,
+	0x81, 0xd8, 0xc3, 0xe1, 0x24, 0x9b, 0x8d, 0xc8, 0xd8, 0x68, 0x20, 0xbc, 0x7f, 0xf0, 0x20, 0x8a,
+	0x4a, 0x4d, 0x7c, 0xaf, 0x52, 0x9b, 0x31, 0xb8, 0xd2, 0x57, 0x34, 0xb8, 0xa4, 0x0b, 0x06, 0xd7,
+	0x1e, 0x48, 0xcc, 0x5d, 0x94, 0x05, 0x71, 0xcf, 0xb6, 0xc8, 0xd2, 0xb9, 0x10, 0xdb, 0x88, 0xb0,
+	0x51, 0xe6, 0x50, 0x21, 0xd8, 0x26, 0xb0, 0x51, 0xe6, 0x51, 0x11, 0x72, 0x0c, 0x99, 0x84, 0x14,
+	0xc8, 0xe5, 0xce, 0x10, 0xeb, 0x03, 0x42, 0x89, 0xca, 0x8f, 0x22, 0xe4, 0x9a, 0x5a, 0xdf, 0x60,
+	0x69, 0xfa, 0x88, 0x05, 0xf5, 0x06, 0xa4, 0x2d, 0xdb, 0xc0, 0x6c, 0x73, 0x9a, 0x6a, 0x66, 0xec,
+	0xee, 0xc2, 0xf1, 0x21, 0x24, 0xc7, 0x47, 0x68, 0xd6, 0x55, 0x8f, 0x0f, 0x74, 0x0f, 0x16, 0x23,
+	0x2b, 0x82, 0x15, 0x51, 0x9c, 0x5a, 0x11, 0x17, 0x42, 0x26, 0x76, 0x10, 0x8d, 0x88, 0x74, 0x7c,
+	0x44, 0x3c, 0x86, 0xa5, 0xd8, 0x4f, 0xc8, 0xd0, 0x2b, 0x59, 0xba, 0x00, 0xeb, 0x0c, 0x12, 0x28,
+	0xfa, 0x45, 0x19, 0xdc, 0xa1, 0x1d, 0x28, 0x91, 0x53, 0xf3, 0x68, 0xd0, 0xd7, 0x3c, 0xd3, 0xb6,
+	0x5c, 0x39, 0xb3, 0x2e, 0x24, 0x7e, 0x78, 0xc5, 0xef, 0x03, 0x74, 0x9d, 0x17, 0xba, 0xc2, 0x09,
+	0xf9, 0x29, 0x14, 0xcf, 0x3d, 0x38, 0x67, 0x50, 0xbe, 0x4f, 0xfe, 0x95, 0x89, 0x00, 0xe5, 0xb6,
+	0xa5, 0x39, 0xee, 0xb1, 0xed, 0x3d, 0xc1, 0x9e, 0x46, 0x2b, 0xef, 0x5b, 0x0e, 0x96, 0xb1, 0x8f,
+	0xc8, 0x04, 0x26, 0x38, 0xea, 0xfa, 0x66, 0x6c, 0x2d, 0x4a, 0x08, 0x57, 0x03, 0x28, 0x5f, 0x16,
+	0x19, 0x4b, 0x78, 0x86, 0x20, 0x7a, 0x0c, 0x68, 0xca, 0x92, 0xe0, 0xb7, 0xc0, 0xca, 0x9c, 0xf6,
+	0xe3, 0x27, 0x60, 0x31, 0xa9, 0xd0, 0x45, 0xb7, 0x20, 0xdf, 0xd3, 0x86, 0x21, 0xc0, 0x84, 0x29,
+	0x80, 0xe5, 0x7a, 0xda, 0x90, 0x7d, 0x46, 0xe1, 0x13, 0x2f, 0x28, 0x9f, 0xd9, 0xf8, 0x9b, 0x46,
+	0x8c, 0xf4, 0x21, 0x88, 0x79, 0x04, 0xd7, 0xe7, 0x06, 0xf3, 0x52, 0x80, 0xf9, 0x02, 0xb2, 0x41,
+	0x9a, 0xd0, 0xff, 0x21, 0xdb, 0xf3, 0x53, 0xe5, 0xf7, 0xfe, 0xd5, 0xf9, 0xc9, 0xf4, 0x0d, 0x0b,
+	0x25, 0xc2, 0x7f, 0xd8, 0xf0, 0xd1, 0x3f, 0x6c, 0xea, 0xe5, 0xd7, 0x93, 0x35, 0xee, 0xcd, 0x64,
+	0x8d, 0xfb, 0x75, 0xb2, 0xc6, 0xbd, 0xfc, 0x6d, 0x2d, 0xd5, 0x95, 0xe8, 0x7f, 0xb9, 0xee, 0xfe,
+	0x3e, 0x00, 0x37, 0x51, 0x4e, 0xb2, 0x2e, 0x13, 0x00, 0x00,
 }
@@ -56,6 +56,21 @@ func (b Ballot) Compare(b2 Ballot) int {
 	return 0
 }
 
+// IsInitial returns whether the Ballot is the initial ballot of an instance.
+// The initial ballot implicitly belongs to the replica that proposed the
+// instance.
+func (b Ballot) IsInitial() bool {
+	return b.Number == 0
+}
+
+// Leader returns the replica that leads the provided instance at the Ballot.
+func (b Ballot) Leader(id InstanceID) ReplicaID {
+	if b.IsInitial() {
+		return id.ReplicaID
+	}
+	return b.ReplicaID
+}
+
 // WithDestination returns the message with the provided destination.
 func (msg Message) WithDestination(dest ReplicaID) Message {
 	msg.To = dest
@@ -77,6 +92,16 @@ func WrapMessageInner(msg proto.Message) isMessage_Type {
 		return &Message_AcceptOk{AcceptOk: t}
 	case *Commit:
 		return &Message_Commit{Commit: t}
+	case *Prepare:
+		return &Message_Prepare{Prepare: t}
+	case *PrepareReply:
+		return &Message_PrepareReply{PrepareReply: t}
+	case *NACK:
+		return &Message_Nack{Nack: t}
+	case *Read:
+		return &Message_Read{Read: t}
+	case *ReadReply:
+		return &Message_ReadReply{ReadReply: t}
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in WrapMessageInner", t))
 	}
@@ -94,8 +119,41 @@ func IsReply(t isMessage_Type) bool {
 	case *Message_PreAcceptOk:
 	case *Message_PreAcceptReply:
 	case *Message_AcceptOk:
+	case *Message_PrepareReply:
+	case *Message_Nack:
+	case *Message_ReadReply:
 	default:
 		return false
 	}
 	return true
 }
+
+// TypeName returns the name of the message type, like "PreAccept".
+func TypeName(t isMessage_Type) string {
+	switch t.(type) {
+	case *Message_PreAccept:
+		return "PreAccept"
+	case *Message_PreAcceptOk:
+		return "PreAcceptOK"
+	case *Message_PreAcceptReply:
+		return "PreAcceptReply"
+	case *Message_Accept:
+		return "Accept"
+	case *Message_AcceptOk:
+		return "AcceptOK"
+	case *Message_Commit:
+		return "Commit"
+	case *Message_Prepare:
+		return "Prepare"
+	case *Message_PrepareReply:
+		return "PrepareReply"
+	case *Message_Nack:
+		return "NACK"
+	case *Message_Read:
+		return "Read"
+	case *Message_ReadReply:
+		return "ReadReply"
+	default:
+		return fmt.Sprintf("%T", t)
+	}
+}
@@ -0,0 +1,361 @@
+// Package epaxostest provides an in-memory, deterministic simulation of an
+// EPaxos network, for testing applications that are built on epaxos.
+package epaxostest
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/mjolk/epx2/epaxos"
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+// Application is the replicated state machine of a simulated replica.
+type Application interface {
+	// Apply applies an executed command to the state machine. Commands are
+	// applied in the order that the replica executes them, which includes
+	// reads issued with Network.Read.
+	Apply(cmd pb.Command)
+	// Snapshot returns an encoding of the state machine, which is sent to
+	// replicas that have fallen behind.
+	Snapshot() []byte
+	// Restore replaces the state machine with one encoded by Snapshot.
+	Restore(data []byte)
+}
+
+// Config configures a Network.
+type Config struct {
+	// Nodes is the number of replicas in the Network. The replicas have the
+	// IDs 0 through Nodes-1.
+	Nodes int
+	// Seed seeds all random decisions made by the Network and its replicas,
+	// so that a simulation with the same Seed and the same inputs runs the
+	// same way.
+	Seed int64
+	// Reorder, if set, makes the Network deliver the messages sent in each
+	// tick in a random order instead of in the order they were sent.
+	Reorder bool
+	// Configure, if set, modifies the epaxos.Config of each replica before it
+	// is started. It is also called when a replica restarts, but a restarted
+	// replica always recovers from the Storage that it used before.
+	Configure func(*epaxos.Config)
+	// NewApplication, if set, creates the Application of each replica. The
+	// Application survives restarts of the replica, like its Storage.
+	NewApplication func(id pb.ReplicaID) Application
+	// OnCommit, if set, is called with each command that is committed on a
+	// replica.
+	OnCommit func(id pb.ReplicaID, cc epaxos.CommittedCommand)
+}
+
+// Peer is a replica in a simulated Network.
+type Peer struct {
+	ID      pb.ReplicaID
+	Node    *epaxos.RawNode
+	Storage epaxos.Storage
+	// App is the replica's Application. It is nil if the Network was not
+	// configured with NewApplication.
+	App Application
+
+	crashed bool
+}
+
+type conn struct {
+	from, to pb.ReplicaID
+}
+
+// Network is a simulated network of epaxos replicas. Replicas are driven by a
+// deterministic scheduler: each call to Tick ticks all live replicas, hands
+// out their Readys, and delivers the messages and snapshots that they send.
+// Messages sent while handling a tick are delivered by the next Tick.
+//
+// A Network is not safe for concurrent use.
+type Network struct {
+	c           Config
+	ids         []pb.ReplicaID
+	peers       map[pb.ReplicaID]*Peer
+	dropm       map[conn]float64
+	interceptor func(from pb.ReplicaID, m pb.Message) bool
+	rand        *rand.Rand
+
+	// msgs holds the messages that will be delivered by the next Tick.
+	msgs []pb.Message
+	// snaps holds the snapshots that will be delivered by the next Tick.
+	snaps []pb.Snapshot
+	// snapsTo holds the recipients of snaps.
+	snapsTo []pb.ReplicaID
+}
+
+// NewNetwork creates a Network and starts all of its replicas.
+func NewNetwork(c Config) (*Network, error) {
+	if c.Nodes <= 0 {
+		return nil, fmt.Errorf("epaxostest: invalid number of nodes %d", c.Nodes)
+	}
+	n := &Network{
+		c:     c,
+		ids:   make([]pb.ReplicaID, c.Nodes),
+		peers: make(map[pb.ReplicaID]*Peer, c.Nodes),
+		dropm: make(map[conn]float64),
+		rand:  rand.New(rand.NewSource(c.Seed)),
+	}
+	for i := range n.ids {
+		n.ids[i] = pb.ReplicaID(i)
+	}
+	for _, id := range n.ids {
+		p := &Peer{ID: id}
+		if c.NewApplication != nil {
+			p.App = c.NewApplication(id)
+		}
+		if err := n.start(p); err != nil {
+			return nil, err
+		}
+		n.peers[id] = p
+	}
+	return n, nil
+}
+
+// start starts a new RawNode for the peer on top of its Storage.
+func (n *Network) start(p *Peer) error {
+	c := &epaxos.Config{
+		ID:       p.ID,
+		Nodes:    n.ids,
+		Storage:  p.Storage,
+		RandSeed: n.c.Seed + int64(p.ID) + 1,
+	}
+	if n.c.Configure != nil {
+		n.c.Configure(c)
+	}
+	if p.Storage != nil {
+		c.Storage = p.Storage
+	}
+	rn, err := epaxos.NewRawNode(c)
+	if err != nil {
+		return fmt.Errorf("epaxostest: starting replica %d: %v", p.ID, err)
+	}
+	p.Node = rn
+	p.Storage = c.Storage
+	return nil
+}
+
+// Peer returns the replica with the ID, or nil if there is no such replica.
+func (n *Network) Peer(id pb.ReplicaID) *Peer {
+	return n.peers[id]
+}
+
+// Peers returns all replicas, in order of their IDs.
+func (n *Network) Peers() []*Peer {
+	peers := make([]*Peer, len(n.ids))
+	for i, id := range n.ids {
+		peers[i] = n.peers[id]
+	}
+	return peers
+}
+
+// Propose proposes the command on the replica. It is ignored if the replica
+// has crashed.
+func (n *Network) Propose(id pb.ReplicaID, cmd pb.Command) {
+	if p := n.peers[id]; !p.crashed {
+		p.Node.Propose(cmd)
+	}
+}
+
+// Read performs a linearizable read of the command on the replica. It is
+// ignored if the replica has crashed.
+func (n *Network) Read(id pb.ReplicaID, cmd pb.Command) error {
+	if p := n.peers[id]; !p.crashed {
+		return p.Node.Read(cmd)
+	}
+	return nil
+}
+
+// Crash stops the replica. It does not tick or receive messages and snapshots
+// until it is revived or restarted.
+func (n *Network) Crash(id pb.ReplicaID) {
+	n.peers[id].crashed = true
+}
+
+// Revive resumes a crashed replica with its state intact.
+func (n *Network) Revive(id pb.ReplicaID) {
+	n.peers[id].crashed = false
+}
+
+// Restart replaces the replica with a new one that recovers from its Storage,
+// losing all state that was not persisted. A crashed replica is revived.
+func (n *Network) Restart(id pb.ReplicaID) error {
+	p := n.peers[id]
+	if err := n.start(p); err != nil {
+		return err
+	}
+	p.crashed = false
+	return nil
+}
+
+// Alive returns whether the replica has not crashed.
+func (n *Network) Alive(id pb.ReplicaID) bool {
+	return !n.peers[id].crashed
+}
+
+// Drop drops the given fraction of the messages and snapshots sent from one
+// replica to another.
+func (n *Network) Drop(from, to pb.ReplicaID, perc float64) {
+	n.dropm[conn{from: from, to: to}] = perc
+}
+
+// DropForAll drops the given fraction of the messages and snapshots sent
+// between all replicas.
+func (n *Network) DropForAll(perc float64) {
+	for _, from := range n.ids {
+		for _, to := range n.ids {
+			if from != to {
+				n.Drop(from, to, perc)
+			}
+		}
+	}
+}
+
+// Cut drops all messages and snapshots between the two replicas.
+func (n *Network) Cut(one, other pb.ReplicaID) {
+	n.Drop(one, other, 1.0)
+	n.Drop(other, one, 1.0)
+}
+
+// Isolate cuts the replica off from all other replicas.
+func (n *Network) Isolate(id pb.ReplicaID) {
+	for _, other := range n.ids {
+		if other != id {
+			n.Cut(id, other)
+		}
+	}
+}
+
+// Heal stops dropping messages and snapshots between all replicas.
+func (n *Network) Heal() {
+	n.dropm = make(map[conn]float64)
+}
+
+// SetInterceptor sets a function that is called with each message that is
+// sent, before it is considered for dropping. The message is dropped if the
+// function returns false.
+func (n *Network) SetInterceptor(f func(from pb.ReplicaID, m pb.Message) bool) {
+	n.interceptor = f
+}
+
+// dropped decides whether a message or snapshot between the two replicas is
+// dropped.
+func (n *Network) dropped(from, to pb.ReplicaID) bool {
+	perc := n.dropm[conn{from: from, to: to}]
+	return perc > 0 && n.rand.Float64() < perc
+}
+
+// Tick runs the Network for a single tick. It panics if the Storage of a
+// replica fails.
+func (n *Network) Tick() {
+	msgs, snaps, snapsTo := n.msgs, n.snaps, n.snapsTo
+	n.msgs, n.snaps, n.snapsTo = nil, nil, nil
+	if n.c.Reorder {
+		n.rand.Shuffle(len(msgs), func(i, j int) { msgs[i], msgs[j] = msgs[j], msgs[i] })
+	}
+	for _, m := range msgs {
+		if p, ok := n.peers[m.To]; ok && !p.crashed {
+			p.Node.Step(m)
+		}
+	}
+	for i, snap := range snaps {
+		if p, ok := n.peers[snapsTo[i]]; ok && !p.crashed {
+			p.Node.ApplySnapshot(snap)
+		}
+	}
+
+	for _, id := range n.ids {
+		if p := n.peers[id]; !p.crashed {
+			p.Node.Tick()
+			n.handleReady(p)
+		}
+	}
+}
+
+// handleReady handles the replica's Ready the way that an application does,
+// and queues its messages and snapshots to be delivered.
+func (n *Network) handleReady(p *Peer) {
+	if !p.Node.HasReady() {
+		return
+	}
+	rd := p.Node.Ready()
+	if rd.HardState != nil {
+		if err := p.Storage.PersistHardState(*rd.HardState); err != nil {
+			panic(fmt.Sprintf("epaxostest: persisting HardState of replica %d: %v", p.ID, err))
+		}
+	}
+	for i := range rd.InstanceStates {
+		if err := p.Storage.PersistInstance(&rd.InstanceStates[i]); err != nil {
+			panic(fmt.Sprintf("epaxostest: persisting instance of replica %d: %v", p.ID, err))
+		}
+	}
+	for r, i := range rd.TruncatedInstances {
+		if err := p.Storage.TruncateInstances(r, i); err != nil {
+			panic(fmt.Sprintf("epaxostest: truncating instances of replica %d: %v", p.ID, err))
+		}
+	}
+	for _, m := range rd.Messages {
+		if n.interceptor != nil && !n.interceptor(p.ID, m) {
+			continue
+		}
+		if !n.dropped(p.ID, m.To) {
+			n.msgs = append(n.msgs, m)
+		}
+	}
+	if rd.Snapshot != nil && p.App != nil {
+		p.App.Restore(rd.Snapshot.Data)
+	}
+	if n.c.OnCommit != nil {
+		for _, cc := range rd.CommittedCommands {
+			n.c.OnCommit(p.ID, cc)
+		}
+	}
+	if p.App != nil {
+		for _, cmd := range rd.ExecutedCommands {
+			p.App.Apply(cmd)
+		}
+	}
+	for _, req := range rd.SendSnapshots {
+		if n.dropped(p.ID, req.To) {
+			continue
+		}
+		snap := pb.Snapshot{Metadata: req.Metadata}
+		if p.App != nil {
+			snap.Data = p.App.Snapshot()
+		}
+		n.snaps = append(n.snaps, snap)
+		n.snapsTo = append(n.snapsTo, req.To)
+	}
+	if err := p.Node.Advance(); err != nil {
+		panic(fmt.Sprintf("epaxostest: advancing replica %d: %v", p.ID, err))
+	}
+}
+
+// RunFor runs the Network for up to maxTicks ticks, until the condition is
+// satisfied. It returns whether the condition was satisfied.
+func (n *Network) RunFor(maxTicks int, cond func() bool) bool {
+	for i := 0; i < maxTicks; i++ {
+		n.Tick()
+		if cond() {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of live replicas that satisfy the predicate.
+func (n *Network) Count(pred func(*Peer) bool) int {
+	count := 0
+	for _, id := range n.ids {
+		if p := n.peers[id]; !p.crashed && pred(p) {
+			count++
+		}
+	}
+	return count
+}
+
+// AllAlive returns whether all live replicas satisfy the predicate.
+func (n *Network) AllAlive(pred func(*Peer) bool) bool {
+	return n.Count(pred) == n.Count(func(*Peer) bool { return true })
+}
@@ -0,0 +1,194 @@
+package epaxostest
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/mjolk/epx2/epaxos"
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+// kvApp is a key-value store that applies writes to single keys.
+type kvApp struct {
+	kvs     map[string]string
+	applied map[uint64]struct{}
+}
+
+func newKVApp(pb.ReplicaID) Application {
+	return &kvApp{kvs: make(map[string]string), applied: make(map[uint64]struct{})}
+}
+
+func (a *kvApp) Apply(cmd pb.Command) {
+	a.applied[cmd.ID] = struct{}{}
+	if cmd.Writing {
+		a.kvs[string(cmd.Span.Key)] = string(cmd.Data)
+	}
+}
+
+func (a *kvApp) Snapshot() []byte {
+	data, err := json.Marshal(a.kvs)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func (a *kvApp) Restore(data []byte) {
+	a.kvs = make(map[string]string)
+	if err := json.Unmarshal(data, &a.kvs); err != nil {
+		panic(err)
+	}
+}
+
+func newWrite(id uint64, key, value string) pb.Command {
+	return pb.Command{
+		ID:      id,
+		Span:    pb.Span{Key: pb.Key(key)},
+		Writing: true,
+		Data:    []byte(value),
+	}
+}
+
+func newTestingNetwork(t *testing.T, c Config) *Network {
+	c.NewApplication = newKVApp
+	n, err := NewNetwork(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// appliedAll returns a predicate that is satisfied by replicas that applied
+// all commands with the IDs.
+func appliedAll(ids ...uint64) func(*Peer) bool {
+	return func(p *Peer) bool {
+		for _, id := range ids {
+			if _, ok := p.App.(*kvApp).applied[id]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func assertConsistent(t *testing.T, n *Network) {
+	t.Helper()
+	var exp map[string]string
+	for _, p := range n.Peers() {
+		kvs := p.App.(*kvApp).kvs
+		if exp == nil {
+			exp = kvs
+		} else if !reflect.DeepEqual(kvs, exp) {
+			t.Errorf("replica %d: expected state %v, found %v", p.ID, exp, kvs)
+		}
+	}
+}
+
+// TestNetworkReplicatesCommands verifies that commands proposed on every
+// replica are applied in the same order on all of them.
+func TestNetworkReplicatesCommands(t *testing.T) {
+	n := newTestingNetwork(t, Config{Nodes: 3})
+	for _, p := range n.Peers() {
+		n.Propose(p.ID, newWrite(uint64(p.ID)+1, "a", string('x'+rune(p.ID))))
+	}
+	if !n.RunFor(20, func() bool { return n.AllAlive(appliedAll(1, 2, 3)) }) {
+		t.Fatalf("commands never applied on all replicas")
+	}
+	assertConsistent(t, n)
+}
+
+// TestNetworkOnCommit verifies that the OnCommit hook is called for committed
+// commands on every replica.
+func TestNetworkOnCommit(t *testing.T) {
+	committed := make(map[pb.ReplicaID][]uint64)
+	n := newTestingNetwork(t, Config{
+		Nodes: 3,
+		OnCommit: func(id pb.ReplicaID, cc epaxos.CommittedCommand) {
+			committed[id] = append(committed[id], cc.Command.ID)
+		},
+	})
+	n.Propose(0, newWrite(1, "a", "x"))
+	if !n.RunFor(20, func() bool { return len(committed) == 3 }) {
+		t.Fatalf("command never committed on all replicas, found %v", committed)
+	}
+	for id, ids := range committed {
+		if e := []uint64{1}; !reflect.DeepEqual(ids, e) {
+			t.Errorf("replica %d: expected committed commands %v, found %v", id, e, ids)
+		}
+	}
+}
+
+// TestNetworkIsolate verifies that a quorum makes progress while a replica is
+// isolated, and that the replica catches up once the network heals.
+func TestNetworkIsolate(t *testing.T) {
+	n := newTestingNetwork(t, Config{Nodes: 3})
+	n.Isolate(2)
+	n.Propose(0, newWrite(1, "a", "x"))
+	if !n.RunFor(20, func() bool { return n.Count(appliedAll(1)) == 2 }) {
+		t.Fatalf("command never applied on a quorum")
+	}
+	if appliedAll(1)(n.Peer(2)) {
+		t.Fatalf("expected isolated replica not to apply command")
+	}
+
+	n.Heal()
+	n.Propose(2, newWrite(2, "a", "y"))
+	if !n.RunFor(100, func() bool { return n.AllAlive(appliedAll(1, 2)) }) {
+		t.Fatalf("isolated replica never caught up")
+	}
+	assertConsistent(t, n)
+}
+
+// TestNetworkRestart verifies that a replica that crashes and restarts
+// recovers from its Storage and keeps applying commands.
+func TestNetworkRestart(t *testing.T) {
+	n := newTestingNetwork(t, Config{Nodes: 3})
+	n.Propose(0, newWrite(1, "a", "x"))
+	if !n.RunFor(20, func() bool { return n.AllAlive(appliedAll(1)) }) {
+		t.Fatalf("command never applied on all replicas")
+	}
+
+	n.Crash(1)
+	n.Propose(0, newWrite(2, "a", "y"))
+	if !n.RunFor(20, func() bool { return n.AllAlive(appliedAll(2)) }) {
+		t.Fatalf("command never applied on live replicas")
+	}
+	if err := n.Restart(1); err != nil {
+		t.Fatal(err)
+	}
+	n.Propose(1, newWrite(3, "a", "z"))
+	if !n.RunFor(100, func() bool { return n.AllAlive(appliedAll(1, 2, 3)) }) {
+		t.Fatalf("restarted replica never caught up")
+	}
+	assertConsistent(t, n)
+}
+
+// TestNetworkDeterministic verifies that two simulations with the same Seed
+// send the same messages, even when messages are dropped and reordered.
+func TestNetworkDeterministic(t *testing.T) {
+	run := func() []pb.Message {
+		var msgs []pb.Message
+		n := newTestingNetwork(t, Config{Nodes: 5, Seed: 42, Reorder: true})
+		n.SetInterceptor(func(_ pb.ReplicaID, m pb.Message) bool {
+			msgs = append(msgs, m)
+			return true
+		})
+		n.DropForAll(0.1)
+		for i := 0; i < 10; i++ {
+			id := pb.ReplicaID(i % 5)
+			n.Propose(id, newWrite(uint64(i)+1, string('a'+rune(i%3)), "v"))
+			n.Tick()
+		}
+		n.RunFor(50, func() bool { return false })
+		return msgs
+	}
+	first, second := run(), run()
+	if len(first) == 0 {
+		t.Fatalf("expected messages to be sent")
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected identical simulations to send the same %d messages, found %d",
+			len(first), len(second))
+	}
+}
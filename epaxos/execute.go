@@ -36,16 +36,35 @@ type history interface {
 }
 
 // executor is responsible for executing executables in-order, based on their
-// dependencies. On each run of the executor, the executor topologically sorts
-// all executables and executes those that are able to be executed.
+// dependencies. The executor works incrementally. On each run, it only
+// considers executables that were added since the last run or whose
+// dependencies have changed since then. These executables are topologically
+// sorted and those that are able to be executed are executed.
+//
+// Executables that are not able to be executed are blocked on one of their
+// dependencies that has not been executed yet. They are not considered again
+// until that dependency is added to the executor, executes, or is unblocked
+// itself, at which point they are woken up. Blocked executables therefore do
+// not cost anything on runs of the executor until they may be able to execute.
 type executor struct {
 	h history
 
+	// vertices holds all executables that have been added to the executor
+	// and have not yet executed.
+	vertices map[executableID]*tarjanNode
+	// dirty holds all vertices that need to be considered on the next run of
+	// the executor. Every vertex is either dirty or blocked.
+	dirty []*tarjanNode
+	// waiters maps the identifiers of executables that have not yet executed
+	// to the blocked vertices that are waiting on them. These are the reverse
+	// dependency edges of the graph.
+	waiters map[executableID][]*tarjanNode
+
 	// values scoped to a single run of the executor's tarjan's strongly
 	// connected components algorithm.
-	vertices   map[executableID]*tarjanNode
 	index      int
 	stack      []*tarjanNode
+	visited    []*tarjanNode
 	components []scc
 }
 
@@ -53,21 +72,26 @@ func makeExecutor(h history) executor {
 	return executor{
 		h:        h,
 		vertices: make(map[executableID]*tarjanNode),
+		waiters:  make(map[executableID][]*tarjanNode),
 	}
 }
 
 func (e *executor) run() {
-	// Separate the strongly connected components while reverse topologically
-	// sorting.
-	comps := e.strongConnect()
+	// Executing vertices wakes up the vertices that are waiting on them, so
+	// keep going until no vertices are dirty.
+	for len(e.dirty) > 0 {
+		// Separate the strongly connected components while reverse
+		// topologically sorting.
+		comps := e.strongConnect()
+
+		// Execute each strongly connected component, in-order.
+		for _, comp := range comps {
+			e.executeSCC(comp)
+		}
 
-	// Execute each strongly connected component, in-order.
-	for _, comp := range comps {
-		e.executeSCC(comp)
+		// Reset the executor.
+		e.reset()
 	}
-
-	// Reset the executor.
-	e.reset()
 }
 
 func (e *executor) addExecs(execs ...executable) {
@@ -77,34 +101,85 @@ func (e *executor) addExecs(execs ...executable) {
 }
 
 func (e *executor) addExec(exec executable) {
-	e.vertices[exec.Identifier()] = &tarjanNode{exec: exec}
+	id := exec.Identifier()
+	v := &tarjanNode{exec: exec, index: -1, lowlink: -1}
+	e.vertices[id] = v
+	e.markDirty(v)
+	e.wake(id)
+}
+
+// removeExec removes the executable from the executor without executing it.
+// This is used when the executable is known to have executed elsewhere.
+func (e *executor) removeExec(id executableID) {
+	delete(e.vertices, id)
+	e.wake(id)
+}
+
+// pending returns whether the vertex is still waiting to be executed.
+func (e *executor) pending(v *tarjanNode) bool {
+	return e.vertices[v.exec.Identifier()] == v
+}
+
+func (e *executor) markDirty(v *tarjanNode) {
+	if !v.dirty {
+		v.dirty = true
+		e.dirty = append(e.dirty, v)
+	}
+}
+
+// block blocks all vertices in the strongly connected component on the
+// provided executable, which has not executed yet.
+func (e *executor) block(comp scc, on executableID) {
+	for _, v := range comp {
+		v.blocked = true
+	}
+	e.waiters[on] = append(e.waiters[on], comp...)
+}
+
+// wake wakes up all vertices that are blocked on the provided executable,
+// which has been added to the executor, has executed, or has been woken up
+// itself. Vertices that are woken up also wake up the vertices blocked on
+// them, because they may be part of the same strongly connected component.
+func (e *executor) wake(id executableID) {
+	ids := []executableID{id}
+	for len(ids) > 0 {
+		id, ids = ids[len(ids)-1], ids[:len(ids)-1]
+		for _, v := range e.waiters[id] {
+			if !v.blocked || !e.pending(v) {
+				continue
+			}
+			v.blocked = false
+			e.markDirty(v)
+			ids = append(ids, v.exec.Identifier())
+		}
+		delete(e.waiters, id)
+	}
 }
 
 func (e *executor) reset() {
+	for _, v := range e.visited {
+		v.index = -1
+		v.lowlink = -1
+	}
 	e.index = 0
 	e.stack = e.stack[:0]
+	e.visited = e.visited[:0]
 	e.components = e.components[:0]
 }
 
 type tarjanNode struct {
 	exec executable
 
-	// depNodes holds all dependencies of the instance that were also
-	// tarjanNodes in the current graph.
-	depNodes []*tarjanNode
+	// dirty is set while the vertex is in the executor's dirty set.
+	dirty bool
+	// blocked is set while the vertex is waiting on a dependency.
+	blocked bool
 
 	index   int
 	lowlink int
 	onStack bool
 }
 
-func (v *tarjanNode) reset() {
-	v.depNodes = v.depNodes[:0]
-	v.index = -1
-	v.lowlink = -1
-	v.onStack = false
-}
-
 func (v *tarjanNode) visited() bool {
 	return v.index >= 0
 }
@@ -121,20 +196,19 @@ func (comp scc) contains(v *tarjanNode) bool {
 }
 
 // strongConnect runs the Tarjan's strongly connected components algorithm,
-// returning an ordered slice of strongly connected components.
+// starting from each dirty vertex and returning an ordered slice of strongly
+// connected components. Blocked vertices are not traversed. Any vertex that
+// depends on a blocked vertex can not execute before it, so it will be blocked
+// by executeSCC as well.
 func (e *executor) strongConnect() []scc {
-	for _, v := range e.vertices {
-		v.reset()
-
-		for _, dep := range v.exec.Dependencies() {
-			if w, ok := e.vertices[dep]; ok {
-				v.depNodes = append(v.depNodes, w)
-			}
-		}
+	dirty := e.dirty
+	e.dirty = nil
+	for _, v := range dirty {
+		v.dirty = false
 	}
 
-	for _, v := range e.vertices {
-		if !v.visited() {
+	for _, v := range dirty {
+		if e.pending(v) && !v.blocked && !v.visited() {
 			e.visit(v)
 		}
 	}
@@ -157,11 +231,16 @@ func (e *executor) visit(v *tarjanNode) {
 	v.index = e.index
 	v.lowlink = e.index
 	e.index++
+	e.visited = append(e.visited, v)
 
 	v.onStack = true
 	e.push(v)
 
-	for _, w := range v.depNodes {
+	for _, dep := range v.exec.Dependencies() {
+		w, ok := e.vertices[dep]
+		if !ok || w.blocked {
+			continue
+		}
 		if !w.visited() {
 			e.visit(w)
 			v.lowlink = min(v.lowlink, w.lowlink)
@@ -186,8 +265,8 @@ func (e *executor) executeSCC(comp scc) {
 		for _, dep := range v.exec.Dependencies() {
 			// The dependency should either be in this strongly connected
 			// component or have already been executed (possibly by an earlier
-			// SCC). If those conditions are not true, abort executing the
-			// entire SCC.
+			// SCC). If those conditions are not true, block the entire SCC on
+			// the dependency.
 			if w, ok := e.vertices[dep]; ok && comp.contains(w) {
 				// The dependency is in this SCC.
 				continue
@@ -195,6 +274,7 @@ func (e *executor) executeSCC(comp scc) {
 			if !e.h.HasExecuted(dep) {
 				// The dependency is not in this SCC and has not been executed
 				// in a prerequisite SCC. We cannot execute at this time.
+				e.block(comp, dep)
 				return
 			}
 		}
@@ -212,8 +292,10 @@ func (e *executor) executeSCC(comp scc) {
 }
 
 func (e *executor) execute(exec executable) {
-	delete(e.vertices, exec.Identifier())
+	id := exec.Identifier()
+	delete(e.vertices, id)
 	exec.Execute()
+	e.wake(id)
 }
 
 func min(a, b int) int {
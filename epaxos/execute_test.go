@@ -299,3 +299,180 @@ func TestExecuteStronglyConnectedComponent(t *testing.T) {
 		})
 	}
 }
+
+// countingHistory is a historySet that counts calls to HasExecuted.
+type countingHistory struct {
+	historySet
+	calls int
+}
+
+func (h *countingHistory) HasExecuted(e executableID) bool {
+	h.calls++
+	return h.historySet.HasExecuted(e)
+}
+
+// TestExecutorRunsOnlyAfterChanges tests that the executor does not attempt to
+// execute blocked executables again until executables have been added to or
+// removed from it.
+func TestExecutorRunsOnlyAfterChanges(t *testing.T) {
+	h := &countingHistory{historySet: make(historySet)}
+	e := makeExecutor(h)
+
+	var executed []int
+	onExecute := func(id int) {
+		h.SetExecuted(id)
+		executed = append(executed, id)
+	}
+	e.addExec(execNode{id: 1, deps: []int{0}, onExecute: onExecute})
+	e.run()
+	if len(executed) != 0 || h.calls == 0 {
+		t.Fatalf("expected blocked executable to be checked but not executed")
+	}
+
+	calls := h.calls
+	e.run()
+	if h.calls != calls {
+		t.Errorf("expected run without changes to be skipped")
+	}
+
+	// The dependency executes elsewhere.
+	h.SetExecuted(0)
+	e.removeExec(0)
+	e.run()
+	if a, exp := executed, []int{1}; !reflect.DeepEqual(a, exp) {
+		t.Errorf("expected execution %v, found %v", exp, a)
+	}
+}
+
+// TestExecutorWakesBlockedExecutables tests that executables that are blocked
+// on dependencies that have not been executed are executed once those
+// dependencies are, regardless of the order in which they are added.
+func TestExecutorWakesBlockedExecutables(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		adds      []execNode
+		execution []int
+	}{
+		{
+			desc: "chain",
+			adds: []execNode{
+				{id: 3, deps: []int{2}},
+				{id: 2, deps: []int{1}},
+				{id: 1, deps: []int{0}},
+				{id: 0},
+			},
+			execution: []int{0, 1, 2, 3},
+		},
+		{
+			desc: "cycle blocked on missing dependency",
+			adds: []execNode{
+				{id: 2, deps: []int{1, 0}},
+				{id: 1, deps: []int{2}},
+				{id: 0},
+			},
+			execution: []int{0, 1, 2},
+		},
+		{
+			desc: "cycle blocked through blocked dependency",
+			adds: []execNode{
+				{id: 3, deps: []int{2}},
+				{id: 2, deps: []int{0, 4}},
+				{id: 4, deps: []int{3}},
+				{id: 1, deps: []int{4}},
+				{id: 0},
+			},
+			execution: []int{0, 2, 3, 4, 1},
+		},
+		{
+			desc: "dependent added after blocked cycle",
+			adds: []execNode{
+				{id: 1, deps: []int{2, 0}},
+				{id: 2, deps: []int{1}},
+				{id: 3, deps: []int{1, 2}},
+				{id: 0},
+			},
+			execution: []int{0, 1, 2, 3},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			h := make(historySet)
+			e := makeExecutor(h)
+
+			var executed []int
+			onExecute := func(id int) {
+				h.SetExecuted(id)
+				executed = append(executed, id)
+			}
+			for i, node := range tC.adds {
+				node.onExecute = onExecute
+				e.addExec(node)
+				e.run()
+				if i < len(tC.adds)-1 && len(executed) != 0 {
+					t.Fatalf("expected no execution before all dependencies are added, found %v", executed)
+				}
+			}
+			if a, e := executed, tC.execution; !reflect.DeepEqual(a, e) {
+				t.Errorf("expected execution order %v, found %v", e, a)
+			}
+		})
+	}
+}
+
+// benchmarkExecutorBlocked benchmarks adding executables to an executor that
+// holds a chain of blocked executables. Each new executable depends on the
+// end of the chain, so it is blocked as well.
+func benchmarkExecutorBlocked(b *testing.B, blocked int) {
+	e := makeExecutor(make(historySet))
+	addNode := func(id int) {
+		e.addExec(execNode{id: id, deps: []int{id - 1}})
+		e.run()
+	}
+	// The executable at the start of the chain, 0, is never added.
+	for i := 1; i <= blocked; i++ {
+		addNode(i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		addNode(blocked + 1 + i)
+	}
+}
+
+func BenchmarkExecutorBlocked(b *testing.B) {
+	for _, blocked := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("blocked=%d", blocked), func(b *testing.B) {
+			benchmarkExecutorBlocked(b, blocked)
+		})
+	}
+}
+
+// benchmarkExecutorUnblock benchmarks executing a chain of blocked
+// executables once the dependency that they are all blocked on is added.
+func benchmarkExecutorUnblock(b *testing.B, blocked int) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		h := make(historySet)
+		e := makeExecutor(h)
+		onExecute := func(id int) { h.SetExecuted(id) }
+		for j := 1; j <= blocked; j++ {
+			e.addExec(execNode{id: j, deps: []int{j - 1}, onExecute: onExecute})
+			e.run()
+		}
+		b.StartTimer()
+
+		e.addExec(execNode{id: 0, onExecute: onExecute})
+		e.run()
+		if len(e.vertices) != 0 {
+			b.Fatalf("expected all executables to execute, %d remaining", len(e.vertices))
+		}
+	}
+}
+
+func BenchmarkExecutorUnblock(b *testing.B) {
+	for _, blocked := range []int{1000, 10000} {
+		b.Run(fmt.Sprintf("blocked=%d", blocked), func(b *testing.B) {
+			benchmarkExecutorUnblock(b, blocked)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/google/btree"
 
 	pb "github.com/mjolk/epx2/epaxos/epaxospb"
@@ -18,11 +19,47 @@ type instance struct {
 	differentReplies bool
 	slowPathTimer    tickingTimer
 	acceptReplies    int
+	// thriftyTimer and thriftyPeers are used by thrifty command leaders.
+	// thriftyPeers are the replicas that the current phase's message was sent
+	// to, and the timer falls back to sending it to all others.
+	thriftyTimer tickingTimer
+	thriftyPeers []pb.ReplicaID
+
+	// recovery state
+	recoveryTimer  tickingTimer
+	prepareReplies map[pb.ReplicaID]*pb.PrepareReply
+
+	// unstable is set while the instance's state is waiting to be handed out
+	// to be persisted.
+	unstable bool
+	// unapplied is set while the instance has executed but its commands are
+	// waiting to be handed out to be applied.
+	unapplied bool
+
+	// proposedAt and committedAt are the ticks at which the local replica
+	// proposed and committed the instance, which are used to measure its
+	// latencies. proposed is set if the local replica proposed the instance
+	// and has not committed it yet.
+	proposed    bool
+	proposedAt  int
+	committedAt int
 }
 
 // TODO restructure state machine
 
-const slowPathTimout = 2
+const (
+	// defaultSlowPathTimeout is the default number of ticks a command leader
+	// waits for a fast path quorum once it could take the slow path.
+	defaultSlowPathTimeout = 2
+	// defaultRecoveryTimeout is the default number of ticks a replica waits
+	// for an instance led by another replica to commit before it attempts to
+	// recover the instance.
+	defaultRecoveryTimeout = 10
+	// defaultRetransmitTimeout is the default number of ticks a thrifty
+	// command leader waits for the replicas that it sent a PreAccept or Accept
+	// message to before it sends the message to all other replicas as well.
+	defaultRetransmitTimeout = 3
+)
 
 func (p *epaxos) newInstance(r pb.ReplicaID, i pb.InstanceNum) *instance {
 	inst := &instance{
@@ -32,22 +69,34 @@ func (p *epaxos) newInstance(r pb.ReplicaID, i pb.InstanceNum) *instance {
 				ReplicaID:   r,
 				InstanceNum: i,
 			},
+			Ballot: pb.Ballot{Epoch: p.epoch},
 		},
 	}
-	inst.slowPathTimer = makeTickingTimer(slowPathTimout, func() {
-		inst.transitionTo(pb.InstanceState_Accepted)
-	})
+	inst.initTimers()
 	return inst
 }
 
 func (p *epaxos) newInstanceFromState(is *pb.InstanceState) *instance {
 	inst := &instance{p: p, is: *is}
-	inst.slowPathTimer = makeTickingTimer(slowPathTimout, func() {
-		inst.transitionTo(pb.InstanceState_Accepted)
-	})
+	inst.initTimers()
+	inst.setCommand(is.Command)
 	return inst
 }
 
+func (inst *instance) initTimers() {
+	inst.slowPathTimer = makeTickingTimer(inst.p.slowPathTimeout, inst.fireTimer(TimerSlowPath, func() {
+		inst.p.metrics.SlowPathTimeout()
+		inst.p.metrics.SlowPath()
+		inst.transitionTo(pb.InstanceState_Accepted, ReasonSlowPathTimeout)
+	}))
+	inst.recoveryTimer = makeTickingTimer(inst.p.recoveryTimeout, inst.fireTimer(TimerRecovery, func() {
+		inst.prepare()
+	}))
+	inst.thriftyTimer = makeTickingTimer(inst.p.retransmitTimeout, inst.fireTimer(TimerRetransmit, func() {
+		inst.broadcastToRemaining()
+	}))
+}
+
 //
 // BTree Functions
 //
@@ -67,7 +116,7 @@ func instanceKey(i pb.InstanceNum) btree.Item {
 //
 
 func (inst *instance) persist() {
-	inst.p.storage.PersistInstance(&inst.is)
+	inst.p.persistInstance(inst)
 }
 
 //
@@ -103,7 +152,57 @@ func (inst *instance) ExecutesBefore(b executable) bool {
 }
 
 func (inst *instance) Execute() {
-	inst.transitionTo(pb.InstanceState_Executed)
+	inst.transitionTo(pb.InstanceState_Executed, ReasonExecuted)
+}
+
+//
+// Ballot Functions
+//
+
+// leader returns the replica that leads the instance at its current ballot.
+func (inst *instance) leader() pb.ReplicaID {
+	return inst.is.Ballot.Leader(inst.is.InstanceID)
+}
+
+// isLeader returns whether the local replica leads the instance at its
+// current ballot.
+func (inst *instance) isLeader() bool {
+	return inst.leader() == inst.p.id
+}
+
+// promise records that the replica will not participate in any ballot for
+// the instance that is smaller than the provided ballot. If the replica was
+// leading the instance at a smaller ballot, it steps down.
+func (inst *instance) promise(b pb.Ballot) {
+	if inst.is.Ballot.Compare(b) >= 0 {
+		return
+	}
+	inst.p.unregisterTimer(&inst.slowPathTimer)
+	inst.p.unregisterTimer(&inst.thriftyTimer)
+	inst.prepareReplies = nil
+	inst.is.Ballot = b
+}
+
+// onNACK handles the rejection of a message that the replica sent while
+// leading the instance. Another replica has since taken over the instance
+// with a larger ballot, so the replica steps down and waits for the new
+// leader to finish the instance, recovering it itself if that never happens.
+func (inst *instance) onNACK(nack *pb.NACK) {
+	if inst.is.Ballot.Compare(nack.Ballot) >= 0 {
+		return
+	}
+	inst.p.logger.Debugf("instance %v preempted by ballot %v", inst.is.InstanceID, nack.Ballot)
+	inst.promise(nack.Ballot)
+	inst.persist()
+	if inst.isLeader() && inst.isStates(pb.InstanceState_PreAccepted, pb.InstanceState_Accepted) {
+		// The instance was fenced off by a new configuration epoch, but the
+		// replica still leads it. Retry its current phase in the new epoch.
+		inst.resetLeaderState()
+		inst.is.AcceptedBallot = inst.is.Ballot
+		inst.restartTransition()
+		return
+	}
+	inst.maybeResetRecoveryTimer()
 }
 
 //
@@ -118,34 +217,56 @@ func (st stateTransition) String() string {
 	return fmt.Sprintf("{%v -> %v}", st.from, st.to)
 }
 
-var stateTransitions = map[stateTransition]func(*instance){
-	stateTransition{pb.InstanceState_None, pb.InstanceState_PreAccepted}: func(inst *instance) {
-		inst.broadcastPreAccept()
-	},
-	stateTransition{pb.InstanceState_PreAccepted, pb.InstanceState_Accepted}: func(inst *instance) {
-		inst.broadcastAccept()
-	},
-	stateTransition{pb.InstanceState_PreAccepted, pb.InstanceState_Committed}: func(inst *instance) {
-		inst.broadcastCommit()
-		inst.prepareToExecute()
-	},
-	stateTransition{pb.InstanceState_Accepted, pb.InstanceState_Committed}: func(inst *instance) {
-		inst.broadcastCommit()
-		inst.prepareToExecute()
-	},
-	stateTransition{pb.InstanceState_Committed, pb.InstanceState_Executed}: func(inst *instance) {
-		inst.p.deliverExecutedCommand(*inst.is.Command)
-	},
-}
-
-func (inst *instance) transitionTo(to pb.InstanceState_Status) {
+// stateTransitions maps each valid state transition to the action taken when
+// an instance performs it. It is populated in init to avoid an initialization
+// cycle, because the actions themselves create instances.
+var stateTransitions map[stateTransition]func(*instance)
+
+func init() {
+	stateTransitions = map[stateTransition]func(*instance){
+		stateTransition{pb.InstanceState_None, pb.InstanceState_PreAccepted}: func(inst *instance) {
+			inst.broadcastPreAccept()
+		},
+		stateTransition{pb.InstanceState_PreAccepted, pb.InstanceState_Accepted}: func(inst *instance) {
+			inst.broadcastAccept()
+		},
+		stateTransition{pb.InstanceState_PreAccepted, pb.InstanceState_Committed}: func(inst *instance) {
+			inst.broadcastCommit()
+			inst.prepareToExecute()
+		},
+		stateTransition{pb.InstanceState_Accepted, pb.InstanceState_Committed}: func(inst *instance) {
+			inst.broadcastCommit()
+			inst.prepareToExecute()
+		},
+		stateTransition{pb.InstanceState_Committed, pb.InstanceState_Executed}: func(inst *instance) {
+			inst.p.metrics.ExecuteLatency(inst.p.ticks - inst.committedAt)
+			// Instances without a command are no-ops, which are committed
+			// during recovery.
+			// Rejected reconfiguration commands are not handed to the
+			// application.
+			if inst.is.Command != nil &&
+				(!inst.is.Command.IsConfChange() || inst.p.applyConfChange(inst)) {
+				// The commands in a batch are executed in order.
+				for _, cmd := range inst.is.Command.Commands() {
+					inst.p.deliverExecutedCommand(cmd)
+				}
+				inst.unapplied = true
+				inst.p.unappliedInsts = append(inst.p.unappliedInsts, inst)
+			}
+			inst.p.observer.Executed(inst.is.InstanceID, inst.is.Ballot)
+		},
+	}
+}
+
+func (inst *instance) transitionTo(to pb.InstanceState_Status, reason TransitionReason) {
 	st := stateTransition{from: inst.is.Status, to: to}
 	action, ok := stateTransitions[st]
 	if !ok {
 		inst.p.logger.Panicf("unexpected state transition %s", st)
 	}
 
-	inst.is.Status = to
+	inst.setStatus(to, reason)
+	inst.is.AcceptedBallot = inst.is.Ballot
 	action(inst)
 	inst.persist()
 }
@@ -173,14 +294,45 @@ func (inst *instance) assertState(valid ...pb.InstanceState_Status) {
 	}
 }
 
-// broadcastPreAccept broadcasts a PreAccept message to all other nodes.
+// broadcastPreAccept broadcasts a PreAccept message to all other nodes, or
+// only to a fast path quorum if the replica is thrifty.
 func (inst *instance) broadcastPreAccept() {
-	inst.broadcast(&pb.PreAccept{InstanceData: inst.instanceData()})
+	m := &pb.PreAccept{InstanceData: inst.instanceData()}
+	inst.sendToQuorum(m, inst.p.quorums.FastPath(len(inst.p.nodesAt(inst.is.Ballot.Epoch))))
 }
 
-// broadcastAccept broadcasts an Accept message to all other nodes.
+// broadcastAccept broadcasts an Accept message to all other nodes, or only to
+// a slow path quorum if the replica is thrifty. The message includes the
+// command so that any replica that accepts it is able to recover the
+// instance.
 func (inst *instance) broadcastAccept() {
-	inst.broadcast(&pb.Accept{InstanceData: inst.instanceDataWithoutCommand()})
+	m := &pb.Accept{InstanceData: inst.instanceData()}
+	inst.sendToQuorum(m, inst.p.quorums.SlowPath(len(inst.p.nodesAt(inst.is.Ballot.Epoch))))
+}
+
+// broadcastToRemaining sends the message of the current phase to all
+// replicas that a thrifty command leader did not send it to, because some of
+// the replicas that it did send it to have not replied in time.
+func (inst *instance) broadcastToRemaining() {
+	if inst.thriftyPeers == nil || !inst.isLeader() {
+		return
+	}
+	var m proto.Message
+	switch inst.is.Status {
+	case pb.InstanceState_PreAccepted:
+		m = &pb.PreAccept{InstanceData: inst.instanceData()}
+	case pb.InstanceState_Accepted:
+		m = &pb.Accept{InstanceData: inst.instanceData()}
+	default:
+		return
+	}
+	inst.p.logger.Debugf("thrifty quorum for instance %v timed out", inst.is.InstanceID)
+	for _, node := range inst.p.nodes {
+		if node != inst.p.id && !inReplicaSlice(node, inst.thriftyPeers) {
+			inst.p.sendTo(m, node, inst)
+		}
+	}
+	inst.thriftyPeers = nil
 }
 
 // broadcastCommit broadcasts a Commit message to all other nodes.
@@ -198,13 +350,14 @@ func (inst *instance) onPreAccept(pa *pb.PreAccept) {
 		inst.p.logger.Debugf("ignoring PreAccept message while in state %v: %v", inst.is.Status, pa)
 		return
 	}
-	inst.is.Status = pb.InstanceState_PreAccepted
+	inst.setStatus(pb.InstanceState_PreAccepted, ReasonPreAccept)
+	inst.is.AcceptedBallot = inst.is.Ballot
 
 	// Determine the local sequence number and deps for this command.
 	maxLocalSeq, localDeps := inst.p.seqAndDepsForCommand(pa.Command, inst.is.InstanceID)
 
 	// Record the command for the instance.
-	inst.is.Command = pa.Command
+	inst.setCommand(pa.Command)
 
 	// The updated sequence number is set to the maximum of the local maximum
 	// sequence number and the the PreAccept's sequence number
@@ -216,10 +369,15 @@ func (inst *instance) onPreAccept(pa *pb.PreAccept) {
 		depsUnion[dep] = struct{}{}
 	}
 	inst.is.Deps = depSliceFromMap(depsUnion)
+	inst.is.PreAcceptedUnchanged = inst.is.SeqNum == pa.SeqNum && len(inst.is.Deps) == len(pa.Deps)
+
+	// The reply promises that the instance was pre-accepted with these
+	// attributes, so they must be durable before it is sent.
+	inst.persist()
 
 	// If the sequence number and the deps turn out to be the same as those in
 	// the PreAccept message, reply with a simple PreAcceptOK message.
-	if inst.is.SeqNum == pa.SeqNum && len(inst.is.Deps) == len(pa.Deps) {
+	if inst.is.PreAcceptedUnchanged {
 		inst.reply(&pb.PreAcceptOK{})
 		return
 	}
@@ -275,19 +433,22 @@ func (inst *instance) onPreAcceptReply(paReply *pb.PreAcceptReply) {
 
 func (inst *instance) onEitherPreAcceptReply() {
 	replies := inst.preAcceptReplies + 1 // +1 for leader
-	takeFastPath := !inst.differentReplies && inst.p.fastQuorum(replies)
-	takeSlowPath := inst.p.quorum(replies)
+	epoch := inst.is.Ballot.Epoch
+	takeFastPath := !inst.differentReplies && inst.p.fastQuorum(epoch, replies)
+	takeSlowPath := inst.p.quorum(epoch, replies)
 	switch {
 	case takeFastPath:
 		inst.p.unregisterTimer(&inst.slowPathTimer)
-		inst.transitionTo(pb.InstanceState_Committed)
+		inst.p.metrics.FastPathCommit()
+		inst.transitionTo(pb.InstanceState_Committed, ReasonFastPathQuorum)
 	case takeSlowPath:
 		// We have enough replies to take the slow path, however we don't want to
 		// take it immediately in-case it's possible to take the fast path instead.
 		if !inst.fastPathAvailable() {
 			// Since the fast path will never be available, take the slow path.
 			inst.p.unregisterTimer(&inst.slowPathTimer)
-			inst.transitionTo(pb.InstanceState_Accepted)
+			inst.p.metrics.SlowPath()
+			inst.transitionTo(pb.InstanceState_Accepted, ReasonConflictingReplies)
 		} else if !inst.slowPathTimer.isSet() {
 			// Delay for a few ticks before taking slow path to allow for the fast
 			// path quorum to be achieved.
@@ -304,8 +465,12 @@ func (inst *instance) onAccept(a *pb.Accept) {
 		return
 	}
 
-	inst.is.Status = pb.InstanceState_Accepted
+	inst.setStatus(pb.InstanceState_Accepted, ReasonAccept)
+	inst.is.AcceptedBallot = inst.is.Ballot
+	inst.setCommand(a.Command)
 	inst.replaceInstanceData(a.SeqNum, a.Deps)
+	// The accepted attributes must be durable before the AcceptOK is sent.
+	inst.persist()
 	inst.reply(&pb.AcceptOK{})
 }
 
@@ -316,8 +481,8 @@ func (inst *instance) onAcceptOK(aOK *pb.AcceptOK) {
 	}
 
 	inst.acceptReplies++
-	if inst.p.quorum(inst.acceptReplies + 1 /* +1 for leader */) {
-		inst.transitionTo(pb.InstanceState_Committed)
+	if inst.p.slowQuorum(inst.is.Ballot.Epoch, inst.acceptReplies+1 /* +1 for leader */) {
+		inst.transitionTo(pb.InstanceState_Committed, ReasonSlowPathQuorum)
 	}
 }
 
@@ -327,9 +492,11 @@ func (inst *instance) onCommit(c *pb.Commit) {
 		return
 	}
 
-	inst.is.Status = pb.InstanceState_Committed
-	inst.is.Command = c.Command
+	inst.setStatus(pb.InstanceState_Committed, ReasonCommit)
+	inst.is.AcceptedBallot = inst.is.Ballot
+	inst.setCommand(c.Command)
 	inst.replaceInstanceData(c.SeqNum, c.Deps)
+	inst.persist()
 	inst.prepareToExecute()
 }
 
@@ -337,19 +504,14 @@ func (inst *instance) onCommit(c *pb.Commit) {
 // Utility Functions
 //
 
-func (inst *instance) instanceDataWithoutCommand() pb.InstanceData {
+func (inst *instance) instanceData() pb.InstanceData {
 	return pb.InstanceData{
-		SeqNum: inst.is.SeqNum,
-		Deps:   inst.is.Deps,
+		Command: inst.is.Command,
+		SeqNum:  inst.is.SeqNum,
+		Deps:    inst.is.Deps,
 	}
 }
 
-func (inst *instance) instanceData() pb.InstanceData {
-	is := inst.instanceDataWithoutCommand()
-	is.Command = inst.is.Command
-	return is
-}
-
 func (inst *instance) replaceInstanceData(newSeq pb.SeqNum, newDeps []pb.InstanceID) {
 	inst.is.SeqNum = newSeq
 	inst.is.Deps = newDeps
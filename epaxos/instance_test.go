@@ -80,11 +80,11 @@ func TestOnPreAcceptWithNoNewInfo(t *testing.T) {
 			// is not aware of this command, which is why it its proposed sequence
 			// number did not take this command into account.
 			inst03 := p.newInstance(0, 3)
-			inst03.is.InstanceData = pb.InstanceData{
+			inst03.setInstanceData(pb.InstanceData{
 				Command: newTestingCommand("zz", "zzz"),
 				SeqNum:  6,
 				Deps:    []pb.InstanceID{},
-			}
+			})
 			p.commands[0].ReplaceOrInsert(inst03)
 		}
 
@@ -125,11 +125,11 @@ func TestOnPreAcceptWithExtraInterferingCommand(t *testing.T) {
 	// is not aware of this command, which is why it its proposed sequence
 	// number did not take this command into account.
 	inst03 := p.newInstance(0, 3)
-	inst03.is.InstanceData = pb.InstanceData{
+	inst03.setInstanceData(pb.InstanceData{
 		Command: newTestingCommand("a", "z"),
 		SeqNum:  6,
 		Deps:    []pb.InstanceID{},
-	}
+	})
 	p.commands[0].ReplaceOrInsert(inst03)
 
 	instMeta, instData, msg := preAcceptMsg()
@@ -252,7 +252,6 @@ func TestOnPreAcceptReply(t *testing.T) {
 
 	// Assert outbox.
 	instanceState := testingInstanceData
-	instanceState.Command = nil
 	instanceState.SeqNum = 7
 	instanceState.Deps = updatedDeps
 	msg := pb.Message{
@@ -261,3 +260,90 @@ func TestOnPreAcceptReply(t *testing.T) {
 	}
 	p.assertOutbox(t, msg.WithDestination(1), msg.WithDestination(2))
 }
+
+// TestThriftyPreAccept tests that a thrifty command leader only sends its
+// PreAccept message to a fast path quorum, and that it sends it to all other
+// replicas once the quorum fails to reply in time.
+func TestThriftyPreAccept(t *testing.T) {
+	c := &Config{ID: 2, Nodes: []pb.ReplicaID{0, 1, 2, 3, 4}, Thrifty: true}
+	p := newEPaxos(c)
+	inst := p.onRequest(testingCmd)
+
+	msg := pb.Message{
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.PreAccept{InstanceData: inst.instanceData()}),
+	}
+	p.assertOutbox(t, msg.WithDestination(3), msg.WithDestination(4), msg.WithDestination(0))
+	p.clearMsgs()
+
+	for i := 0; i < defaultRetransmitTimeout-1; i++ {
+		p.Tick()
+	}
+	p.assertOutboxEmpty(t)
+	p.Tick()
+	p.assertOutbox(t, msg.WithDestination(1))
+	p.clearMsgs()
+
+	// The message is only sent to the remaining replicas once.
+	for i := 0; i < 2*defaultRetransmitTimeout; i++ {
+		p.Tick()
+	}
+	p.assertOutboxEmpty(t)
+}
+
+// TestThriftyAccept tests that a thrifty command leader only sends its Accept
+// message to a slow path quorum.
+func TestThriftyAccept(t *testing.T) {
+	c := &Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2, 3, 4}, Thrifty: true}
+	p := newEPaxos(c)
+	inst := p.onRequest(testingCmd)
+	p.clearMsgs()
+
+	inst.transitionTo(pb.InstanceState_Accepted, ReasonSlowPathTimeout)
+	msg := pb.Message{
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.Accept{InstanceData: inst.instanceData()}),
+	}
+	p.assertOutbox(t, msg.WithDestination(1), msg.WithDestination(2))
+	p.clearMsgs()
+
+	for i := 0; i < 2; i++ {
+		p.Step(pb.Message{
+			To:         0,
+			InstanceID: inst.is.InstanceID,
+			Type:       pb.WrapMessageInner(&pb.AcceptOK{}),
+		})
+	}
+	inst.assertState(pb.InstanceState_Committed, pb.InstanceState_Executed)
+	p.clearMsgs()
+
+	// The message is not sent to the remaining replicas after the instance
+	// commits.
+	for i := 0; i < 2*defaultRetransmitTimeout; i++ {
+		p.Tick()
+	}
+	p.assertOutboxEmpty(t)
+}
+
+// TestThriftyNetwork verifies that commands led by thrifty replicas are
+// executed on all replicas, even when one of the replicas that a command
+// leader chose for its quorums is unreachable.
+func TestThriftyNetwork(t *testing.T) {
+	n := newNetworkWithConfig(5, func(c *Config) { c.Thrifty = true })
+	for i := 0; i < 5; i++ {
+		inst := n.peers[pb.ReplicaID(i)].onRequest(newTestingCommand("a", "z"))
+		if !n.waitExecuteInstance(inst, false /* quorum */) {
+			t.Fatalf("command execution failed, instance %+v never installed", inst)
+		}
+	}
+
+	n.cut(0, 1)
+	inst := n.peers[0].onRequest(newTestingCommand("a", "z"))
+	if !n.runNetworkFor(recoveryTicks, func() bool {
+		return n.quorumHas(func(p *epaxos) bool {
+			return p.hasExecuted(inst.is.ReplicaID, inst.is.InstanceNum)
+		})
+	}) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+}
@@ -6,12 +6,29 @@ import (
 	pb "github.com/mjolk/epx2/epaxos/epaxospb"
 )
 
+// send adds the message to the outbox.
+func (p *epaxos) send(m pb.Message) {
+	p.metrics.MessageSent(pb.TypeName(m.Type))
+	p.observer.MessageSent(&m)
+	p.msgs = append(p.msgs, m)
+}
+
 func (p *epaxos) sendTo(m proto.Message, to pb.ReplicaID, inst *instance) {
 	mm := pb.WrapMessage(m)
 	mm.To = to
 	mm.InstanceID = inst.is.InstanceID
-	// mm.Ballot = 1 TODO
-	p.msgs = append(p.msgs, mm)
+	mm.Ballot = inst.is.Ballot
+	p.send(mm)
+}
+
+// nack rejects a message that carries a stale ballot by informing the leader
+// of that ballot about the larger ballot promised for the instance.
+func (p *epaxos) nack(m pb.Message, inst *instance) {
+	mm := pb.WrapMessage(&pb.NACK{Ballot: inst.is.Ballot})
+	mm.To = m.Ballot.Leader(m.InstanceID)
+	mm.InstanceID = m.InstanceID
+	mm.Ballot = m.Ballot
+	p.send(mm)
 }
 
 func (p *epaxos) broadcast(m proto.Message, inst *instance) {
@@ -22,14 +39,54 @@ func (p *epaxos) broadcast(m proto.Message, inst *instance) {
 	}
 }
 
+// thriftyPeers returns the size-1 other nodes that, together with the local
+// replica, form a quorum of the provided size. The nodes following the local
+// replica are chosen, so that different command leaders spread their load
+// across different replicas.
+func (p *epaxos) thriftyPeers(size int) []pb.ReplicaID {
+	nodes := p.nodes
+	start := 0
+	for i, node := range nodes {
+		if node == p.id {
+			start = i
+			break
+		}
+	}
+	peers := make([]pb.ReplicaID, 0, size-1)
+	for i := 1; i < len(nodes) && len(peers) < size-1; i++ {
+		peers = append(peers, nodes[(start+i)%len(nodes)])
+	}
+	return peers
+}
+
+// reply sends a message to the leader of the instance's current ballot.
 func (inst *instance) reply(m proto.Message) {
-	inst.p.sendTo(m, inst.is.ReplicaID, inst)
+	inst.p.sendTo(m, inst.leader(), inst)
 }
 
 func (inst *instance) broadcast(m proto.Message) {
 	inst.p.broadcast(m, inst)
 }
 
+// sendToQuorum sends a message that the instance's leader needs a quorum of
+// the provided size to reply to. Replicas that are not thrifty broadcast the
+// message to all other nodes. Thrifty replicas only send it to enough nodes to
+// form the quorum, and send it to the rest if they do not reply in time.
+func (inst *instance) sendToQuorum(m proto.Message, size int) {
+	if !inst.p.thrifty {
+		inst.broadcast(m)
+		return
+	}
+	inst.thriftyPeers = inst.p.thriftyPeers(size)
+	for _, to := range inst.thriftyPeers {
+		inst.p.sendTo(m, to, inst)
+	}
+	if !inst.thriftyTimer.isSet() {
+		inst.p.registerOneTimeTimer(&inst.thriftyTimer)
+	}
+	inst.thriftyTimer.reset()
+}
+
 func (p *epaxos) clearMsgs() {
 	p.msgs = nil
 }
@@ -0,0 +1,41 @@
+package epaxos
+
+// Metrics records measurements of the events in the EPaxos protocol. Its
+// methods are called while the epaxos state machine processes events, so they
+// should return quickly. Latencies are measured in ticks.
+type Metrics interface {
+	// FastPathCommit records that the local replica committed an instance that
+	// it leads on the fast path.
+	FastPathCommit()
+	// SlowPath records that the local replica started the Accept phase of an
+	// instance that it leads instead of committing it on the fast path.
+	SlowPath()
+	// SlowPathTimeout records that the local replica gave up waiting for a
+	// fast path quorum for an instance that it leads. Each timeout is also
+	// recorded as a SlowPath.
+	SlowPathTimeout()
+	// CommitLatency records the number of ticks between the local replica
+	// proposing an instance and committing it.
+	CommitLatency(ticks int)
+	// ExecuteLatency records the number of ticks between an instance being
+	// committed and being executed on the local replica.
+	ExecuteLatency(ticks int)
+	// MessageSent records that the local replica sent a message of the type,
+	// as returned by epaxospb.TypeName.
+	MessageSent(msgType string)
+	// MessageReceived records that the local replica received a message of
+	// the type, as returned by epaxospb.TypeName.
+	MessageReceived(msgType string)
+}
+
+// nopMetrics is an implementation of the Metrics interface that discards all
+// measurements.
+type nopMetrics struct{}
+
+func (nopMetrics) FastPathCommit()        {}
+func (nopMetrics) SlowPath()              {}
+func (nopMetrics) SlowPathTimeout()       {}
+func (nopMetrics) CommitLatency(int)      {}
+func (nopMetrics) ExecuteLatency(int)     {}
+func (nopMetrics) MessageSent(string)     {}
+func (nopMetrics) MessageReceived(string) {}
@@ -0,0 +1,127 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+// testingMetrics is an implementation of the Metrics interface that records
+// all measurements.
+type testingMetrics struct {
+	fastPathCommits  int
+	slowPaths        int
+	slowPathTimeouts int
+	commitLatencies  []int
+	executeLatencies []int
+	sent             map[string]int
+	received         map[string]int
+}
+
+func newTestingMetrics() *testingMetrics {
+	return &testingMetrics{
+		sent:     make(map[string]int),
+		received: make(map[string]int),
+	}
+}
+
+func (m *testingMetrics) FastPathCommit()  { m.fastPathCommits++ }
+func (m *testingMetrics) SlowPath()        { m.slowPaths++ }
+func (m *testingMetrics) SlowPathTimeout() { m.slowPathTimeouts++ }
+func (m *testingMetrics) CommitLatency(ticks int) {
+	m.commitLatencies = append(m.commitLatencies, ticks)
+}
+func (m *testingMetrics) ExecuteLatency(ticks int) {
+	m.executeLatencies = append(m.executeLatencies, ticks)
+}
+func (m *testingMetrics) MessageSent(msgType string)     { m.sent[msgType]++ }
+func (m *testingMetrics) MessageReceived(msgType string) { m.received[msgType]++ }
+
+// newNetworkWithMetrics creates a network of peers using the default
+// ClassicQuorum, where replica 0 records its measurements in the returned
+// testingMetrics.
+func newNetworkWithMetrics(nodeCount int) (network, *testingMetrics) {
+	m := newTestingMetrics()
+	n := newNetworkWithConfig(nodeCount, func(c *Config) {
+		if c.ID == 0 {
+			c.Metrics = m
+		}
+	})
+	return n, m
+}
+
+// TestMetricsFastPath verifies that a command leader records the fast path
+// commit of an instance, its latencies, and the messages it exchanged.
+func TestMetricsFastPath(t *testing.T) {
+	n, m := newNetworkWithMetrics(3)
+	inst := n.peers[0].onRequest(newTestingCommand("a", "z"))
+	if !n.waitExecuteInstance(inst, false /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+
+	if m.fastPathCommits != 1 || m.slowPaths != 0 || m.slowPathTimeouts != 0 {
+		t.Errorf("expected a single fast path commit, found %+v", m)
+	}
+	if len(m.commitLatencies) != 1 || len(m.executeLatencies) != 1 {
+		t.Errorf("expected a single commit and execute latency, found %v and %v",
+			m.commitLatencies, m.executeLatencies)
+	}
+	if a, e := m.sent["PreAccept"], 2; a != e {
+		t.Errorf("expected %d PreAccept messages sent, found %d", e, a)
+	}
+	if a, e := m.sent["Commit"], 2; a != e {
+		t.Errorf("expected %d Commit messages sent, found %d", e, a)
+	}
+	if a := m.received["PreAcceptOK"]; a == 0 {
+		t.Errorf("expected PreAcceptOK messages to be received")
+	}
+}
+
+// TestMetricsSlowPathTimeout verifies that a command leader records the slow
+// path timer firing when it can not reach a fast path quorum.
+func TestMetricsSlowPathTimeout(t *testing.T) {
+	n, m := newNetworkWithMetrics(5)
+	n.cut(0, 3)
+	n.cut(0, 4)
+	inst := n.peers[0].onRequest(newTestingCommand("a", "z"))
+	if !n.waitExecuteInstance(inst, true /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+
+	if m.fastPathCommits != 0 || m.slowPaths != 1 || m.slowPathTimeouts != 1 {
+		t.Errorf("expected a single slow path after a timeout, found %+v", m)
+	}
+	if len(m.commitLatencies) != 1 || m.commitLatencies[0] < n.peers[0].slowPathTimeout {
+		t.Errorf("expected commit latency to include the slow path timeout, found %v", m.commitLatencies)
+	}
+	if a := m.sent["Accept"]; a == 0 {
+		t.Errorf("expected Accept messages to be sent")
+	}
+}
+
+// TestMetricsExecuteLatency verifies that the execute latency of an instance
+// includes the time that it waits for its dependencies.
+func TestMetricsExecuteLatency(t *testing.T) {
+	p := newTestingEPaxos()
+	m := newTestingMetrics()
+	p.metrics = m
+
+	// Instance 1.1 is blocked on instance 0.1, which has not been committed.
+	inst11 := p.getInstance(1, 1)
+	inst11.is.Status = pb.InstanceState_Committed
+	p.prepareToExecute(inst11)
+	for i := 0; i < 5; i++ {
+		p.Tick()
+	}
+	if len(m.executeLatencies) != 0 {
+		t.Fatalf("expected instance to wait for its dependency, found %v", m.executeLatencies)
+	}
+
+	inst01 := p.getInstance(0, 1)
+	inst01.is.Status = pb.InstanceState_Committed
+	p.prepareToExecute(inst01)
+	p.Tick()
+	if a, e := m.executeLatencies, []int{1, 6}; len(a) != 2 || a[0] != e[0] || a[1] != e[1] {
+		t.Errorf("expected execute latencies %v, found %v", e, a)
+	}
+}
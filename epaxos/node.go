@@ -10,25 +10,102 @@ import (
 var (
 	// ErrStopped is returned by methods on Nodes that have been stopped.
 	ErrStopped = errors.New("epaxos: stopped")
+	// ErrDuplicateProposal is returned by ProposeAndWait if a command with the
+	// same ID is already being waited on.
+	ErrDuplicateProposal = errors.New("epaxos: duplicate proposal ID")
+	// ErrInvalidRead is returned by Read if the command writes, is a
+	// reconfiguration, is a batch, is a multi-span command, or is in a client
+	// session.
+	ErrInvalidRead = errors.New("epaxos: invalid read command")
+	// ErrNoReady is returned by RawNode.Advance if no Ready has been handed
+	// out since the last call to Advance.
+	ErrNoReady = errors.New("epaxos: no Ready to advance")
+	// ErrInvalidConfChange is returned by ProposeAndWait if a reconfiguration
+	// command was rejected when it executed, because the configured quorums
+	// are not valid for the number of nodes that it would have left.
+	ErrInvalidConfChange = errors.New("epaxos: ConfChange leaves invalid quorums")
+	// ErrReadAbandoned is returned by Read if a read quorum of replicas did not
+	// reply to the read after it was retransmitted maxReadRetransmits times.
+	ErrReadAbandoned = errors.New("epaxos: read abandoned without a quorum")
 )
 
 // Ready encapsulates the entries and messages that are ready to read,
 // be saved to stable storage, committed or sent to other peers.
 // All fields in Ready are read-only.
 type Ready struct {
-	// Messages specifies outbound messages to be sent AFTER Entries are
-	// committed to stable storage.
+	// HardState specifies the current state of the Node, which must be saved
+	// to stable storage BEFORE Messages are sent. It is nil if the state has
+	// not changed since the last Ready.
+	HardState *pb.HardState
+
+	// InstanceStates specifies the states of instances that must be saved to
+	// stable storage BEFORE Messages are sent. Instances that are Executed
+	// are not executed again after a restart, so an application whose
+	// state-machine is durable may save them as Committed instead, and save
+	// them as Executed once their commands in ExecutedCommands are applied.
+	InstanceStates []pb.InstanceState
+
+	// TruncatedInstances specifies, for each replica, the instance number up
+	// to and including which the replica's instances must be removed from
+	// stable storage. HardState records their truncation, so they can be
+	// removed in the same batch as HardState or in any batch after it, but
+	// should be removed before Advance is called. All of their commands were
+	// returned in the ExecutedCommands of earlier Readys.
+	TruncatedInstances map[pb.ReplicaID]pb.InstanceNum
+
+	// Messages specifies outbound messages to be sent AFTER HardState and
+	// InstanceStates are saved to stable storage.
 	Messages []pb.Message
 
+	// Snapshot specifies a snapshot of another replica's state machine that
+	// should replace the local state-machine. It must be applied before
+	// ExecutedCommands.
+	Snapshot *pb.Snapshot
+
+	// CommittedCommands specifies commands that have been committed, along
+	// with the instances they were committed in. Their position in the
+	// execution order is fixed, so commands that do not need to observe the
+	// state-machine, like blind writes, can be acknowledged to clients before
+	// they execute. They must not be run on the state-machine until they are
+	// returned in ExecutedCommands. A command may be returned more than once,
+	// for instance after the Node restarts.
+	CommittedCommands []CommittedCommand
+
 	// ExecutedCommands specifies commands to be executed by a state-machine.
-	// These have previously been committed to stable store.
+	// These have previously been committed to stable store, except for reads
+	// performed with Node.Read, which are not part of any instance.
 	ExecutedCommands []pb.Command
+
+	// SendSnapshots specifies snapshots of the local state-machine that should
+	// be sent to other replicas. The snapshots must be taken AFTER Snapshot and
+	// ExecutedCommands have been applied to the state-machine.
+	SendSnapshots []SnapshotRequest
+}
+
+// CommittedCommand is a command that has been committed in an instance.
+type CommittedCommand struct {
+	// InstanceID is the instance that the command was committed in.
+	InstanceID pb.InstanceID
+	// Command is the committed command.
+	Command pb.Command
+}
+
+// SnapshotRequest is a request to send a snapshot of the local state-machine
+// to a replica that has fallen behind.
+type SnapshotRequest struct {
+	// To is the replica that the snapshot should be sent to.
+	To pb.ReplicaID
+	// Metadata describes the instances that are reflected in the snapshot. It
+	// should be sent along with the snapshot's data.
+	Metadata pb.SnapshotMetadata
 }
 
 // containsUpdates returns whether the Ready struct contains any updates that
 // need to be acted upon.
 func (rd Ready) containsUpdates() bool {
-	return len(rd.Messages) > 0 || len(rd.ExecutedCommands) > 0
+	return rd.HardState != nil || len(rd.InstanceStates) > 0 ||
+		len(rd.TruncatedInstances) > 0 || len(rd.Messages) > 0 || rd.Snapshot != nil || len(rd.CommittedCommands) > 0 ||
+		len(rd.ExecutedCommands) > 0 || len(rd.SendSnapshots) > 0
 }
 
 // Node represents a node in a paxos cluster.
@@ -36,11 +113,44 @@ type Node interface {
 	// Tick increments the internal logical clock for the Node by a single tick.
 	// Election timeouts and progress timeouts are in units of ticks.
 	Tick()
-	// Propose proposes that data be ordered by paxos.
+	// Propose proposes that data be ordered by paxos. Proposals that arrive
+	// within the same tick are proposed together in a single instance on the
+	// next tick.
 	Propose(ctx context.Context, command pb.Command) error
+	// ProposeAndWait proposes that data be ordered by paxos and blocks until
+	// the command reaches the provided stage. For WaitExecuted, it returns the
+	// result that the application reports with ReportResult once it has
+	// applied the command. For WaitCommitted, it returns the command's
+	// CommittedCommand once the Ready that contains it has been advanced.
+	// ctx.Err() will be returned if the context is done first, in which case
+	// the command may still be executed.
+	//
+	// A command that was executed on another replica and reaches the local
+	// replica through a snapshot is never reported as executed, so callers
+	// waiting for WaitExecuted should use a context with a deadline.
+	ProposeAndWait(ctx context.Context, command pb.Command, stage WaitStage) (interface{}, error)
+	// ReportResult reports the result of applying an executed command to the
+	// state-machine to the caller of ProposeAndWait that is waiting for it, if
+	// any. Applications should call it for each command in ExecutedCommands
+	// once the command has been applied.
+	ReportResult(id uint64, result interface{})
+	// Read performs a linearizable read of the command, which must be a
+	// single-span command that does not write, without ordering it in an
+	// instance. The Node asks a quorum of replicas for the interfering
+	// instances they know of and returns the command in ExecutedCommands once
+	// all of them have executed locally. Read blocks until the application
+	// reports the command's result with ReportResult. ErrReadAbandoned will be
+	// returned if not enough replicas reply to the read, and ctx.Err() will be
+	// returned if the context is done first.
+	Read(ctx context.Context, command pb.Command) (interface{}, error)
 	// Step advances the state machine using the given message. ctx.Err() will be
 	// returned, if any.
 	Step(ctx context.Context, msg pb.Message) error
+	// ApplySnapshot hands a snapshot of another replica's state-machine to the
+	// Node. If the snapshot is ahead of the local replica, it will be returned
+	// in Ready to be applied to the local state-machine. ctx.Err() will be
+	// returned, if any.
+	ApplySnapshot(ctx context.Context, snap pb.Snapshot) error
 	// Ready returns a channel that returns the current point-in-time state.
 	// Users of the Node must call Advance after retrieving the state returned by
 	// Ready.
@@ -48,64 +158,133 @@ type Node interface {
 	// NOTE: No committed entries from the next Ready may be applied until all
 	// committed entries and snapshots from the previous one have finished.
 	Ready() <-chan Ready
+	// Advance notifies the Node that the application has saved the HardState
+	// and InstanceStates of the last Ready to stable storage and removed its
+	// TruncatedInstances. The Node will
+	// not return another Ready until Advance is called. The application
+	// should persist the state of each Ready in a single batch. If it fails to
+	// do so, it must stop the Node instead of calling Advance.
+	Advance()
+	// Status returns a consistent snapshot of the Node's state. It returns an
+	// empty Status if the Node has been stopped.
+	Status() Status
 	// Stop performs any necessary termination of the Node.
 	Stop()
 }
 
-// StartNode returns a new Node with the given configuration.
-func StartNode(c *Config) Node {
-	p := newEPaxos(c)
+// StartNode returns a new Node with the given configuration. An error is
+// returned if the configuration is invalid or if the state of the Node can
+// not be read out of its Storage.
+func StartNode(c *Config) (Node, error) {
+	p, err := startEPaxos(c)
+	if err != nil {
+		return nil, err
+	}
 	n := makeNode()
 	n.logger = c.Logger
 	go n.run(p)
-	return &n
+	return &n, nil
 }
 
+// maxBatchSize is the maximum number of proposals that are batched into a
+// single instance. A batch that reaches it is proposed without waiting for
+// the next tick.
+const maxBatchSize = 256
+
 // node is the canonical implementation of the Node interface. It provides a
 // thread-safe handle around the thread-unsafe paxos object.
 type node struct {
-	propc  chan pb.Command
-	msgc   chan pb.Message
-	readyc chan Ready
-	tickc  chan struct{}
-	done   chan struct{}
-	stop   chan struct{}
+	propc    chan pb.Command
+	readc    chan pb.Command
+	msgc     chan pb.Message
+	snapc    chan pb.Snapshot
+	readyc   chan Ready
+	advancec chan struct{}
+	tickc    chan struct{}
+	statusc  chan chan Status
+	waiters  *proposalWaiters
+	done     chan struct{}
+	stop     chan struct{}
 
 	logger Logger
 }
 
 func makeNode() node {
 	return node{
-		propc:  make(chan pb.Command),
-		msgc:   make(chan pb.Message),
-		readyc: make(chan Ready),
+		propc:    make(chan pb.Command),
+		readc:    make(chan pb.Command),
+		msgc:     make(chan pb.Message),
+		snapc:    make(chan pb.Snapshot),
+		readyc:   make(chan Ready),
+		advancec: make(chan struct{}),
 		// buffered chan, so paxos node can buffer some ticks when the node is
 		// busy processing messages. Paxos node will resume process buffered
 		// ticks when it becomes idle.
-		tickc: make(chan struct{}, 128),
-		done:  make(chan struct{}),
-		stop:  make(chan struct{}),
+		tickc:   make(chan struct{}, 128),
+		statusc: make(chan chan Status),
+		waiters: newProposalWaiters(),
+		done:    make(chan struct{}),
+		stop:    make(chan struct{}),
 	}
 }
 
 func (n *node) run(p *epaxos) {
+	var advancec chan struct{}
+	// committed holds the committed commands of the Ready that is waiting to
+	// be advanced.
+	var committed []CommittedCommand
+	// batch holds the proposals that arrived since the last tick.
+	var batch []pb.Command
 	for {
+		for _, f := range p.failedCmds {
+			n.waiters.fail(f.id, f.err)
+		}
+		p.clearFailedCommands()
+
+		// Wait for the application to persist the last Ready before
+		// handing out the next one.
 		var readyc chan Ready
-		rd := makeReady(p)
-		if rd.containsUpdates() {
-			readyc = n.readyc
+		var rd Ready
+		if advancec == nil {
+			rd = makeReady(p)
+			if rd.containsUpdates() {
+				readyc = n.readyc
+			}
 		}
 
 		select {
 		case <-n.tickc:
+			p.RequestBatch(batch)
+			batch = nil
 			p.Tick()
 		case cmd := <-n.propc:
-			p.Request(&cmd)
+			if cmd.IsConfChange() {
+				p.Request(&cmd)
+				break
+			}
+			batch = append(batch, cmd)
+			if len(batch) >= maxBatchSize {
+				p.RequestBatch(batch)
+				batch = nil
+			}
+		case cmd := <-n.readc:
+			p.Read(&cmd)
 		case m := <-n.msgc:
 			p.Step(m)
+		case snap := <-n.snapc:
+			p.applySnapshot(snap)
+		case c := <-n.statusc:
+			c <- p.status()
 		case readyc <- rd:
-			p.clearMsgs()
-			p.clearExecutedCommands()
+			acceptReady(p)
+			committed = rd.CommittedCommands
+			advancec = n.advancec
+		case <-advancec:
+			for _, cc := range committed {
+				n.waiters.trigger(cc.Command.ID, WaitCommitted, cc)
+			}
+			committed = nil
+			advancec = nil
 		case <-n.stop:
 			close(n.done)
 			return
@@ -135,6 +314,72 @@ func (n *node) Propose(ctx context.Context, cmd pb.Command) error {
 	}
 }
 
+// ProposeAndWait implements the Node interface.
+func (n *node) ProposeAndWait(
+	ctx context.Context, cmd pb.Command, stage WaitStage,
+) (interface{}, error) {
+	return n.wait(ctx, cmd.ID, stage, func() error {
+		return n.Propose(ctx, cmd)
+	})
+}
+
+// Read implements the Node interface.
+func (n *node) Read(ctx context.Context, cmd pb.Command) (interface{}, error) {
+	if !validRead(cmd) {
+		return nil, ErrInvalidRead
+	}
+	return n.wait(ctx, cmd.ID, WaitExecuted, func() error {
+		select {
+		case n.readc <- cmd:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-n.done:
+			return ErrStopped
+		}
+	})
+}
+
+// validRead returns whether the command can be performed as a read, which is
+// not part of any instance.
+func validRead(cmd pb.Command) bool {
+	return !cmd.Writing && !cmd.IsConfChange() && !cmd.IsBatch() && !cmd.IsMultiSpan() &&
+		!cmd.HasSession()
+}
+
+// wait registers a waiter for the command with the provided ID, submits the
+// command to the Node, and waits until the command reaches the stage.
+func (n *node) wait(
+	ctx context.Context, id uint64, stage WaitStage, submit func() error,
+) (interface{}, error) {
+	c, ok := n.waiters.register(id, stage)
+	if !ok {
+		return nil, ErrDuplicateProposal
+	}
+	if err := submit(); err != nil {
+		n.waiters.cancel(id)
+		return nil, err
+	}
+	select {
+	case res := <-c:
+		if err, ok := res.(waitError); ok {
+			return nil, err.err
+		}
+		return res, nil
+	case <-ctx.Done():
+		n.waiters.cancel(id)
+		return nil, ctx.Err()
+	case <-n.done:
+		n.waiters.cancel(id)
+		return nil, ErrStopped
+	}
+}
+
+// ReportResult implements the Node interface.
+func (n *node) ReportResult(id uint64, result interface{}) {
+	n.waiters.trigger(id, WaitExecuted, result)
+}
+
 // Step implements the Node interface.
 func (n *node) Step(ctx context.Context, m pb.Message) error {
 	select {
@@ -147,15 +392,69 @@ func (n *node) Step(ctx context.Context, m pb.Message) error {
 	}
 }
 
+// ApplySnapshot implements the Node interface.
+func (n *node) ApplySnapshot(ctx context.Context, snap pb.Snapshot) error {
+	select {
+	case n.snapc <- snap:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-n.done:
+		return ErrStopped
+	}
+}
+
 // Ready implements the Node interface.
 func (n *node) Ready() <-chan Ready {
 	return n.readyc
 }
 
+// Advance implements the Node interface.
+func (n *node) Advance() {
+	select {
+	case n.advancec <- struct{}{}:
+	case <-n.done:
+	}
+}
+
 func makeReady(p *epaxos) Ready {
+	var hs *pb.HardState
+	if p.unstableHardState {
+		s := p.hardState()
+		hs = &s
+	}
 	return Ready{
-		Messages:         p.msgs,
-		ExecutedCommands: p.executedCmds,
+		HardState:          hs,
+		InstanceStates:     p.unstableInstanceStates(),
+		TruncatedInstances: p.truncatedInstances(),
+		Messages:           p.msgs,
+		Snapshot:           p.snapshot,
+		CommittedCommands:  p.committedCmds,
+		ExecutedCommands:   p.executedCmds,
+		SendSnapshots:      p.snapshotRequests(),
+	}
+}
+
+// acceptReady is called once a Ready made by makeReady has been handed out,
+// removing everything that it holds from the state machine.
+func acceptReady(p *epaxos) {
+	p.clearUnstable()
+	p.clearMsgs()
+	p.clearSnapshot()
+	p.clearCommittedCommands()
+	p.clearExecutedCommands()
+	p.clearFailedCommands()
+	p.clearSnapshotRequests()
+}
+
+// Status implements the Node interface.
+func (n *node) Status() Status {
+	c := make(chan Status)
+	select {
+	case n.statusc <- c:
+		return <-c
+	case <-n.done:
+		return Status{}
 	}
 }
 
@@ -0,0 +1,374 @@
+package epaxos
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+// TestReadyInstanceStates tests that changes to instances are handed out in
+// Ready to be persisted, instead of being written to storage directly.
+func TestReadyInstanceStates(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+	if rd := makeReady(p); rd.HardState == nil {
+		t.Fatalf("expected initial HardState in Ready")
+	}
+	p.persistReady()
+
+	inst := p.onRequest(newTestingCommand("a", "z"))
+	if insts, _ := p.storage.Instances(); len(insts) != 0 {
+		t.Errorf("expected no persisted instances before Ready is handled, found %v", insts)
+	}
+	rd := makeReady(p)
+	if rd.HardState != nil {
+		t.Errorf("expected no HardState in Ready, found %v", rd.HardState)
+	}
+	if len(rd.InstanceStates) != 1 || rd.InstanceStates[0].InstanceID != inst.is.InstanceID {
+		t.Fatalf("expected instance %v in Ready, found %v", inst.is.InstanceID, rd.InstanceStates)
+	}
+	if a, e := rd.InstanceStates[0].Status, pb.InstanceState_PreAccepted; a != e {
+		t.Errorf("expected instance in state %v, found %v", e, a)
+	}
+
+	p.persistReady()
+	if insts, _ := p.storage.Instances(); len(insts) != 1 {
+		t.Errorf("expected 1 persisted instance, found %v", insts)
+	}
+	if rd := makeReady(p); len(rd.InstanceStates) != 0 {
+		t.Errorf("expected no instances in Ready after it was handled, found %v", rd.InstanceStates)
+	}
+}
+
+// TestNodeAdvance tests that a Node does not hand out a new Ready until the
+// previous one has been advanced.
+func TestNodeAdvance(t *testing.T) {
+	c := &Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}}
+	n, err := StartNode(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Stop()
+
+	rd := <-n.Ready()
+	if rd.HardState == nil {
+		t.Fatalf("expected initial HardState in Ready")
+	}
+	c.Storage.PersistHardState(*rd.HardState)
+
+	if err := n.Propose(context.Background(), *newTestingCommand("a", "z")); err != nil {
+		t.Fatal(err)
+	}
+	n.Tick()
+	select {
+	case rd := <-n.Ready():
+		t.Fatalf("unexpected Ready before Advance: %+v", rd)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	n.Advance()
+	rd = <-n.Ready()
+	if len(rd.InstanceStates) != 1 {
+		t.Fatalf("expected 1 instance in Ready, found %v", rd.InstanceStates)
+	}
+	if a, e := len(rd.Messages), 2; a != e {
+		t.Errorf("expected %d messages in Ready, found %d", e, a)
+	}
+	n.Advance()
+}
+
+// TestNodeBatchesProposals tests that the proposals a Node receives within a
+// tick are proposed in a single instance.
+func TestNodeBatchesProposals(t *testing.T) {
+	c := &Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}}
+	n, err := StartNode(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Stop()
+	<-n.Ready()
+	n.Advance()
+
+	var cmds []pb.Command
+	for i := 0; i < 3; i++ {
+		cmd := *newTestingCommand("a", "z")
+		if err := n.Propose(context.Background(), cmd); err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+	}
+	n.Tick()
+
+	rd := <-n.Ready()
+	if len(rd.InstanceStates) != 1 {
+		t.Fatalf("expected 1 instance in Ready, found %v", rd.InstanceStates)
+	}
+	if a := rd.InstanceStates[0].Command.Commands(); !reflect.DeepEqual(a, cmds) {
+		t.Errorf("expected batch %v, found %v", cmds, a)
+	}
+	n.Advance()
+}
+
+// failingStorage is a Storage whose methods fail once err is set.
+type failingStorage struct {
+	Storage
+	err error
+}
+
+func (fs *failingStorage) HardState() (pb.HardState, bool, error) {
+	if fs.err != nil {
+		return pb.HardState{}, false, fs.err
+	}
+	return fs.Storage.HardState()
+}
+
+// TestStartNodeStorageError tests that StartNode returns an error if the
+// Node's state can not be read out of storage.
+func TestStartNodeStorageError(t *testing.T) {
+	c := &Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}}
+	c.Storage = &failingStorage{Storage: NewMemoryStorage(c), err: errors.New("disk failure")}
+	if _, err := StartNode(c); err == nil {
+		t.Errorf("expected error when starting Node with failing storage")
+	}
+}
+
+// ackMessages acknowledges all PreAccept and Read messages in msgs as if by
+// the replicas they were sent to.
+func ackMessages(n Node, msgs []pb.Message) {
+	for _, m := range msgs {
+		switch t := m.Type.(type) {
+		case *pb.Message_PreAccept:
+			n.Step(context.Background(), pb.Message{
+				To:         m.Ballot.Leader(m.InstanceID),
+				InstanceID: m.InstanceID,
+				Ballot:     m.Ballot,
+				Type:       pb.WrapMessageInner(&pb.PreAcceptOK{}),
+			})
+		case *pb.Message_Read:
+			n.Step(context.Background(), pb.Message{
+				To: t.Read.From,
+				Type: pb.WrapMessageInner(&pb.ReadReply{
+					ReadID: t.Read.ReadID,
+					From:   m.To,
+				}),
+			})
+		}
+	}
+}
+
+// runNode drives the Node like an application would until the returned
+// function is called. Executed commands are reported with the provided result.
+// If ack is set, all PreAccept and Read messages are acknowledged as if by the
+// other replicas.
+func runNode(n Node, s Storage, result interface{}, ack bool) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				n.Tick()
+			case rd := <-n.Ready():
+				if rd.HardState != nil {
+					s.PersistHardState(*rd.HardState)
+				}
+				for i := range rd.InstanceStates {
+					s.PersistInstance(&rd.InstanceStates[i])
+				}
+				if ack {
+					ackMessages(n, rd.Messages)
+				}
+				for _, cmd := range rd.ExecutedCommands {
+					n.ReportResult(cmd.ID, result)
+				}
+				n.Advance()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func TestProposeAndWait(t *testing.T) {
+	for _, stage := range []WaitStage{WaitCommitted, WaitExecuted} {
+		c := &Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}}
+		n, err := StartNode(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stop := runNode(n, c.Storage, "result", true /* ack */)
+
+		ctx := context.Background()
+		cmd := *newTestingCommand("a", "z")
+		res, err := n.ProposeAndWait(ctx, cmd, stage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch stage {
+		case WaitCommitted:
+			cc, ok := res.(CommittedCommand)
+			if !ok || cc.Command.ID != cmd.ID || cc.InstanceID.InstanceNum != 1 {
+				t.Errorf("expected committed command %v, found %v", cmd, res)
+			}
+		case WaitExecuted:
+			if res != "result" {
+				t.Errorf("expected reported result, found %v", res)
+			}
+		}
+		if l := n.(*node).waiters.len(); l != 0 {
+			t.Errorf("expected no waiters after result, found %d", l)
+		}
+		stop()
+		n.Stop()
+	}
+}
+
+// TestProposeAndWaitInvalidConfChange tests that ProposeAndWait returns an
+// error for a reconfiguration command that is rejected when it executes.
+func TestProposeAndWaitInvalidConfChange(t *testing.T) {
+	c := &Config{
+		ID:     0,
+		Nodes:  []pb.ReplicaID{0, 1, 2},
+		Quorum: FlexibleQuorum{FastPathSize: 3, SlowPathSize: 2, PrepareSize: 2},
+	}
+	n, err := StartNode(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Stop()
+	stop := runNode(n, c.Storage, "result", true /* ack */)
+	defer stop()
+
+	cmd := *newConfChangeCommand(pb.ConfChange_RemoveNode, 2)
+	if _, err := n.ProposeAndWait(context.Background(), cmd, WaitExecuted); err != ErrInvalidConfChange {
+		t.Errorf("expected %v, found %v", ErrInvalidConfChange, err)
+	}
+	if a, e := n.Status().Nodes, c.Nodes; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected nodes %v, found %v", e, a)
+	}
+}
+
+// TestNodeRead tests that a Node returns the result of a read once a quorum
+// of replicas has replied to it, without proposing it in an instance.
+func TestNodeRead(t *testing.T) {
+	c := &Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}}
+	n, err := StartNode(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Stop()
+	stop := runNode(n, c.Storage, "result", true /* ack */)
+	defer stop()
+
+	ctx := context.Background()
+	if _, err := n.Read(ctx, *newTestingCommand("a", "")); err != ErrInvalidRead {
+		t.Errorf("expected %v for writing read, found %v", ErrInvalidRead, err)
+	}
+	res, err := n.Read(ctx, *newTestingReadCommand("a", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "result" {
+		t.Errorf("expected reported result, found %v", res)
+	}
+	if insts, _ := c.Storage.Instances(); len(insts) != 0 {
+		t.Errorf("expected read to not be proposed in an instance, found %v", insts)
+	}
+}
+
+// TestNodeReadAbandoned tests that a Node returns ErrReadAbandoned for a read
+// that a read quorum of replicas never replies to.
+func TestNodeReadAbandoned(t *testing.T) {
+	c := &Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}}
+	n, err := StartNode(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Stop()
+	stop := runNode(n, c.Storage, "result", false /* ack */)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := n.Read(ctx, *newTestingReadCommand("a", "")); err != ErrReadAbandoned {
+		t.Errorf("expected %v, found %v", ErrReadAbandoned, err)
+	}
+}
+
+// TestNodeStatus tests that a Node reports the status of its replica, and an
+// empty status once it has been stopped.
+func TestNodeStatus(t *testing.T) {
+	c := &Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}}
+	n, err := StartNode(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := runNode(n, c.Storage, "result", true /* ack */)
+
+	cmd := *newTestingCommand("a", "z")
+	if _, err := n.ProposeAndWait(context.Background(), cmd, WaitExecuted); err != nil {
+		t.Fatal(err)
+	}
+	s := n.Status()
+	if a, e := s.Replicas[0].MaxInstanceNum, pb.InstanceNum(1); a != e {
+		t.Errorf("expected max instance number %v, found %v", e, a)
+	}
+	if a := s.InstanceCounts[pb.InstanceState_Executed]; a != 1 {
+		t.Errorf("expected 1 executed instance, found %d", a)
+	}
+	if !reflect.DeepEqual(s.Nodes, c.Nodes) {
+		t.Errorf("expected nodes %v, found %v", c.Nodes, s.Nodes)
+	}
+
+	stop()
+	n.Stop()
+	if s := n.Status(); !reflect.DeepEqual(s, Status{}) {
+		t.Errorf("expected empty status for stopped node, found %+v", s)
+	}
+}
+
+// TestProposeAndWaitCancel tests that callers of ProposeAndWait stop waiting
+// when their context is canceled or the Node is stopped.
+func TestProposeAndWaitCancel(t *testing.T) {
+	c := &Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}}
+	n, err := StartNode(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := runNode(n, c.Storage, nil, false /* ack */)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	cmd := *newTestingCommand("a", "z")
+	if _, err := n.ProposeAndWait(ctx, cmd, WaitExecuted); err != context.DeadlineExceeded {
+		t.Errorf("expected %v, found %v", context.DeadlineExceeded, err)
+	}
+	if l := n.(*node).waiters.len(); l != 0 {
+		t.Errorf("expected waiter to be removed, found %d waiters", l)
+	}
+
+	errC := make(chan error, 1)
+	go func() {
+		_, err := n.ProposeAndWait(context.Background(), *newTestingCommand("a", "z"), WaitExecuted)
+		errC <- err
+	}()
+	for n.(*node).waiters.len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	n.Stop()
+	if err := <-errC; err != ErrStopped {
+		t.Errorf("expected %v, found %v", ErrStopped, err)
+	}
+}
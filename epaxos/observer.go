@@ -0,0 +1,109 @@
+package epaxos
+
+import (
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+// TransitionReason describes why an instance changed its state.
+type TransitionReason string
+
+const (
+	// ReasonProposed is given when the local replica proposes a command in a
+	// new instance.
+	ReasonProposed TransitionReason = "proposed"
+	// ReasonPreAccept is given when a PreAccept message is received.
+	ReasonPreAccept TransitionReason = "pre-accept"
+	// ReasonFastPathQuorum is given when a command leader receives a fast path
+	// quorum of identical PreAccept replies.
+	ReasonFastPathQuorum TransitionReason = "fast-path-quorum"
+	// ReasonConflictingReplies is given when a command leader takes the slow
+	// path because the PreAccept replies it received disagree.
+	ReasonConflictingReplies TransitionReason = "conflicting-replies"
+	// ReasonSlowPathTimeout is given when a command leader takes the slow path
+	// because it did not receive a fast path quorum in time.
+	ReasonSlowPathTimeout TransitionReason = "slow-path-timeout"
+	// ReasonAccept is given when an Accept message is received.
+	ReasonAccept TransitionReason = "accept"
+	// ReasonSlowPathQuorum is given when a command leader receives a slow path
+	// quorum of Accept replies.
+	ReasonSlowPathQuorum TransitionReason = "slow-path-quorum"
+	// ReasonCommit is given when a Commit message is received.
+	ReasonCommit TransitionReason = "commit"
+	// ReasonRecovery is given when a replica that recovers an instance
+	// restarts one of its phases.
+	ReasonRecovery TransitionReason = "recovery"
+	// ReasonExecuted is given when the executor executes an instance.
+	ReasonExecuted TransitionReason = "executed"
+	// ReasonSnapshot is given when an instance is covered by an applied
+	// snapshot, without being executed locally.
+	ReasonSnapshot TransitionReason = "snapshot"
+)
+
+// TimerKind identifies one of the timers of an instance.
+type TimerKind string
+
+const (
+	// TimerSlowPath fires when a command leader stops waiting for a fast path
+	// quorum.
+	TimerSlowPath TimerKind = "slow-path"
+	// TimerRecovery fires when a replica starts recovering an instance that
+	// has not committed in time.
+	TimerRecovery TimerKind = "recovery"
+	// TimerRetransmit fires when a thrifty command leader sends the message of
+	// its current phase to the replicas that it skipped.
+	TimerRetransmit TimerKind = "retransmit"
+)
+
+// Observer is notified of the events in the lifecycle of instances, which
+// allows the lifecycle of each instance to be traced. Its methods are called
+// while the epaxos state machine processes events, so they should return
+// quickly and must not call back into the Node.
+type Observer interface {
+	// Transition is called when an instance changes its state, with the
+	// instance's ballot after the change. From and to are equal when an
+	// instance repeats a phase, for instance at a larger ballot.
+	Transition(id pb.InstanceID, ballot pb.Ballot, from, to pb.InstanceState_Status, reason TransitionReason)
+	// MessageSent is called when the local replica sends a message. The
+	// message holds the instance ID and ballot that it refers to.
+	MessageSent(m *pb.Message)
+	// MessageReceived is called when the local replica receives a message.
+	MessageReceived(m *pb.Message)
+	// TimerFired is called when one of the timers of an instance fires, before
+	// the action of the timer is taken.
+	TimerFired(id pb.InstanceID, ballot pb.Ballot, timer TimerKind)
+	// Executed is called once the commands of an instance have been executed
+	// on the local replica.
+	Executed(id pb.InstanceID, ballot pb.Ballot)
+}
+
+// nopObserver is an implementation of the Observer interface that ignores
+// all events.
+type nopObserver struct{}
+
+func (nopObserver) Transition(pb.InstanceID, pb.Ballot, pb.InstanceState_Status, pb.InstanceState_Status, TransitionReason) {
+}
+func (nopObserver) MessageSent(*pb.Message)                        {}
+func (nopObserver) MessageReceived(*pb.Message)                    {}
+func (nopObserver) TimerFired(pb.InstanceID, pb.Ballot, TimerKind) {}
+func (nopObserver) Executed(pb.InstanceID, pb.Ballot)              {}
+
+// setStatus moves the instance to the status and notifies the observer.
+// Whether the instance was pre-accepted unchanged is only relevant while it is
+// PreAccepted, so it is cleared in all other states.
+func (inst *instance) setStatus(to pb.InstanceState_Status, reason TransitionReason) {
+	from := inst.is.Status
+	inst.is.Status = to
+	if to != pb.InstanceState_PreAccepted {
+		inst.is.PreAcceptedUnchanged = false
+	}
+	inst.p.observer.Transition(inst.is.InstanceID, inst.is.Ballot, from, to, reason)
+}
+
+// fireTimer returns the action of an instance timer, which notifies the
+// observer before taking the action.
+func (inst *instance) fireTimer(timer TimerKind, action func()) func() {
+	return func() {
+		inst.p.observer.TimerFired(inst.is.InstanceID, inst.is.Ballot, timer)
+		action()
+	}
+}
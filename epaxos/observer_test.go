@@ -0,0 +1,125 @@
+package epaxos
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+type testingTransition struct {
+	from, to pb.InstanceState_Status
+	reason   TransitionReason
+}
+
+// testingObserver is an implementation of the Observer interface that records
+// the events of each instance.
+type testingObserver struct {
+	transitions map[pb.InstanceID][]testingTransition
+	sent        []pb.Message
+	received    []pb.Message
+	timers      map[pb.InstanceID][]TimerKind
+	executed    []pb.InstanceID
+}
+
+func newTestingObserver() *testingObserver {
+	return &testingObserver{
+		transitions: make(map[pb.InstanceID][]testingTransition),
+		timers:      make(map[pb.InstanceID][]TimerKind),
+	}
+}
+
+func (o *testingObserver) Transition(
+	id pb.InstanceID, _ pb.Ballot, from, to pb.InstanceState_Status, reason TransitionReason,
+) {
+	o.transitions[id] = append(o.transitions[id], testingTransition{from, to, reason})
+}
+func (o *testingObserver) MessageSent(m *pb.Message)     { o.sent = append(o.sent, *m) }
+func (o *testingObserver) MessageReceived(m *pb.Message) { o.received = append(o.received, *m) }
+func (o *testingObserver) TimerFired(id pb.InstanceID, _ pb.Ballot, timer TimerKind) {
+	o.timers[id] = append(o.timers[id], timer)
+}
+func (o *testingObserver) Executed(id pb.InstanceID, _ pb.Ballot) {
+	o.executed = append(o.executed, id)
+}
+
+// newNetworkWithObservers creates a network of peers using the default
+// ClassicQuorum, where each replica notifies its own testingObserver.
+func newNetworkWithObservers(nodeCount int) (network, map[pb.ReplicaID]*testingObserver) {
+	obs := make(map[pb.ReplicaID]*testingObserver, nodeCount)
+	n := newNetworkWithConfig(nodeCount, func(c *Config) {
+		obs[c.ID] = newTestingObserver()
+		c.Observer = obs[c.ID]
+	})
+	return n, obs
+}
+
+// TestObserverFastPath verifies that the command leader and the other
+// replicas report the lifecycle of an instance that commits on the fast path.
+func TestObserverFastPath(t *testing.T) {
+	n, obs := newNetworkWithObservers(3)
+	inst := n.peers[0].onRequest(newTestingCommand("a", "z"))
+	if !n.waitExecuteInstance(inst, false /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+	id := inst.is.InstanceID
+
+	expLeader := []testingTransition{
+		{pb.InstanceState_None, pb.InstanceState_PreAccepted, ReasonProposed},
+		{pb.InstanceState_PreAccepted, pb.InstanceState_Committed, ReasonFastPathQuorum},
+		{pb.InstanceState_Committed, pb.InstanceState_Executed, ReasonExecuted},
+	}
+	if a := obs[0].transitions[id]; !reflect.DeepEqual(a, expLeader) {
+		t.Errorf("expected command leader transitions %v, found %v", expLeader, a)
+	}
+	expFollower := []testingTransition{
+		{pb.InstanceState_None, pb.InstanceState_PreAccepted, ReasonPreAccept},
+		{pb.InstanceState_PreAccepted, pb.InstanceState_Committed, ReasonCommit},
+		{pb.InstanceState_Committed, pb.InstanceState_Executed, ReasonExecuted},
+	}
+	for _, r := range []pb.ReplicaID{1, 2} {
+		if a := obs[r].transitions[id]; !reflect.DeepEqual(a, expFollower) {
+			t.Errorf("expected replica %d transitions %v, found %v", r, expFollower, a)
+		}
+	}
+
+	for r, o := range obs {
+		if a, e := o.executed, []pb.InstanceID{id}; !reflect.DeepEqual(a, e) {
+			t.Errorf("expected replica %d to execute %v, found %v", r, e, a)
+		}
+	}
+	for _, m := range obs[0].sent {
+		if m.InstanceID != id || m.Ballot != inst.is.Ballot {
+			t.Errorf("expected sent message for instance %v at ballot %v, found %+v", id, inst.is.Ballot, m)
+		}
+	}
+	if a, e := len(obs[0].received), 2; a != e {
+		t.Errorf("expected %d received messages, found %d", e, a)
+	}
+}
+
+// TestObserverSlowPathTimeout verifies that the command leader reports the
+// slow path timer firing, and the transition that it causes.
+func TestObserverSlowPathTimeout(t *testing.T) {
+	n, obs := newNetworkWithObservers(5)
+	n.cut(0, 3)
+	n.cut(0, 4)
+	inst := n.peers[0].onRequest(newTestingCommand("a", "z"))
+	if !n.waitExecuteInstance(inst, true /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+	id := inst.is.InstanceID
+
+	if a, e := obs[0].timers[id], []TimerKind{TimerSlowPath}; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected timers %v to fire, found %v", e, a)
+	}
+	exp := []testingTransition{
+		{pb.InstanceState_None, pb.InstanceState_PreAccepted, ReasonProposed},
+		{pb.InstanceState_PreAccepted, pb.InstanceState_Accepted, ReasonSlowPathTimeout},
+		{pb.InstanceState_Accepted, pb.InstanceState_Committed, ReasonSlowPathQuorum},
+		{pb.InstanceState_Committed, pb.InstanceState_Executed, ReasonExecuted},
+	}
+	if a := obs[0].transitions[id]; !reflect.DeepEqual(a, exp) {
+		t.Errorf("expected transitions %v, found %v", exp, a)
+	}
+}
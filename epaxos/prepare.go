@@ -1 +1,319 @@
 package epaxos
+
+import (
+	"sort"
+
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+//
+// Recovery Timer Functions
+//
+
+// resetRecoveryTimer (re)starts the instance's recovery timer. The timeout is
+// jittered so that replicas waiting on the same instance are unlikely to
+// attempt to recover it at the same time.
+func (inst *instance) resetRecoveryTimer() {
+	if !inst.recoveryTimer.isSet() {
+		inst.p.registerOneTimeTimer(&inst.recoveryTimer)
+	}
+	jitter := 0
+	if maxJitter := inst.p.recoveryTimeout / 2; maxJitter > 0 {
+		jitter = inst.p.rand.Intn(maxJitter)
+	}
+	inst.recoveryTimer.resetWithJitter(jitter)
+}
+
+// maybeResetRecoveryTimer resets the instance's recovery timer if the instance
+// is led by another replica and has not yet been committed.
+func (inst *instance) maybeResetRecoveryTimer() {
+	if inst.isLeader() || inst.isStates(pb.InstanceState_Committed, pb.InstanceState_Executed) {
+		return
+	}
+	inst.resetRecoveryTimer()
+}
+
+//
+// Prepare Phase
+//
+
+// prepare begins the explicit Prepare phase for the instance. The local
+// replica takes over leadership of the instance by choosing a ballot larger
+// than any it has seen and asks all other replicas for the state they have
+// accepted for the instance. This is used to finish instances whose command
+// leader is suspected to have failed.
+func (inst *instance) prepare() {
+	if inst.isStates(pb.InstanceState_Committed, pb.InstanceState_Executed) {
+		return
+	}
+	inst.p.logger.Debugf("recovering instance %v", inst.is.InstanceID)
+
+	b := inst.is.Ballot
+	if b.Epoch < inst.p.epoch {
+		b = pb.Ballot{Epoch: inst.p.epoch}
+	}
+	inst.promise(pb.Ballot{
+		Epoch:     b.Epoch,
+		Number:    b.Number + 1,
+		ReplicaID: inst.p.id,
+	})
+	inst.persist()
+
+	// The local replica is a member of the Prepare quorum.
+	inst.prepareReplies = make(map[pb.ReplicaID]*pb.PrepareReply, len(inst.p.nodes))
+	inst.prepareReplies[inst.p.id] = inst.prepareReply()
+	inst.broadcast(&pb.Prepare{})
+
+	// Try again with a larger ballot if recovery stalls.
+	inst.resetRecoveryTimer()
+}
+
+func (inst *instance) prepareReply() *pb.PrepareReply {
+	return &pb.PrepareReply{
+		From:           inst.p.id,
+		Status:         inst.is.Status,
+		InstanceData:   inst.instanceData(),
+		AcceptedBallot: inst.is.AcceptedBallot,
+
+		PreAcceptedUnchanged: inst.is.PreAcceptedUnchanged,
+	}
+}
+
+func (inst *instance) onPrepare(prep *pb.Prepare) {
+	// The Prepare's ballot was promised in Step. Make sure that the promise
+	// is durable before replying.
+	inst.persist()
+	inst.reply(inst.prepareReply())
+}
+
+func (inst *instance) onPrepareReply(prepReply *pb.PrepareReply) {
+	if inst.prepareReplies == nil {
+		inst.p.logger.Debugf("ignoring PrepareReply message while not preparing: %v", prepReply)
+		return
+	}
+
+	inst.prepareReplies[prepReply.From] = prepReply
+	if !inst.hasPrepareQuorum() {
+		return
+	}
+
+	replies := make([]*pb.PrepareReply, 0, len(inst.prepareReplies))
+	for _, r := range inst.prepareReplies {
+		replies = append(replies, r)
+	}
+	// Sort so that recovery is deterministic.
+	sort.Slice(replies, func(i, j int) bool {
+		return replies[i].From < replies[j].From
+	})
+	inst.prepareReplies = nil
+	inst.recover(replies)
+}
+
+// hasPrepareQuorum returns whether the Prepare replies form a Prepare quorum of
+// the current configuration epoch, and of every earlier epoch that a replica
+// accepted or pre-accepted the instance in. An instance may have been decided
+// by quorums sized by the nodes of the epoch of its ballot, so recovery must
+// intersect them.
+func (inst *instance) hasPrepareQuorum() bool {
+	epochs := []uint64{inst.p.epoch}
+	for _, r := range inst.prepareReplies {
+		if r.Status != pb.InstanceState_None && r.AcceptedBallot.Epoch < inst.p.epoch {
+			epochs = append(epochs, r.AcceptedBallot.Epoch)
+		}
+	}
+	for _, epoch := range epochs {
+		if !inst.p.prepareQuorum(epoch, inst.prepareRepliesFrom(epoch)) {
+			return false
+		}
+	}
+	return true
+}
+
+// prepareRepliesFrom returns the number of Prepare replies from the nodes of
+// the configuration epoch.
+func (inst *instance) prepareRepliesFrom(epoch uint64) int {
+	count := 0
+	for _, r := range inst.p.nodesAt(epoch) {
+		if _, ok := inst.prepareReplies[r]; ok {
+			count++
+		}
+	}
+	return count
+}
+
+// recover decides how to finish the instance once a quorum of replicas have
+// replied to its Prepare. It follows the procedure laid out in Figure 3 of
+// the EPaxos paper:
+//   - if any replica has committed the instance, commit it with the same data.
+//   - else if any replica has accepted the instance, run the Paxos-Accept phase
+//     with the data accepted at the largest ballot.
+//   - else if enough replicas other than the command leader have pre-accepted
+//     the data it proposed unchanged at the initial ballot, the instance may
+//     have committed on the fast path, so run the Paxos-Accept phase with that
+//     data.
+//   - else if any replica has pre-accepted the instance, restart the
+//     PreAccept phase for its command, avoiding the fast path.
+//   - else no replica can have committed the instance, so commit a no-op.
+func (inst *instance) recover(replies []*pb.PrepareReply) {
+	var accepted *pb.PrepareReply
+	var preAccepted []*pb.PrepareReply
+	for _, r := range replies {
+		switch r.Status {
+		case pb.InstanceState_Committed, pb.InstanceState_Executed:
+			inst.recoverCommit(r.InstanceData)
+			return
+		case pb.InstanceState_Accepted:
+			if accepted == nil || accepted.AcceptedBallot.Compare(r.AcceptedBallot) < 0 {
+				accepted = r
+			}
+		case pb.InstanceState_PreAccepted:
+			preAccepted = append(preAccepted, r)
+		}
+	}
+
+	switch {
+	case accepted != nil:
+		inst.recoverAccept(accepted.InstanceData)
+	case len(preAccepted) > 0:
+		if data, ok := inst.fastPathCandidate(replies); ok {
+			inst.recoverAccept(data)
+		} else {
+			inst.recoverPreAccept(preAccepted[0].InstanceData)
+		}
+	default:
+		inst.recoverAccept(pb.InstanceData{})
+	}
+}
+
+// fastPathCandidate searches the PreAccepted replies for data that the
+// instance's command leader could have committed on the fast path. If it did,
+// then the replicas other than the command leader in its fast path quorum
+// pre-accepted the data unchanged at the leader's initial ballot, and at least
+// FastPath+R-N of them are among the R replies from the N nodes of the
+// ballot's epoch. With the classic quorums and a Prepare quorum of replies,
+// that is floor(N/2). Only replies at the largest ballot that any replica
+// pre-accepted at are considered, and the data is only returned if no other
+// data could also have been committed this way.
+func (inst *instance) fastPathCandidate(replies []*pb.PrepareReply) (pb.InstanceData, bool) {
+	var preAccepted []*pb.PrepareReply
+	var ballot pb.Ballot
+	for _, r := range replies {
+		if r.Status != pb.InstanceState_PreAccepted {
+			continue
+		}
+		preAccepted = append(preAccepted, r)
+		if r.AcceptedBallot.Compare(ballot) > 0 {
+			ballot = r.AcceptedBallot
+		}
+	}
+	if len(preAccepted) == 0 || !ballot.IsInitial() {
+		return pb.InstanceData{}, false
+	}
+
+	nodes := inst.p.nodesAt(ballot.Epoch)
+	replied := 0
+	for _, r := range replies {
+		if inReplicaSlice(r.From, nodes) {
+			replied++
+		}
+	}
+	minIdentical := inst.p.quorums.FastPath(len(nodes)) + replied - len(nodes)
+
+	var candidates []pb.InstanceData
+	var counts []int
+	for _, r := range preAccepted {
+		if r.From == inst.is.ReplicaID || !r.PreAcceptedUnchanged || r.AcceptedBallot != ballot {
+			continue
+		}
+		found := false
+		for i, c := range candidates {
+			if c.SeqNum == r.SeqNum && depsEqual(c.Deps, r.Deps) {
+				counts[i]++
+				found = true
+				break
+			}
+		}
+		if !found {
+			candidates = append(candidates, r.InstanceData)
+			counts = append(counts, 1)
+		}
+	}
+
+	candidate, ok := pb.InstanceData{}, false
+	for i, c := range candidates {
+		if counts[i] < minIdentical {
+			continue
+		}
+		if ok {
+			// Different data is only pre-accepted unchanged at the same
+			// ballot if the command leader restarted before committing and
+			// resent updated data, after which it never takes the fast path.
+			// Neither candidate can have been committed on the fast path.
+			return pb.InstanceData{}, false
+		}
+		candidate, ok = c, true
+	}
+	return candidate, ok
+}
+
+// resetLeaderState resets all command-leader state for the instance.
+func (inst *instance) resetLeaderState() {
+	inst.preAcceptReplies = 0
+	inst.differentReplies = false
+	inst.acceptReplies = 0
+}
+
+// recoverCommit commits the instance with data that another replica has
+// already committed.
+func (inst *instance) recoverCommit(data pb.InstanceData) {
+	inst.setStatus(pb.InstanceState_Committed, ReasonRecovery)
+	inst.is.AcceptedBallot = inst.is.Ballot
+	inst.setInstanceData(data)
+	inst.broadcastCommit()
+	inst.prepareToExecute()
+	inst.persist()
+}
+
+// recoverAccept runs the Paxos-Accept phase for the instance with the
+// provided data.
+func (inst *instance) recoverAccept(data pb.InstanceData) {
+	inst.resetLeaderState()
+	inst.setStatus(pb.InstanceState_Accepted, ReasonRecovery)
+	inst.is.AcceptedBallot = inst.is.Ballot
+	inst.setInstanceData(data)
+	inst.broadcastAccept()
+	inst.persist()
+}
+
+// recoverPreAccept restarts the PreAccept phase for the instance with the
+// provided data. The fast path is never taken, because other replicas may
+// have pre-accepted the command with different dependencies.
+func (inst *instance) recoverPreAccept(data pb.InstanceData) {
+	maxLocalSeq, localDeps := inst.p.seqAndDepsForCommand(data.Command, inst.is.InstanceID)
+	for _, dep := range data.Deps {
+		localDeps[dep] = struct{}{}
+	}
+
+	inst.resetLeaderState()
+	inst.differentReplies = true
+	inst.setStatus(pb.InstanceState_PreAccepted, ReasonRecovery)
+	inst.is.AcceptedBallot = inst.is.Ballot
+	inst.is.PreAcceptedUnchanged = false
+	inst.setCommand(data.Command)
+	inst.is.SeqNum = pb.MaxSeqNum(data.SeqNum, maxLocalSeq+1)
+	inst.is.Deps = depSliceFromMap(localDeps)
+	inst.broadcastPreAccept()
+	inst.persist()
+}
+
+func depsEqual(a, b []pb.InstanceID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,429 @@
+package epaxos
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+// recoveryTicks is the number of ticks that tests wait for recovery to
+// complete. It allows for a few rounds of dueling recoveries.
+const recoveryTicks = 5 * defaultRecoveryTimeout
+
+func prepareMsg(b pb.Ballot) pb.Message {
+	return pb.Message{
+		To:         0,
+		Ballot:     b,
+		InstanceID: pb.InstanceID{ReplicaID: 1, InstanceNum: 2},
+		Type:       pb.WrapMessageInner(&pb.Prepare{}),
+	}
+}
+
+// TestOnPrepare tests how a replica behaves when it receives a Prepare
+// message. It should promise the ballot and reply with its state for the
+// instance, and then reject all messages with smaller ballots.
+func TestOnPrepare(t *testing.T) {
+	p := newTestingEPaxos()
+	inst := p.getInstance(1, 2)
+	inst.is.Status = pb.InstanceState_PreAccepted
+
+	b := pb.Ballot{Number: 1, ReplicaID: 2}
+	p.Step(prepareMsg(b))
+
+	if a, e := inst.is.Ballot, b; a != e {
+		t.Errorf("expected instance with ballot %v, found %v", e, a)
+	}
+	reply := pb.Message{
+		To:         2,
+		Ballot:     b,
+		InstanceID: inst.is.InstanceID,
+		Type: pb.WrapMessageInner(&pb.PrepareReply{
+			From:         0,
+			Status:       pb.InstanceState_PreAccepted,
+			InstanceData: inst.instanceData(),
+		}),
+	}
+	p.assertOutbox(t, reply)
+	p.clearMsgs()
+
+	// A Prepare with a smaller ballot should be rejected.
+	stale := pb.Ballot{Number: 1, ReplicaID: 1}
+	p.Step(prepareMsg(stale))
+	p.assertOutbox(t, pb.Message{
+		To:         1,
+		Ballot:     stale,
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.NACK{Ballot: b}),
+	})
+	p.clearMsgs()
+	if a, e := inst.is.Ballot, b; a != e {
+		t.Errorf("expected instance with ballot %v, found %v", e, a)
+	}
+}
+
+// TestRejectStaleBallot tests that a replica rejects PreAccept, Accept, and
+// Commit messages sent at a ballot smaller than the one that it has promised
+// for the instance, and that it informs the sender of the larger ballot.
+func TestRejectStaleBallot(t *testing.T) {
+	for _, msg := range []proto.Message{
+		&pb.PreAccept{InstanceData: testingInstanceData},
+		&pb.Accept{InstanceData: testingInstanceData},
+		&pb.Commit{InstanceData: testingInstanceData},
+	} {
+		p := newTestingEPaxos()
+		inst := p.getInstance(1, 2)
+		inst.is.Status = pb.InstanceState_PreAccepted
+
+		b := pb.Ballot{Number: 1, ReplicaID: 2}
+		p.Step(prepareMsg(b))
+		p.clearMsgs()
+
+		// The command leader sends the message at the initial ballot.
+		m := pb.WrapMessage(msg)
+		m.To = 0
+		m.InstanceID = inst.is.InstanceID
+		p.Step(m)
+
+		p.assertOutbox(t, pb.Message{
+			To:         1,
+			InstanceID: inst.is.InstanceID,
+			Type:       pb.WrapMessageInner(&pb.NACK{Ballot: b}),
+		})
+		inst.assertState(pb.InstanceState_PreAccepted)
+		if a, e := inst.is.Ballot, b; a != e {
+			t.Errorf("expected instance with ballot %v, found %v", e, a)
+		}
+	}
+}
+
+// TestOnNACK tests that a command leader steps down when one of its messages
+// is rejected because another replica has taken over the instance.
+func TestOnNACK(t *testing.T) {
+	p := newTestingEPaxos()
+	inst := p.onRequest(testingCmd)
+	p.clearMsgs()
+
+	b := pb.Ballot{Number: 1, ReplicaID: 1}
+	nack := pb.WrapMessage(&pb.NACK{Ballot: b})
+	nack.To = 0
+	nack.InstanceID = inst.is.InstanceID
+	p.Step(nack)
+
+	if a, e := inst.is.Ballot, b; a != e {
+		t.Errorf("expected instance with ballot %v, found %v", e, a)
+	}
+	if inst.isLeader() {
+		t.Errorf("expected replica to step down as leader of instance")
+	}
+	if !inst.recoveryTimer.isSet() {
+		t.Errorf("expected recovery timer to be set after stepping down")
+	}
+
+	// Replies to the stale ballot should be ignored.
+	for i := 0; i < 2; i++ {
+		reply := pb.WrapMessage(&pb.PreAcceptOK{})
+		reply.To = 0
+		reply.InstanceID = inst.is.InstanceID
+		p.Step(reply)
+	}
+	inst.assertState(pb.InstanceState_PreAccepted)
+	p.assertOutboxEmpty(t)
+}
+
+// waitRecoverInstance waits until the given instance has been executed on all
+// live nodes, which requires that some replica recovers it.
+func (n *network) waitRecoverInstance(id pb.InstanceID) bool {
+	return n.runNetworkFor(recoveryTicks, func() bool {
+		return n.allAliveHave(func(p *epaxos) bool {
+			return p.hasExecuted(id.ReplicaID, id.InstanceNum)
+		})
+	})
+}
+
+// assertRecoveredCommand asserts that the given instance has been committed
+// with the same command on all live nodes.
+func (n *network) assertRecoveredCommand(t *testing.T, id pb.InstanceID, cmd *pb.Command) {
+	for r, p := range n.peers {
+		if !n.alive(p) {
+			continue
+		}
+		inst := p.getInstance(id.ReplicaID, id.InstanceNum)
+		if a, e := inst.is.Command, cmd; !reflect.DeepEqual(a, e) {
+			t.Errorf("peer %d: expected recovered command %v, found %v", r, e, a)
+		}
+	}
+}
+
+// TestRecoverPreAcceptedInstance verifies that an instance which was
+// pre-accepted by all replicas is committed with its original command after
+// its command leader crashes.
+func TestRecoverPreAcceptedInstance(t *testing.T) {
+	n := newNetwork(5)
+
+	cmd := newTestingCommand("a", "z")
+	inst := n.peers[0].onRequest(cmd)
+
+	// Deliver the PreAccept messages, then crash the command leader before
+	// it receives any replies.
+	n.deliverAllMessages()
+	n.crash(0)
+
+	if !n.waitRecoverInstance(inst.is.InstanceID) {
+		t.Fatalf("instance %+v never recovered", inst.is.InstanceID)
+	}
+	n.assertRecoveredCommand(t, inst.is.InstanceID, cmd)
+}
+
+// TestRecoverAcceptedInstance verifies that an instance which was accepted by
+// a quorum of replicas is committed with its original command after its
+// command leader crashes.
+func TestRecoverAcceptedInstance(t *testing.T) {
+	n := newNetwork(5)
+
+	// Cut the command leader off from two nodes so that the command can not
+	// fast-path.
+	n.cut(0, 3)
+	n.cut(0, 4)
+
+	cmd := newTestingCommand("a", "z")
+	inst := n.peers[0].onRequest(cmd)
+
+	if !n.waitAcceptInstance(inst, true /* quorum */) {
+		t.Fatalf("command acceptance failed, instance %+v never installed", inst)
+	}
+	n.clearAllMessages()
+	n.crash(0)
+	n.heal()
+
+	if !n.waitRecoverInstance(inst.is.InstanceID) {
+		t.Fatalf("instance %+v never recovered", inst.is.InstanceID)
+	}
+	n.assertRecoveredCommand(t, inst.is.InstanceID, cmd)
+}
+
+// TestRecoverUnknownDependency verifies that a committed instance which
+// depends on an instance that most replicas have never heard of is able to
+// execute after the dependency's command leader crashes.
+func TestRecoverUnknownDependency(t *testing.T) {
+	n := newNetwork(5)
+
+	// The command leader only manages to send its PreAccept to replica 1
+	// before crashing.
+	depInst := n.peers[0].onRequest(newTestingCommand("a", "z"))
+	for _, m := range n.peers[0].ReadMessages() {
+		if m.To == 1 {
+			n.peers[1].Step(m)
+		}
+	}
+	n.clearAllMessages()
+	n.crash(0)
+
+	// Replica 1 proposes an interfering command, which depends on the
+	// unfinished instance.
+	inst := n.peers[1].onRequest(newTestingCommand("a", "z"))
+	if !reflect.DeepEqual(inst.is.Deps, []pb.InstanceID{depInst.is.InstanceID}) {
+		t.Fatalf("expected instance to depend on %v, found %v", depInst.is.InstanceID, inst.is.Deps)
+	}
+
+	if !n.waitRecoverInstance(inst.is.InstanceID) {
+		t.Fatalf("instance %+v never executed", inst.is.InstanceID)
+	}
+
+	// The dependency was either committed with its original command or as a
+	// no-op, but all replicas must agree.
+	var depData *pb.InstanceData
+	for r, p := range n.peers {
+		if !n.alive(p) {
+			continue
+		}
+		dep := p.getInstance(0, 1)
+		if !dep.isStates(pb.InstanceState_Executed) {
+			t.Fatalf("peer %d: expected dependency to be executed, found %v", r, dep.is.Status)
+		}
+		if depData == nil {
+			depData = &dep.is.InstanceData
+		} else if !reflect.DeepEqual(*depData, dep.is.InstanceData) {
+			t.Errorf("peer %d: recovered dependencies differ: %+v vs %+v", r, *depData, dep.is.InstanceData)
+		}
+	}
+}
+
+// TestPreemptedLeaderStepsDown verifies that a command leader that falls
+// behind while another replica recovers its instance has its stale messages
+// rejected, and that it ends up agreeing with the outcome of the recovery.
+func TestPreemptedLeaderStepsDown(t *testing.T) {
+	n := newNetwork(5)
+
+	// Cut the command leader off from two nodes so that the command can not
+	// fast-path, and let it gather enough replies to arm its slow path timer.
+	n.cut(0, 3)
+	n.cut(0, 4)
+
+	cmd := newTestingCommand("a", "z")
+	inst := n.peers[0].onRequest(cmd)
+	n.deliverAllMessages()
+	n.deliverAllMessages()
+	if !inst.slowPathTimer.isSet() {
+		t.Fatalf("expected slow path timer to be set")
+	}
+
+	// Stall the command leader until the other replicas have recovered the
+	// instance.
+	n.crash(0)
+	n.heal()
+	if !n.waitRecoverInstance(inst.is.InstanceID) {
+		t.Fatalf("instance %+v never recovered", inst.is.InstanceID)
+	}
+
+	// When the command leader resumes, it sends Accept messages at its stale
+	// ballot. These are rejected, and the leader eventually learns the
+	// recovered outcome.
+	n.revive(0)
+	if !n.waitRecoverInstance(inst.is.InstanceID) {
+		t.Fatalf("instance %+v never executed on command leader", inst.is.InstanceID)
+	}
+	if inst.is.Ballot.IsInitial() {
+		t.Errorf("expected command leader to observe a larger ballot")
+	}
+	n.assertRecoveredCommand(t, inst.is.InstanceID, cmd)
+}
+
+// TestRecoverFastPathCommitWithConflictingReply verifies that recovery commits
+// an instance that its command leader committed on the fast path with the
+// same dependencies, even if another replica in the Prepare quorum
+// pre-accepted it with different dependencies. With 3 nodes, a single reply
+// from a replica other than the command leader is enough to recognize the
+// fast path, so only replies that left the leader's data unchanged may count.
+func TestRecoverFastPathCommitWithConflictingReply(t *testing.T) {
+	n := newNetwork(3)
+
+	// Replica 1 proposes a command, but none of its messages are delivered.
+	n.peers[1].onRequest(newTestingCommand("a", "z"))
+	n.peers[1].ReadMessages()
+
+	// Replica 0 proposes an interfering command. Replica 1 adds a dependency
+	// on its own instance, while replica 2 pre-accepts it unchanged.
+	inst := n.peers[0].onRequest(newTestingCommand("a", "z"))
+	for _, m := range n.peers[0].ReadMessages() {
+		n.peers[m.To].Step(m)
+	}
+	n.peers[1].ReadMessages()
+	for _, m := range n.peers[2].ReadMessages() {
+		n.peers[0].Step(m)
+	}
+	inst.assertState(pb.InstanceState_Committed, pb.InstanceState_Executed)
+	if len(inst.is.Deps) != 0 {
+		t.Fatalf("expected fast path commit without dependencies, found %v", inst.is.Deps)
+	}
+	committed := inst.instanceData()
+
+	// The command leader crashes before its Commit messages are delivered.
+	n.peers[0].ReadMessages()
+	n.crash(0)
+
+	if !n.waitRecoverInstance(inst.is.InstanceID) {
+		t.Fatalf("instance %+v never recovered", inst.is.InstanceID)
+	}
+	for _, r := range []pb.ReplicaID{1, 2} {
+		recovered := n.peers[r].getInstance(0, 1).instanceData()
+		if !reflect.DeepEqual(recovered, committed) {
+			t.Errorf("peer %d: expected recovered data %v, found %v", r, committed, recovered)
+		}
+	}
+}
+
+// TestFastPathCandidate tests which PreAccepted replies recovery considers to
+// be a possible fast path commit by the command leader, in a 3 node network.
+func TestFastPathCandidate(t *testing.T) {
+	p := newEPaxos(&Config{ID: 1, Nodes: []pb.ReplicaID{0, 1, 2}})
+	inst := p.newInstance(0, 1)
+
+	a := pb.InstanceData{SeqNum: 1}
+	b := pb.InstanceData{SeqNum: 2, Deps: []pb.InstanceID{{ReplicaID: 1, InstanceNum: 1}}}
+	reply := func(from pb.ReplicaID, data pb.InstanceData, unchanged bool, ballot pb.Ballot) *pb.PrepareReply {
+		return &pb.PrepareReply{
+			From:                 from,
+			Status:               pb.InstanceState_PreAccepted,
+			InstanceData:         data,
+			AcceptedBallot:       ballot,
+			PreAcceptedUnchanged: unchanged,
+		}
+	}
+	initial := pb.Ballot{}
+	recovery := pb.Ballot{Number: 1, ReplicaID: 2}
+
+	testCases := []struct {
+		replies []*pb.PrepareReply
+		exp     *pb.InstanceData
+	}{
+		{[]*pb.PrepareReply{reply(1, b, false, initial), reply(2, a, true, initial)}, &a},
+		{[]*pb.PrepareReply{reply(1, b, false, initial), reply(2, a, false, initial)}, nil},
+		// The command leader's own reply does not count.
+		{[]*pb.PrepareReply{reply(0, a, true, initial), reply(1, b, false, initial)}, nil},
+		// Two different candidates are ambiguous.
+		{[]*pb.PrepareReply{reply(1, b, true, initial), reply(2, a, true, initial)}, nil},
+		// Replies at a larger ballot take precedence.
+		{[]*pb.PrepareReply{reply(1, b, false, recovery), reply(2, a, true, initial)}, nil},
+	}
+	for i, tc := range testCases {
+		data, ok := inst.fastPathCandidate(tc.replies)
+		if tc.exp == nil {
+			if ok {
+				t.Errorf("%d: expected no fast path candidate, found %v", i, data)
+			}
+		} else if !ok || !reflect.DeepEqual(data, *tc.exp) {
+			t.Errorf("%d: expected fast path candidate %v, found %v (%t)", i, *tc.exp, data, ok)
+		}
+	}
+}
+
+// TestFastPathCandidateEarlierEpoch tests that recovery recognizes a fast path
+// commit with the quorum sizes of the epoch of the instance's ballot, after a
+// fourth node was added to a 3 node network.
+func TestFastPathCandidateEarlierEpoch(t *testing.T) {
+	p := newEPaxos(&Config{ID: 1, Nodes: []pb.ReplicaID{0, 1, 2, 3}})
+	p.epoch = 1
+	p.configurations[0] = []pb.ReplicaID{0, 1, 2}
+	inst := p.newInstance(0, 1)
+
+	a := pb.InstanceData{SeqNum: 1}
+	b := pb.InstanceData{SeqNum: 2, Deps: []pb.InstanceID{{ReplicaID: 1, InstanceNum: 1}}}
+	replies := []*pb.PrepareReply{
+		{From: 1, Status: pb.InstanceState_PreAccepted, InstanceData: b},
+		{From: 2, Status: pb.InstanceState_PreAccepted, InstanceData: a, PreAcceptedUnchanged: true},
+		{From: 3, Status: pb.InstanceState_None},
+	}
+	if data, ok := inst.fastPathCandidate(replies); !ok || !reflect.DeepEqual(data, a) {
+		t.Errorf("expected fast path candidate %v, found %v (%t)", a, data, ok)
+	}
+}
+
+// TestPrepareQuorumEarlierEpoch tests that recovery waits for a Prepare quorum
+// of the nodes of every earlier epoch that the instance was pre-accepted in.
+func TestPrepareQuorumEarlierEpoch(t *testing.T) {
+	p := newEPaxos(&Config{ID: 3, Nodes: []pb.ReplicaID{0, 1, 2, 3, 4}})
+	p.epoch = 2
+	p.configurations[0] = []pb.ReplicaID{0, 1, 2}
+	p.configurations[1] = []pb.ReplicaID{0, 1, 2, 3}
+	inst := p.newInstance(0, 1)
+	inst.prepareReplies = map[pb.ReplicaID]*pb.PrepareReply{
+		3: {From: 3, Status: pb.InstanceState_None},
+		4: {From: 4, Status: pb.InstanceState_None},
+	}
+	if inst.hasPrepareQuorum() {
+		t.Errorf("expected no Prepare quorum of the current epoch")
+	}
+
+	inst.prepareReplies[1] = &pb.PrepareReply{From: 1, Status: pb.InstanceState_PreAccepted}
+	if inst.hasPrepareQuorum() {
+		t.Errorf("expected no Prepare quorum of the epoch the instance was pre-accepted in")
+	}
+
+	inst.prepareReplies[2] = &pb.PrepareReply{From: 2, Status: pb.InstanceState_None}
+	if !inst.hasPrepareQuorum() {
+		t.Errorf("expected Prepare quorum of all epochs")
+	}
+}
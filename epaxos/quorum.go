@@ -0,0 +1,116 @@
+package epaxos
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Quorum determines how many replicas, including the replica running a phase,
+// must participate in each phase of the EPaxos protocol in a network of n
+// nodes. Regardless of the Quorum, a command leader always waits for PreAccept
+// replies from a simple majority before taking the slow path, because the
+// attributes of any two interfering commands must be computed by intersecting
+// sets of replicas.
+//
+// Larger fast path quorums make the fast path harder to achieve but allow
+// smaller Prepare quorums, and vice versa.
+type Quorum interface {
+	// FastPath returns the number of replicas that must pre-accept a command
+	// with identical attributes for the command leader to commit it without
+	// running the Paxos-Accept phase.
+	FastPath(n int) int
+	// SlowPath returns the number of replicas that must accept a command in
+	// the Paxos-Accept phase for it to be committed.
+	SlowPath(n int) int
+	// Prepare returns the number of replicas that must reply to a Prepare
+	// message for the recovering replica to decide on the outcome of an
+	// instance.
+	Prepare(n int) int
+	// Validate returns an error if the quorums are not safe to use in a
+	// network of n nodes.
+	Validate(n int) error
+}
+
+func majority(n int) int {
+	return n/2 + 1
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// ClassicQuorum is the quorum system used by the basic EPaxos protocol. Its
+// fast path quorum includes all but one of the replicas, and all slow path
+// quorums are simple majorities. It is the default Quorum.
+type ClassicQuorum struct{}
+
+// FastPath implements the Quorum interface.
+func (ClassicQuorum) FastPath(n int) int { return maxInt(n-1, majority(n)) }
+
+// SlowPath implements the Quorum interface.
+func (ClassicQuorum) SlowPath(n int) int { return majority(n) }
+
+// Prepare implements the Quorum interface.
+func (ClassicQuorum) Prepare(n int) int { return majority(n) }
+
+// Validate implements the Quorum interface.
+func (ClassicQuorum) Validate(n int) error { return nil }
+
+// FlexibleQuorum is a quorum system with fixed quorum sizes for each phase,
+// following Flexible Paxos. The Paxos-Accept phase only needs to intersect
+// with the Prepare phase, so a small SlowPath quorum can be traded for a large
+// Prepare quorum, making recovery of failed command leaders slower and less
+// fault tolerant in exchange for faster commits in the common case.
+//
+// Because the quorum sizes are fixed, they must be revalidated whenever the
+// set of nodes in the EPaxos network changes.
+type FlexibleQuorum struct {
+	// FastPathSize is the size of fast path quorums. It must be a majority
+	// of the nodes and, together with PrepareSize, exceed the number of nodes.
+	// A Prepare quorum must also not be able to hold two disjoint sets of
+	// FastPathSize+PrepareSize-N replicas, which is how many replicas of a
+	// fast path quorum recovery requires to recognize a fast path commit.
+	FastPathSize int
+	// SlowPathSize is the size of Paxos-Accept quorums. Together with
+	// PrepareSize, it must exceed the number of nodes.
+	SlowPathSize int
+	// PrepareSize is the size of Prepare quorums.
+	PrepareSize int
+}
+
+// FastPath implements the Quorum interface.
+func (q FlexibleQuorum) FastPath(n int) int { return q.FastPathSize }
+
+// SlowPath implements the Quorum interface.
+func (q FlexibleQuorum) SlowPath(n int) int { return q.SlowPathSize }
+
+// Prepare implements the Quorum interface.
+func (q FlexibleQuorum) Prepare(n int) int { return q.PrepareSize }
+
+// Validate implements the Quorum interface.
+func (q FlexibleQuorum) Validate(n int) error {
+	for _, size := range []int{q.FastPathSize, q.SlowPathSize, q.PrepareSize} {
+		if size < 1 || size > n {
+			return errors.Errorf("quorum size %d out of range for %d nodes", size, n)
+		}
+	}
+	if q.FastPathSize < majority(n) {
+		return errors.Errorf("fast path quorum %d is not a majority of %d nodes",
+			q.FastPathSize, n)
+	}
+	if q.SlowPathSize+q.PrepareSize <= n {
+		return errors.Errorf("slow path quorum %d does not intersect Prepare quorum %d",
+			q.SlowPathSize, q.PrepareSize)
+	}
+	if q.FastPathSize+q.PrepareSize <= n {
+		return errors.Errorf("fast path quorum %d does not intersect Prepare quorum %d",
+			q.FastPathSize, q.PrepareSize)
+	}
+	if minIdentical := q.FastPathSize + q.PrepareSize - n; 2*minIdentical <= q.PrepareSize {
+		return errors.Errorf("Prepare quorum %d can hold two different fast path candidates of %d replicas",
+			q.PrepareSize, minIdentical)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+func TestQuorumSizes(t *testing.T) {
+	testCases := []struct {
+		q                   Quorum
+		n                   int
+		fast, slow, prepare int
+	}{
+		{ClassicQuorum{}, 1, 1, 1, 1},
+		{ClassicQuorum{}, 3, 2, 2, 2},
+		{ClassicQuorum{}, 5, 4, 3, 3},
+		{ClassicQuorum{}, 7, 6, 4, 4},
+		{FlexibleQuorum{FastPathSize: 4, SlowPathSize: 2, PrepareSize: 4}, 5, 4, 2, 4},
+	}
+	for _, tc := range testCases {
+		if a, e := tc.q.FastPath(tc.n), tc.fast; a != e {
+			t.Errorf("%T with %d nodes: expected fast path quorum %d, found %d", tc.q, tc.n, e, a)
+		}
+		if a, e := tc.q.SlowPath(tc.n), tc.slow; a != e {
+			t.Errorf("%T with %d nodes: expected slow path quorum %d, found %d", tc.q, tc.n, e, a)
+		}
+		if a, e := tc.q.Prepare(tc.n), tc.prepare; a != e {
+			t.Errorf("%T with %d nodes: expected Prepare quorum %d, found %d", tc.q, tc.n, e, a)
+		}
+		if err := tc.q.Validate(tc.n); err != nil {
+			t.Errorf("%T with %d nodes: unexpected error %v", tc.q, tc.n, err)
+		}
+	}
+}
+
+func TestFlexibleQuorumValidate(t *testing.T) {
+	testCases := []struct {
+		q     FlexibleQuorum
+		valid bool
+	}{
+		{FlexibleQuorum{FastPathSize: 4, SlowPathSize: 3, PrepareSize: 3}, true},
+		{FlexibleQuorum{FastPathSize: 4, SlowPathSize: 2, PrepareSize: 4}, true},
+		{FlexibleQuorum{FastPathSize: 5, SlowPathSize: 1, PrepareSize: 5}, true},
+		// Quorum sizes out of range.
+		{FlexibleQuorum{FastPathSize: 4, SlowPathSize: 0, PrepareSize: 5}, false},
+		{FlexibleQuorum{FastPathSize: 6, SlowPathSize: 3, PrepareSize: 3}, false},
+		// Fast path quorums must intersect.
+		{FlexibleQuorum{FastPathSize: 2, SlowPathSize: 3, PrepareSize: 4}, false},
+		// Slow path quorums must intersect Prepare quorums.
+		{FlexibleQuorum{FastPathSize: 4, SlowPathSize: 2, PrepareSize: 3}, false},
+		// Fast path quorums must intersect Prepare quorums.
+		{FlexibleQuorum{FastPathSize: 3, SlowPathSize: 4, PrepareSize: 2}, false},
+		// Fast path candidates during recovery must be unambiguous.
+		{FlexibleQuorum{FastPathSize: 3, SlowPathSize: 3, PrepareSize: 3}, false},
+		{FlexibleQuorum{FastPathSize: 3, SlowPathSize: 2, PrepareSize: 4}, false},
+	}
+	for _, tc := range testCases {
+		if err := tc.q.Validate(5); (err == nil) != tc.valid {
+			t.Errorf("%+v: expected valid=%t, found error %v", tc.q, tc.valid, err)
+		}
+	}
+
+	c := &Config{
+		ID:     0,
+		Nodes:  []pb.ReplicaID{0, 1, 2, 3, 4},
+		Quorum: FlexibleQuorum{FastPathSize: 4, SlowPathSize: 2, PrepareSize: 3},
+	}
+	if err := c.validate(); err == nil {
+		t.Errorf("expected error for Config with unsafe Quorum")
+	}
+}
+
+// TestFlexibleQuorumSlowPath tests that a command leader commits an instance
+// once a FlexibleQuorum's slow path quorum has accepted it, even if that is
+// not a majority.
+func TestFlexibleQuorumSlowPath(t *testing.T) {
+	c := &Config{
+		ID:     0,
+		Nodes:  []pb.ReplicaID{0, 1, 2, 3, 4},
+		Quorum: FlexibleQuorum{FastPathSize: 4, SlowPathSize: 2, PrepareSize: 4},
+	}
+	p := newEPaxos(c)
+	inst := p.onRequest(testingCmd)
+	inst.transitionTo(pb.InstanceState_Accepted, ReasonSlowPathTimeout)
+	p.clearMsgs()
+
+	p.Step(pb.Message{
+		To:         0,
+		InstanceID: inst.is.InstanceID,
+		Type:       pb.WrapMessageInner(&pb.AcceptOK{}),
+	})
+	inst.assertState(pb.InstanceState_Committed, pb.InstanceState_Executed)
+}
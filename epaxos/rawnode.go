@@ -0,0 +1,92 @@
+package epaxos
+
+import (
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+// RawNode is a thread-unsafe handle around the epaxos state machine. Unlike a
+// Node, it processes every call synchronously in the calling goroutine, which
+// allows a caller like a simulator to drive a set of replicas
+// deterministically. Its Ready must be handled like a Node's Ready, and
+// Advance must be called before the next Ready is handed out.
+type RawNode struct {
+	p *epaxos
+	// pending is set while a Ready has been handed out and not advanced.
+	pending bool
+}
+
+// NewRawNode returns a new RawNode with the given configuration. An error is
+// returned if the configuration is invalid or if the state of the RawNode can
+// not be read out of its Storage.
+func NewRawNode(c *Config) (*RawNode, error) {
+	p, err := startEPaxos(c)
+	if err != nil {
+		return nil, err
+	}
+	return &RawNode{p: p}, nil
+}
+
+// Tick increments the internal logical clock of the RawNode.
+func (rn *RawNode) Tick() {
+	rn.p.Tick()
+}
+
+// Propose proposes that the command be replicated in a new instance. Unlike
+// Node.Propose, commands are not batched.
+func (rn *RawNode) Propose(cmd pb.Command) {
+	rn.p.Request(&cmd)
+}
+
+// Read performs a linearizable read of the command's span, like Node.Read.
+// The read is returned in the ExecutedCommands of a later Ready.
+func (rn *RawNode) Read(cmd pb.Command) error {
+	if !validRead(cmd) {
+		return ErrInvalidRead
+	}
+	rn.p.Read(&cmd)
+	return nil
+}
+
+// Step advances the state machine using the given message.
+func (rn *RawNode) Step(m pb.Message) {
+	rn.p.Step(m)
+}
+
+// ApplySnapshot applies a snapshot of another replica's state machine, like
+// Node.ApplySnapshot.
+func (rn *RawNode) ApplySnapshot(snap pb.Snapshot) {
+	rn.p.applySnapshot(snap)
+}
+
+// HasReady returns whether Ready would return any updates.
+func (rn *RawNode) HasReady() bool {
+	return !rn.pending && makeReady(rn.p).containsUpdates()
+}
+
+// Ready returns the current point-in-time state of the RawNode. The Ready is
+// empty if the previous Ready has not been advanced.
+func (rn *RawNode) Ready() Ready {
+	if rn.pending {
+		return Ready{}
+	}
+	rd := makeReady(rn.p)
+	acceptReady(rn.p)
+	rn.pending = true
+	return rd
+}
+
+// Advance notifies the RawNode that the application has persisted and
+// applied the last Ready. ErrNoReady is returned if no Ready has been handed
+// out since the last call to Advance.
+func (rn *RawNode) Advance() error {
+	if !rn.pending {
+		return ErrNoReady
+	}
+	rn.pending = false
+	return nil
+}
+
+// Status returns the current status of the replica.
+func (rn *RawNode) Status() Status {
+	return rn.p.status()
+}
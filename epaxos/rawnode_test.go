@@ -0,0 +1,55 @@
+package epaxos
+
+import (
+	"testing"
+
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+// TestRawNodeReadyAdvance verifies that a RawNode hands out a proposal's
+// state in a Ready, and does not hand out another Ready until the previous
+// one has been advanced.
+func TestRawNodeReadyAdvance(t *testing.T) {
+	rn, err := NewRawNode(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rn.Advance(); err != ErrNoReady {
+		t.Errorf("expected %v when advancing without a Ready, found %v", ErrNoReady, err)
+	}
+	if !rn.HasReady() {
+		t.Fatalf("expected initial Ready")
+	}
+	if rd := rn.Ready(); rd.HardState == nil {
+		t.Fatalf("expected initial HardState in Ready")
+	}
+
+	rn.Propose(*newTestingCommand("a", "z"))
+	if rn.HasReady() {
+		t.Errorf("expected no Ready before the previous one is advanced")
+	}
+	if rd := rn.Ready(); rd.containsUpdates() {
+		t.Errorf("expected empty Ready before the previous one is advanced, found %+v", rd)
+	}
+	if err := rn.Advance(); err != nil {
+		t.Fatal(err)
+	}
+
+	rd := rn.Ready()
+	if len(rd.InstanceStates) != 1 {
+		t.Errorf("expected proposed instance in Ready, found %v", rd.InstanceStates)
+	}
+	if a, e := len(rd.Messages), 2; a != e {
+		t.Errorf("expected %d PreAccept messages in Ready, found %d", e, a)
+	}
+	if err := rn.Advance(); err != nil {
+		t.Fatal(err)
+	}
+	if rn.HasReady() {
+		t.Errorf("expected no Ready after all updates were advanced")
+	}
+
+	if err := rn.Read(*newTestingCommand("a", "")); err != ErrInvalidRead {
+		t.Errorf("expected %v for writing read, found %v", ErrInvalidRead, err)
+	}
+}
@@ -0,0 +1,209 @@
+package epaxos
+
+import (
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+// maxReadRetransmits is the number of times that a Read message is resent to
+// the replicas that have not replied to it before the read is abandoned.
+const maxReadRetransmits = 10
+
+// read is a linearizable read that the local replica performs without
+// proposing it in an instance.
+//
+// A command that has been committed was accepted by a fast path or slow path
+// quorum of replicas. The read asks a quorum of replicas that intersects all
+// of those quorums for the instances they know of that interfere with it, so
+// it learns about every interfering command that may have been committed and
+// acknowledged before the read started. Once all of these instances have
+// executed locally, the read is delivered with the executed commands and
+// observes their effects.
+type read struct {
+	cmd pb.Command
+	// replies holds the replicas that have replied to the read, including the
+	// local replica.
+	replies map[pb.ReplicaID]struct{}
+	// deps holds the instances that must execute before the read.
+	deps map[pb.InstanceID]struct{}
+	// truncated is a mapping from replica to the maximum truncation index
+	// reported for its command space. All instances up to and including the
+	// index must execute before the read.
+	truncated map[pb.ReplicaID]pb.InstanceNum
+	// quorum is set once a read quorum of replicas has replied.
+	quorum bool
+
+	retransmitTimer tickingTimer
+	retransmits     int
+}
+
+// readQuorum returns the number of replicas, including the local replica, that
+// must reply to a read. Any two sets of that size intersect with all fast path
+// and slow path quorums.
+func (p *epaxos) readQuorum() int {
+	n := len(p.nodes)
+	commit := p.quorums.FastPath(n)
+	if s := p.quorums.SlowPath(n); s < commit {
+		commit = s
+	}
+	return n - commit + 1
+}
+
+// onReadRequest starts a linearizable read of the command's span. The command
+// is delivered with the executed commands once it can observe all interfering
+// commands that may have been committed before the read started.
+func (p *epaxos) onReadRequest(cmd *pb.Command) {
+	if _, ok := p.reads[cmd.ID]; ok {
+		p.logger.Warningf("ignoring duplicate read %+v", cmd)
+		return
+	}
+	r := &read{
+		cmd:       *cmd,
+		replies:   make(map[pb.ReplicaID]struct{}, len(p.nodes)),
+		deps:      make(map[pb.InstanceID]struct{}),
+		truncated: make(map[pb.ReplicaID]pb.InstanceNum),
+	}
+	p.reads[cmd.ID] = r
+
+	local := p.readReply(cmd.ID, cmd.Span)
+	p.addReadReply(r, &local)
+	if r.quorum {
+		return
+	}
+
+	r.retransmitTimer = makeTickingTimer(p.retransmitTimeout, func() {
+		r.retransmits++
+		if r.retransmits > maxReadRetransmits {
+			p.logger.Warningf("abandoning read %+v without a quorum", r.cmd)
+			p.unregisterTimer(&r.retransmitTimer)
+			delete(p.reads, r.cmd.ID)
+			p.deliverFailedCommand(r.cmd.ID, ErrReadAbandoned)
+			return
+		}
+		p.sendRead(r)
+	})
+	p.registerInfiniteTimer(&r.retransmitTimer)
+	p.sendRead(r)
+}
+
+// sendRead sends a Read message to each replica that has not yet replied to
+// the read.
+func (p *epaxos) sendRead(r *read) {
+	for _, node := range p.nodes {
+		if _, ok := r.replies[node]; ok {
+			continue
+		}
+		m := pb.WrapMessage(&pb.Read{ReadID: r.cmd.ID, From: p.id, Span: r.cmd.Span})
+		m.To = node
+		p.send(m)
+	}
+}
+
+// onRead replies to a Read message with the instances that interfere with it.
+func (p *epaxos) onRead(m *pb.Read) {
+	reply := p.readReply(m.ReadID, m.Span)
+	mm := pb.WrapMessage(&reply)
+	mm.To = m.From
+	p.send(mm)
+}
+
+// readReply returns the local replica's reply to a read of the span. The
+// instances that interfere with the read are found with the conflict index of
+// each command space. The reply holds every write of the span that has not
+// been truncated, not only the latest one: the latest write may still be
+// recovered as a no-op without dependencies, which would let the read execute
+// before the earlier writes.
+func (p *epaxos) readReply(id uint64, span pb.Span) pb.ReadReply {
+	reply := pb.ReadReply{
+		ReadID:                id,
+		From:                  p.id,
+		TruncatedInstanceNums: make(map[pb.ReplicaID]pb.InstanceNum, len(p.maxTruncatedInstanceNum)),
+	}
+	readCmd := pb.Command{Span: span}
+	for r, ci := range p.conflicts {
+		for _, inst := range ci.writes(span) {
+			if inst.is.Command.Interferes(readCmd) {
+				reply.Deps = append(reply.Deps, inst.is.InstanceID)
+			}
+		}
+		if i := p.maxTruncatedInstanceNum[r]; i > 0 {
+			reply.TruncatedInstanceNums[r] = i
+		}
+	}
+	return reply
+}
+
+// onReadReply handles a reply to a read that the local replica is performing.
+func (p *epaxos) onReadReply(m *pb.ReadReply) {
+	r, ok := p.reads[m.ReadID]
+	if !ok || r.quorum {
+		return
+	}
+	if _, ok := r.replies[m.From]; ok {
+		return
+	}
+	p.addReadReply(r, m)
+}
+
+// addReadReply adds a replica's reply to the read. Once a quorum has replied,
+// the local replica makes sure that it will learn the outcome of all instances
+// that the read waits on, and delivers the read if they have all executed.
+func (p *epaxos) addReadReply(r *read, m *pb.ReadReply) {
+	r.replies[m.From] = struct{}{}
+	for _, dep := range m.Deps {
+		r.deps[dep] = struct{}{}
+	}
+	for rep, i := range m.TruncatedInstanceNums {
+		if i > r.truncated[rep] {
+			r.truncated[rep] = i
+		}
+	}
+	if len(r.replies) < p.readQuorum() {
+		return
+	}
+
+	r.quorum = true
+	p.unregisterTimer(&r.retransmitTimer)
+	for dep := range r.deps {
+		p.watchInstance(dep.ReplicaID, dep.InstanceNum)
+	}
+	for rep, upTo := range r.truncated {
+		for i := p.maxTruncatedInstanceNum[rep] + 1; i <= upTo; i++ {
+			p.watchInstance(rep, i)
+		}
+	}
+	p.maybeDeliverRead(r)
+}
+
+// maybeDeliverReads delivers all reads that have heard from a quorum and whose
+// instances have all executed.
+func (p *epaxos) maybeDeliverReads() {
+	for _, r := range p.reads {
+		if r.quorum {
+			p.maybeDeliverRead(r)
+		}
+	}
+}
+
+// maybeDeliverRead delivers the read with the executed commands if all of the
+// instances that it waits on have executed. Instances that have executed are
+// forgotten, so that they are not checked again.
+func (p *epaxos) maybeDeliverRead(r *read) {
+	for dep := range r.deps {
+		if p.hasInstanceSpace(dep.ReplicaID) && !p.hasExecuted(dep.ReplicaID, dep.InstanceNum) {
+			return
+		}
+		delete(r.deps, dep)
+	}
+	for rep, upTo := range r.truncated {
+		if p.hasInstanceSpace(rep) {
+			for i := p.maxTruncatedInstanceNum[rep] + 1; i <= upTo; i++ {
+				if !p.hasExecuted(rep, i) {
+					return
+				}
+			}
+		}
+		delete(r.truncated, rep)
+	}
+	delete(p.reads, r.cmd.ID)
+	p.deliverExecutedCommand(r.cmd)
+}
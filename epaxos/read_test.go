@@ -0,0 +1,199 @@
+package epaxos
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+func TestReadQuorum(t *testing.T) {
+	testCases := []struct {
+		q    Quorum
+		n    int
+		read int
+	}{
+		{ClassicQuorum{}, 1, 1},
+		{ClassicQuorum{}, 3, 2},
+		{ClassicQuorum{}, 5, 3},
+		{FlexibleQuorum{FastPathSize: 4, SlowPathSize: 2, PrepareSize: 4}, 5, 4},
+	}
+	for _, tc := range testCases {
+		nodes := make([]pb.ReplicaID, tc.n)
+		for i := range nodes {
+			nodes[i] = pb.ReplicaID(i)
+		}
+		p := newEPaxos(&Config{ID: 0, Nodes: nodes, Quorum: tc.q})
+		if a, e := p.readQuorum(), tc.read; a != e {
+			t.Errorf("%T with %d nodes: expected read quorum %d, found %d", tc.q, tc.n, e, a)
+		}
+	}
+}
+
+// readDelivered returns whether the read has been delivered with the
+// executed commands, which are appended to cmds.
+func readDelivered(p *epaxos, read *pb.Command, cmds *[]pb.Command) bool {
+	*cmds = append(*cmds, p.ExecutableCommands()...)
+	for _, cmd := range *cmds {
+		if cmd.ID == read.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// TestReadWithoutInterference verifies that a read that no instance interferes
+// with is delivered as soon as a read quorum has replied.
+func TestReadWithoutInterference(t *testing.T) {
+	n := newNetwork(1)
+	read := newTestingReadCommand("a", "")
+	n.peers[0].Read(read)
+	if a := n.peers[0].ExecutableCommands(); len(a) != 1 || a[0].ID != read.ID {
+		t.Errorf("expected read to be delivered immediately on single node, found %v", a)
+	}
+
+	n = newNetwork(3)
+	inst := n.peers[1].onRequest(newTestingCommand("a", ""))
+	if !n.waitExecuteInstance(inst, false /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+	n.peers[0].ExecutableCommands()
+
+	read = newTestingReadCommand("b", "")
+	n.peers[0].Read(read)
+	if a := n.peers[0].ExecutableCommands(); len(a) != 0 {
+		t.Errorf("expected read to wait for a read quorum, found %v", a)
+	}
+	n.deliverAllMessages()
+	n.deliverAllMessages()
+	if a := n.peers[0].ExecutableCommands(); len(a) != 1 || a[0].ID != read.ID {
+		t.Errorf("expected read to be delivered, found %v", a)
+	}
+	if l := len(n.peers[0].reads); l != 0 {
+		t.Errorf("expected no pending reads, found %d", l)
+	}
+}
+
+// TestReadWaitsForInterferingInstances verifies that a read observes a write
+// that was executed before the read started, even if the replica performing
+// the read never heard of the write.
+func TestReadWaitsForInterferingInstances(t *testing.T) {
+	n := newNetwork(3)
+	n.isolate(0)
+	write := newTestingCommand("a", "")
+	inst := n.peers[1].onRequest(write)
+	if !n.waitExecuteInstance(inst, true /* quorum */) {
+		t.Fatalf("command execution failed, instance %+v never installed", inst)
+	}
+	n.heal()
+	if n.peers[0].getInstance(inst.is.ReplicaID, inst.is.InstanceNum) != nil {
+		t.Fatalf("expected replica 0 to not know about instance %v", inst.is.InstanceID)
+	}
+
+	read := newTestingReadCommand("a", "")
+	n.peers[0].Read(read)
+	var cmds []pb.Command
+	if !n.runNetworkFor(100, func() bool { return readDelivered(n.peers[0], read, &cmds) }) {
+		t.Fatalf("read was never delivered")
+	}
+	if len(cmds) != 2 || cmds[0].ID != write.ID || cmds[1].ID != read.ID {
+		t.Errorf("expected write to execute before read, found %v", cmds)
+	}
+}
+
+// TestReadReplyWrites verifies that a reply to a read holds all writes of the
+// span in each command space, but not the reads.
+func TestReadReplyWrites(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+	for i, cmd := range []*pb.Command{
+		newTestingCommand("a", ""),
+		newTestingCommand("a", ""),
+		newTestingReadCommand("a", ""),
+		newTestingCommand("b", ""),
+		newTestingCommand("a", "c"),
+	} {
+		inst := p.newInstance(1, pb.InstanceNum(i+1))
+		inst.setCommand(cmd)
+		p.commands[1].ReplaceOrInsert(inst)
+	}
+
+	reply := p.readReply(1, pb.Span{Key: pb.Key("a")})
+	exp := []pb.InstanceID{
+		{ReplicaID: 1, InstanceNum: 5},
+		{ReplicaID: 1, InstanceNum: 2},
+		{ReplicaID: 1, InstanceNum: 1},
+	}
+	if !reflect.DeepEqual(reply.Deps, exp) {
+		t.Errorf("expected read dependencies %v, found %v", exp, reply.Deps)
+	}
+}
+
+// TestReadLatestWriteRecoveredAsNoOp verifies that a read waits for an earlier
+// committed write of its span, even if the latest write that replicas reported
+// is recovered as a no-op, which executes without dependencies.
+func TestReadLatestWriteRecoveredAsNoOp(t *testing.T) {
+	p := newEPaxos(&Config{ID: 0, Nodes: []pb.ReplicaID{0, 1, 2}})
+	p.addInstanceSpace(1)
+	earlier := p.newInstance(1, 1)
+	earlier.setCommand(newTestingCommand("a", ""))
+	earlier.is.Status = pb.InstanceState_Committed
+	p.commands[1].ReplaceOrInsert(earlier)
+	latest := p.newInstance(1, 2)
+	latest.setCommand(newTestingCommand("a", ""))
+	latest.is.Status = pb.InstanceState_PreAccepted
+	latest.is.Deps = []pb.InstanceID{earlier.is.InstanceID}
+	p.commands[1].ReplaceOrInsert(latest)
+
+	read := newTestingReadCommand("a", "")
+	p.Read(read)
+	reply := p.readReply(read.ID, read.Span)
+	reply.From = 1
+
+	// The latest write is recovered as a no-op and executes, while the
+	// earlier write is still pending.
+	latest.setInstanceData(pb.InstanceData{})
+	latest.is.Status = pb.InstanceState_Executed
+	p.onReadReply(&reply)
+	if a := p.ExecutableCommands(); len(a) != 0 {
+		t.Fatalf("expected read to wait for the earlier write, found %v", a)
+	}
+
+	earlier.is.Status = pb.InstanceState_Executed
+	p.maybeDeliverReads()
+	if a := p.ExecutableCommands(); len(a) != 1 || a[0].ID != read.ID {
+		t.Errorf("expected read to be delivered, found %v", a)
+	}
+}
+
+// TestReadRetransmit verifies that Read messages are resent until a read
+// quorum has replied, and that a read is abandoned if one never does.
+func TestReadRetransmit(t *testing.T) {
+	n := newNetwork(3)
+	p := n.peers[0]
+	n.isolate(0)
+	read := newTestingReadCommand("a", "")
+	p.Read(read)
+	n.deliverAllMessages()
+	n.heal()
+
+	var cmds []pb.Command
+	if !n.runNetworkFor(p.retransmitTimeout+1, func() bool { return readDelivered(p, read, &cmds) }) {
+		t.Fatalf("read was never delivered")
+	}
+
+	n.isolate(0)
+	timers := len(p.timers)
+	read = newTestingReadCommand("a", "")
+	p.Read(read)
+	n.runNetworkFor((maxReadRetransmits+1)*p.retransmitTimeout, func() bool { return false })
+	if l := len(p.reads); l != 0 {
+		t.Errorf("expected read to be abandoned, found %d pending reads", l)
+	}
+	if a, e := len(p.timers), timers; a != e {
+		t.Errorf("expected %d timers after read was abandoned, found %d", e, a)
+	}
+	exp := []failedCommand{{id: read.ID, err: ErrReadAbandoned}}
+	if a := p.failedCmds; !reflect.DeepEqual(a, exp) {
+		t.Errorf("expected failed commands %v, found %v", exp, a)
+	}
+}
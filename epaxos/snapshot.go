@@ -0,0 +1,204 @@
+package epaxos
+
+import (
+	"sort"
+
+	"github.com/google/btree"
+
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+// defaultSnapshotTimeout is the default minimum number of ticks between two
+// snapshots sent to the same replica.
+const defaultSnapshotTimeout = 5 * defaultRecoveryTimeout
+
+// maybeSendSnapshot requests that a snapshot of the local state machine be
+// sent to the provided replica, which has fallen behind the local truncation
+// indexes. Snapshots are throttled so that a replica that is behind on many
+// instances is not sent a snapshot for each of them.
+func (p *epaxos) maybeSendSnapshot(to pb.ReplicaID) {
+	if t, ok := p.snapshotTimers[to]; ok && t.isSet() {
+		return
+	}
+	p.logger.Debugf("replica %v is behind truncation index, sending snapshot", to)
+	t := makeTickingTimer(p.snapshotTimeout, func() {})
+	p.snapshotTimers[to] = &t
+	p.registerOneTimeTimer(&t)
+	p.snapshotsTo = append(p.snapshotsTo, to)
+}
+
+// snapshotRequests returns the snapshots that need to be sent to other
+// replicas. All snapshots share the same metadata, which describes the local
+// state machine once all executed commands have been applied to it.
+func (p *epaxos) snapshotRequests() []SnapshotRequest {
+	if len(p.snapshotsTo) == 0 {
+		return nil
+	}
+	meta := p.snapshotMetadata()
+	reqs := make([]SnapshotRequest, len(p.snapshotsTo))
+	for i, to := range p.snapshotsTo {
+		reqs[i] = SnapshotRequest{To: to, Metadata: meta}
+	}
+	return reqs
+}
+
+// snapshotMetadata returns metadata describing all instances that have been
+// executed by the local replica.
+func (p *epaxos) snapshotMetadata() pb.SnapshotMetadata {
+	meta := pb.SnapshotMetadata{
+		ExecutedInstanceNums: make(map[pb.ReplicaID]pb.InstanceNum, len(p.commands)),
+		MaxSeqNum:            p.maxTruncatedSeqNum,
+		Nodes:                p.nodes,
+		Epoch:                p.epoch,
+		Configurations:       p.configurationList(),
+	}
+	for r, cmds := range p.commands {
+		executedUpTo := p.maxTruncatedInstanceNum[r]
+		cmds.Ascend(func(i btree.Item) bool {
+			inst := i.(*instance)
+			if !inst.isStates(pb.InstanceState_Executed) {
+				return true
+			}
+			meta.MaxSeqNum = pb.MaxSeqNum(meta.MaxSeqNum, inst.is.SeqNum)
+			if inst.is.InstanceNum == executedUpTo+1 {
+				executedUpTo++
+			} else {
+				meta.ExecutedInstances = append(meta.ExecutedInstances, inst.is)
+			}
+			return true
+		})
+		meta.ExecutedInstanceNums[r] = executedUpTo
+	}
+	sort.Slice(meta.ExecutedInstances, func(i, j int) bool {
+		a, b := meta.ExecutedInstances[i], meta.ExecutedInstances[j]
+		if a.ReplicaID != b.ReplicaID {
+			return a.ReplicaID < b.ReplicaID
+		}
+		return a.InstanceNum < b.InstanceNum
+	})
+	return meta
+}
+
+func (p *epaxos) clearSnapshotRequests() {
+	p.snapshotsTo = nil
+}
+
+// applySnapshot applies a snapshot of another replica's state machine. The
+// snapshot replaces the local state machine, so it is only applied if it
+// reflects every instance that has been executed locally and at least one
+// that has not. It returns whether the snapshot was applied.
+func (p *epaxos) applySnapshot(snap pb.Snapshot) bool {
+	meta := snap.Metadata
+	for r := range meta.ExecutedInstanceNums {
+		p.addInstanceSpace(r)
+	}
+	executed := make(map[pb.InstanceID]struct{}, len(meta.ExecutedInstances))
+	for _, is := range meta.ExecutedInstances {
+		executed[is.InstanceID] = struct{}{}
+	}
+	reflected := func(r pb.ReplicaID, i pb.InstanceNum) bool {
+		_, ok := executed[pb.InstanceID{ReplicaID: r, InstanceNum: i}]
+		return ok || i <= meta.ExecutedInstanceNums[r]
+	}
+
+	ahead := false
+	for r, cmds := range p.commands {
+		truncatedUpTo, executedUpTo := p.maxTruncatedInstanceNum[r], meta.ExecutedInstanceNums[r]
+		if truncatedUpTo > executedUpTo {
+			p.logger.Debugf("ignoring snapshot behind truncation index of replica %v", r)
+			return false
+		}
+
+		// Every locally executed instance must be reflected in the snapshot.
+		// Any instance reflected in the snapshot that has not been executed
+		// locally means that the snapshot is ahead of the local replica.
+		covered := true
+		executedInRange := 0
+		cmds.Ascend(func(i btree.Item) bool {
+			inst := i.(*instance)
+			isExecuted := inst.isStates(pb.InstanceState_Executed)
+			isReflected := reflected(r, inst.is.InstanceNum)
+			if isExecuted && !isReflected {
+				covered = false
+				return false
+			}
+			if isExecuted && inst.is.InstanceNum <= executedUpTo {
+				executedInRange++
+			}
+			if !isExecuted && isReflected {
+				ahead = true
+			}
+			return true
+		})
+		if !covered {
+			p.logger.Debugf("ignoring snapshot missing executed instances of replica %v", r)
+			return false
+		}
+		if executedInRange < int(executedUpTo-truncatedUpTo) {
+			ahead = true
+		}
+	}
+	for _, is := range meta.ExecutedInstances {
+		if p.hasInstanceSpace(is.ReplicaID) && !p.hasExecuted(is.ReplicaID, is.InstanceNum) {
+			ahead = true
+		}
+	}
+	if !ahead {
+		p.logger.Debugf("ignoring snapshot that is not ahead of local state")
+		return false
+	}
+
+	// Truncate all instances up to the snapshot's indexes.
+	var truncated []pb.ReplicaID
+	for r := range p.commands {
+		if upTo := meta.ExecutedInstanceNums[r]; upTo > p.maxTruncatedInstanceNum[r] {
+			p.truncateInstances(r, upTo)
+			truncated = append(truncated, r)
+		}
+	}
+	p.maxTruncatedSeqNum = pb.MaxSeqNum(p.maxTruncatedSeqNum, meta.MaxSeqNum)
+
+	// Adopt the configuration of the EPaxos network once all reflected
+	// instances, including any reconfiguration commands, have executed.
+	if meta.Epoch > p.epoch {
+		p.configurations[p.epoch] = p.nodes
+		p.nodes = meta.Nodes
+		p.epoch = meta.Epoch
+		p.addConfigurations(meta.Configurations)
+	}
+
+	// Install all other instances reflected in the snapshot as executed.
+	for _, is := range meta.ExecutedInstances {
+		r, i := is.ReplicaID, is.InstanceNum
+		if !p.hasInstanceSpace(r) || p.hasExecuted(r, i) {
+			continue
+		}
+		inst := p.getInstance(r, i)
+		if inst == nil {
+			inst = p.newInstance(r, i)
+			p.commands[r].ReplaceOrInsert(inst)
+		}
+		p.unregisterTimer(&inst.slowPathTimer)
+		p.unregisterTimer(&inst.recoveryTimer)
+		p.unregisterTimer(&inst.thriftyTimer)
+		p.executor.removeExec(inst.Identifier())
+		inst.setStatus(pb.InstanceState_Executed, ReasonSnapshot)
+		inst.setInstanceData(is.InstanceData)
+		inst.persist()
+	}
+
+	// All commands that were executed locally but not yet applied to the
+	// state machine are reflected in the snapshot.
+	p.clearExecutedCommands()
+	p.snapshot = &snap
+
+	p.persistHardState()
+	for _, r := range truncated {
+		p.truncateStorage(r)
+	}
+	return true
+}
+
+func (p *epaxos) clearSnapshot() {
+	p.snapshot = nil
+}
@@ -0,0 +1,188 @@
+package epaxos
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+func TestSnapshotMetadata(t *testing.T) {
+	p := newTestingEPaxos()
+	executeTestingInstances(p,
+		pb.InstanceID{ReplicaID: 0, InstanceNum: 1},
+		pb.InstanceID{ReplicaID: 1, InstanceNum: 2},
+	)
+	p.maxTruncatedInstanceNum[2] = 4
+	p.maxTruncatedSeqNum = 2
+
+	meta := p.snapshotMetadata()
+	expExecuted := map[pb.ReplicaID]pb.InstanceNum{
+		0: 1,
+		1: 0,
+		2: 4,
+	}
+	if a, e := meta.ExecutedInstanceNums, expExecuted; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected executed instance numbers %v, found %v", e, a)
+	}
+	if a, e := len(meta.ExecutedInstances), 1; a != e {
+		t.Fatalf("expected %d executed instances, found %d", e, a)
+	}
+	if a, e := meta.ExecutedInstances[0].InstanceID, (pb.InstanceID{ReplicaID: 1, InstanceNum: 2}); a != e {
+		t.Errorf("expected executed instance %v, found %v", e, a)
+	}
+	if a, e := meta.MaxSeqNum, pb.SeqNum(5); a != e {
+		t.Errorf("expected max seq num %v, found %v", e, a)
+	}
+}
+
+func TestApplySnapshot(t *testing.T) {
+	p := newTestingEPaxos()
+	executeTestingInstances(p, pb.InstanceID{ReplicaID: 0, InstanceNum: 1})
+	inst12 := p.getInstance(1, 2)
+	p.deliverExecutedCommand(*testingCmd)
+
+	// A snapshot that does not reflect a locally executed instance is ignored.
+	snap := pb.Snapshot{
+		Metadata: pb.SnapshotMetadata{
+			ExecutedInstanceNums: map[pb.ReplicaID]pb.InstanceNum{1: 1},
+		},
+		Data: []byte("data"),
+	}
+	if p.applySnapshot(snap) {
+		t.Fatalf("expected snapshot missing executed instance to be ignored")
+	}
+
+	// A snapshot that is not ahead of the local replica is ignored.
+	snap.Metadata.ExecutedInstanceNums = map[pb.ReplicaID]pb.InstanceNum{0: 1}
+	if p.applySnapshot(snap) {
+		t.Fatalf("expected snapshot that is not ahead to be ignored")
+	}
+
+	// A snapshot that reflects all locally executed instances and is ahead of
+	// the local replica is applied.
+	snap.Metadata = pb.SnapshotMetadata{
+		ExecutedInstanceNums: map[pb.ReplicaID]pb.InstanceNum{0: 2, 1: 1, 2: 3},
+		ExecutedInstances: []pb.InstanceState{{
+			InstanceID:   inst12.is.InstanceID,
+			InstanceData: inst12.is.InstanceData,
+		}},
+		MaxSeqNum: 9,
+	}
+	if !p.applySnapshot(snap) {
+		t.Fatalf("expected snapshot to be applied")
+	}
+	if a, e := p.snapshot, &snap; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected snapshot %v to be ready to apply, found %v", e, a)
+	}
+	if a := p.executedCmds; len(a) != 0 {
+		t.Errorf("expected commands reflected in snapshot to be dropped, found %v", a)
+	}
+	for r, i := range snap.Metadata.ExecutedInstanceNums {
+		if a := p.maxTruncatedInstanceNum[r]; a != i {
+			t.Errorf("expected truncated instance number %v for replica %v, found %v", i, r, a)
+		}
+	}
+	if !p.hasExecuted(1, 2) || p.getInstance(1, 2) != inst12 {
+		t.Errorf("expected instance 1.2 to be executed")
+	}
+	if a, e := p.maxTruncatedSeqNum, pb.SeqNum(9); a != e {
+		t.Errorf("expected truncated seq num %v, found %v", e, a)
+	}
+	p.persistReady()
+	hs, _, _ := p.storage.HardState()
+	if a, e := hs.TruncatedInstanceNums, snap.Metadata.ExecutedInstanceNums; !reflect.DeepEqual(a, e) {
+		t.Errorf("expected persisted truncated instance numbers %v, found %v", e, a)
+	}
+}
+
+// TestSendSnapshotForTruncatedInstance tests that a replica requests that a
+// snapshot be sent to a replica that tries to decide an instance that has
+// already been truncated.
+func TestSendSnapshotForTruncatedInstance(t *testing.T) {
+	p := newTestingEPaxos()
+	p.truncateInstances(1, 3)
+
+	// Commits are not sent by lagging replicas.
+	p.Step(pb.Message{
+		To:         0,
+		InstanceID: pb.InstanceID{ReplicaID: 1, InstanceNum: 3},
+		Type:       pb.WrapMessageInner(&pb.Commit{}),
+	})
+	if a := p.snapshotRequests(); len(a) != 0 {
+		t.Fatalf("expected no snapshot requests, found %v", a)
+	}
+
+	for i := 0; i < 2; i++ {
+		p.Step(prepareMsg(pb.Ballot{Number: 1, ReplicaID: 2}))
+	}
+	p.assertOutboxEmpty(t)
+	reqs := p.snapshotRequests()
+	if len(reqs) != 1 || reqs[0].To != 2 {
+		t.Fatalf("expected a single snapshot request to replica 2, found %v", reqs)
+	}
+	p.clearSnapshotRequests()
+
+	// Snapshots are throttled.
+	p.Step(prepareMsg(pb.Ballot{Number: 2, ReplicaID: 2}))
+	if a := p.snapshotRequests(); len(a) != 0 {
+		t.Fatalf("expected no snapshot requests, found %v", a)
+	}
+	for i := 0; i < defaultSnapshotTimeout; i++ {
+		p.Tick()
+	}
+	p.Step(prepareMsg(pb.Ballot{Number: 3, ReplicaID: 2}))
+	if a := p.snapshotRequests(); len(a) != 1 {
+		t.Fatalf("expected a snapshot request, found %v", a)
+	}
+}
+
+// TestCatchUpWithSnapshot verifies that a replica that was down while other
+// replicas executed and truncated instances is able to catch up using a
+// snapshot.
+func TestCatchUpWithSnapshot(t *testing.T) {
+	n := newNetwork(3)
+	n.crash(2)
+
+	var insts []*instance
+	for _, r := range []pb.ReplicaID{0, 1} {
+		inst := n.peers[r].onRequest(newTestingCommand("a", "z"))
+		if !n.waitExecuteInstance(inst, true /* quorum */) {
+			t.Fatalf("command execution failed, instance %+v never installed", inst)
+		}
+		insts = append(insts, inst)
+	}
+	truncated := func(p *epaxos) bool {
+		for _, inst := range insts {
+			if !p.hasTruncated(inst.is.ReplicaID, inst.is.InstanceNum) {
+				return false
+			}
+		}
+		return true
+	}
+	if !n.runNetworkFor(2*defaultTruncateTimeout, func() bool {
+		n.applyExecutedCommands()
+		return n.allAliveHave(truncated)
+	}) {
+		t.Fatalf("executed instances never truncated")
+	}
+
+	// The lagging replica learns about the truncated instances through the
+	// dependencies of a new command, which it can only execute once it has
+	// caught up.
+	n.revive(2)
+	inst := n.peers[0].onRequest(newTestingCommand("a", "z"))
+	if !n.runNetworkFor(recoveryTicks, func() bool {
+		return n.allHave(func(p *epaxos) bool {
+			return p.hasExecuted(inst.is.ReplicaID, inst.is.InstanceNum)
+		})
+	}) {
+		t.Fatalf("instance %+v never executed on all replicas", inst.is.InstanceID)
+	}
+	if !truncated(n.peers[2]) {
+		t.Errorf("expected lagging replica to truncate instances reflected in snapshot")
+	}
+	if n.peers[2].snapshot == nil {
+		t.Errorf("expected lagging replica to apply snapshot")
+	}
+}
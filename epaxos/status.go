@@ -0,0 +1,97 @@
+package epaxos
+
+import (
+	"sort"
+
+	"github.com/google/btree"
+
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+// Status is a point-in-time snapshot of the state of an epaxos replica.
+type Status struct {
+	// ID is the local replica's ID.
+	ID pb.ReplicaID
+	// Nodes is the set of all nodes in the EPaxos network.
+	Nodes []pb.ReplicaID
+	// Epoch is the configuration epoch of the EPaxos network.
+	Epoch uint64
+	// ConfChangeInstance is the instance of the last executed ConfChange.
+	ConfChangeInstance pb.InstanceID
+
+	// Replicas holds the status of each replica's command space, including
+	// those of replicas that have been removed from the EPaxos network.
+	Replicas map[pb.ReplicaID]ReplicaStatus
+	// InstanceCounts counts the instances in all command spaces by status.
+	// Truncated instances are not counted.
+	InstanceCounts map[pb.InstanceState_Status]int
+
+	// PendingExecutions holds the committed instances that the executor has
+	// not executed yet, in order of their IDs.
+	PendingExecutions []pb.InstanceID
+	// BlockedExecutions is the number of PendingExecutions that are blocked on
+	// a dependency.
+	BlockedExecutions int
+	// ArmedTimers is the number of timers that will fire once enough ticks
+	// have elapsed.
+	ArmedTimers int
+	// PendingReads is the number of linearizable reads that have not been
+	// delivered yet.
+	PendingReads int
+}
+
+// ReplicaStatus is a point-in-time snapshot of a replica's command space.
+type ReplicaStatus struct {
+	// MaxInstanceNum is the largest instance number known in the command
+	// space.
+	MaxInstanceNum pb.InstanceNum
+	// MaxSeqNum is the largest sequence number of the instances in the command
+	// space that have not been truncated.
+	MaxSeqNum pb.SeqNum
+	// MaxTruncatedInstanceNum is the instance number up to which the command
+	// space has been truncated.
+	MaxTruncatedInstanceNum pb.InstanceNum
+}
+
+// status returns the current Status of the replica.
+func (p *epaxos) status() Status {
+	s := Status{
+		ID:                 p.id,
+		Nodes:              append([]pb.ReplicaID(nil), p.nodes...),
+		Epoch:              p.epoch,
+		ConfChangeInstance: p.confChangeInstance,
+		Replicas:           make(map[pb.ReplicaID]ReplicaStatus, len(p.commands)),
+		InstanceCounts:     make(map[pb.InstanceState_Status]int),
+		PendingReads:       len(p.reads),
+	}
+	for r, cmds := range p.commands {
+		rs := ReplicaStatus{
+			MaxInstanceNum:          p.maxInstanceNum(r),
+			MaxTruncatedInstanceNum: p.maxTruncatedInstanceNum[r],
+		}
+		cmds.Ascend(func(i btree.Item) bool {
+			inst := i.(*instance)
+			rs.MaxSeqNum = pb.MaxSeqNum(rs.MaxSeqNum, inst.is.SeqNum)
+			s.InstanceCounts[inst.is.Status]++
+			return true
+		})
+		s.Replicas[r] = rs
+	}
+
+	for id, v := range p.executor.vertices {
+		if instID, ok := id.(pb.InstanceID); ok {
+			s.PendingExecutions = append(s.PendingExecutions, instID)
+		}
+		if v.blocked {
+			s.BlockedExecutions++
+		}
+	}
+	sort.Sort(pb.InstanceIDs(s.PendingExecutions))
+
+	for t := range p.timers {
+		if t.isSet() {
+			s.ArmedTimers++
+		}
+	}
+	return s
+}
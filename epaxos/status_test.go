@@ -0,0 +1,56 @@
+package epaxos
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/mjolk/epx2/epaxos/epaxospb"
+)
+
+func TestStatus(t *testing.T) {
+	p := newTestingEPaxos()
+	executeTestingInstances(p, pb.InstanceID{ReplicaID: 0, InstanceNum: 1})
+
+	// Instance 1.2 is blocked on instance 1.1, which has not been committed.
+	inst12 := p.getInstance(1, 2)
+	inst12.is.Status = pb.InstanceState_Committed
+	p.executor.addExec(inst12)
+	p.executor.run()
+
+	s := p.status()
+	if s.ID != 0 || !reflect.DeepEqual(s.Nodes, p.nodes) || s.Epoch != p.epoch {
+		t.Errorf("expected configuration of replica in status, found %+v", s)
+	}
+	expReplicas := map[pb.ReplicaID]ReplicaStatus{
+		0: {MaxInstanceNum: 2, MaxSeqNum: 4},
+		1: {MaxInstanceNum: 2, MaxSeqNum: 5},
+		2: {MaxInstanceNum: 1, MaxSeqNum: 3},
+	}
+	if !reflect.DeepEqual(s.Replicas, expReplicas) {
+		t.Errorf("expected replica statuses %+v, found %+v", expReplicas, s.Replicas)
+	}
+	expCounts := map[pb.InstanceState_Status]int{
+		pb.InstanceState_None:      3,
+		pb.InstanceState_Committed: 1,
+		pb.InstanceState_Executed:  1,
+	}
+	if !reflect.DeepEqual(s.InstanceCounts, expCounts) {
+		t.Errorf("expected instance counts %v, found %v", expCounts, s.InstanceCounts)
+	}
+	expPending := []pb.InstanceID{{ReplicaID: 1, InstanceNum: 2}}
+	if !reflect.DeepEqual(s.PendingExecutions, expPending) || s.BlockedExecutions != 1 {
+		t.Errorf("expected blocked pending executions %v, found %v with %d blocked",
+			expPending, s.PendingExecutions, s.BlockedExecutions)
+	}
+	// The executor and truncation timers are always armed.
+	if s.ArmedTimers != 2 {
+		t.Errorf("expected 2 armed timers, found %d", s.ArmedTimers)
+	}
+
+	// Truncated instances are reflected in the status of their replica.
+	executeTestingInstances(p, pb.InstanceID{ReplicaID: 0, InstanceNum: 2})
+	p.truncateInstances(0, 2)
+	if a, e := p.status().Replicas[0], (ReplicaStatus{MaxInstanceNum: 2, MaxTruncatedInstanceNum: 2}); a != e {
+		t.Errorf("expected status %+v of truncated replica, found %+v", e, a)
+	}
+}
@@ -7,12 +7,24 @@ import (
 )
 
 // Storage allows for the persistence of EPaxos state to provide durability.
+// The Node only uses it when it starts, and fails to start if any of its
+// methods returns an error. Afterwards, all state to persist is handed out in
+// Ready, and a failure to persist it must stop the Node, because the
+// durability of the state that it promised to other replicas can no longer be
+// guaranteed.
 type Storage interface {
-	HardState() (pb.HardState, bool)
-	PersistHardState(hs pb.HardState)
+	// HardState returns the persisted HardState, and whether one was found.
+	HardState() (pb.HardState, bool, error)
+	// PersistHardState persists the HardState.
+	PersistHardState(hs pb.HardState) error
 
-	Instances() []*pb.InstanceState
-	PersistInstance(is *pb.InstanceState)
+	// Instances returns the states of all persisted instances.
+	Instances() ([]*pb.InstanceState, error)
+	// PersistInstance persists the state of an instance.
+	PersistInstance(is *pb.InstanceState) error
+	// TruncateInstances removes all instances in the replica's instance space
+	// up to and including the provided instance number.
+	TruncateInstances(r pb.ReplicaID, i pb.InstanceNum) error
 }
 
 var _ Storage = &MemoryStorage{}
@@ -40,17 +52,18 @@ func NewMemoryStorage(c *Config) Storage {
 }
 
 // HardState implements the Storage interface.
-func (ms *MemoryStorage) HardState() (pb.HardState, bool) {
+func (ms *MemoryStorage) HardState() (pb.HardState, bool, error) {
 	if ms.hardState.set {
-		return ms.hardState.hs, true
+		return ms.hardState.hs, true, nil
 	}
-	return pb.HardState{}, false
+	return pb.HardState{}, false, nil
 }
 
 // PersistHardState implements the Storage interface.
-func (ms *MemoryStorage) PersistHardState(hs pb.HardState) {
+func (ms *MemoryStorage) PersistHardState(hs pb.HardState) error {
 	ms.hardState.hs = hs
 	ms.hardState.set = true
+	return nil
 }
 
 func instanceStateKey(i pb.InstanceNum) btree.Item {
@@ -58,7 +71,7 @@ func instanceStateKey(i pb.InstanceNum) btree.Item {
 }
 
 // Instances implements the Storage interface.
-func (ms *MemoryStorage) Instances() []*pb.InstanceState {
+func (ms *MemoryStorage) Instances() ([]*pb.InstanceState, error) {
 	var insts []*pb.InstanceState
 	for _, replInsts := range ms.instances {
 		replInsts.Ascend(func(i btree.Item) bool {
@@ -66,10 +79,32 @@ func (ms *MemoryStorage) Instances() []*pb.InstanceState {
 			return true
 		})
 	}
-	return insts
+	return insts, nil
 }
 
 // PersistInstance implements the Storage interface.
-func (ms *MemoryStorage) PersistInstance(is *pb.InstanceState) {
-	ms.instances[is.ReplicaID].ReplaceOrInsert(is)
+func (ms *MemoryStorage) PersistInstance(is *pb.InstanceState) error {
+	replInsts, ok := ms.instances[is.ReplicaID]
+	if !ok {
+		// The replica was added to the EPaxos network.
+		replInsts = btree.New(32 /* degree */)
+		ms.instances[is.ReplicaID] = replInsts
+	}
+	replInsts.ReplaceOrInsert(is)
+	return nil
+}
+
+// TruncateInstances implements the Storage interface.
+func (ms *MemoryStorage) TruncateInstances(r pb.ReplicaID, i pb.InstanceNum) error {
+	replInsts, ok := ms.instances[r]
+	if !ok {
+		return nil
+	}
+	for {
+		minItem := replInsts.Min()
+		if minItem == nil || minItem.(*pb.InstanceState).InstanceNum > i {
+			return nil
+		}
+		replInsts.DeleteMin()
+	}
 }
@@ -0,0 +1,96 @@
+package epaxos
+
+import (
+	"sync"
+)
+
+// WaitStage is the stage of a proposed command that ProposeAndWait waits for
+// the command to reach.
+type WaitStage int
+
+const (
+	// WaitExecuted waits until the command has been executed and the result
+	// of applying it to the state-machine has been reported with ReportResult.
+	WaitExecuted WaitStage = iota
+	// WaitCommitted waits until the command has been committed. Its result is
+	// the CommittedCommand.
+	WaitCommitted
+)
+
+// proposalWaiter is a caller of ProposeAndWait that is waiting for its
+// command to reach a stage.
+type proposalWaiter struct {
+	stage WaitStage
+	c     chan interface{}
+}
+
+// proposalWaiters routes the results of proposed commands to the callers of
+// ProposeAndWait that are waiting on them. It is safe for concurrent use.
+type proposalWaiters struct {
+	mu sync.Mutex
+	m  map[uint64]proposalWaiter
+}
+
+func newProposalWaiters() *proposalWaiters {
+	return &proposalWaiters{m: make(map[uint64]proposalWaiter)}
+}
+
+// register registers a waiter for the command with the provided ID, returning
+// the channel that its result will be delivered on. It returns false if a
+// waiter is already registered for the command.
+func (w *proposalWaiters) register(id uint64, stage WaitStage) (<-chan interface{}, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.m[id]; ok {
+		return nil, false
+	}
+	c := make(chan interface{}, 1)
+	w.m[id] = proposalWaiter{stage: stage, c: c}
+	return c, true
+}
+
+// trigger delivers the result to the waiter for the command with the provided
+// ID, if it is waiting for the command to reach the stage.
+func (w *proposalWaiters) trigger(id uint64, stage WaitStage, result interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	pw, ok := w.m[id]
+	if !ok || pw.stage != stage {
+		return
+	}
+	delete(w.m, id)
+	pw.c <- result
+}
+
+// waitError wraps an error that a waiter is failed with, so that it can not
+// be confused with a result reported by the application.
+type waitError struct {
+	err error
+}
+
+// fail delivers the error to the waiter for the command with the provided ID,
+// regardless of the stage that it is waiting for.
+func (w *proposalWaiters) fail(id uint64, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	pw, ok := w.m[id]
+	if !ok {
+		return
+	}
+	delete(w.m, id)
+	pw.c <- waitError{err: err}
+}
+
+// cancel removes the waiter for the command with the provided ID.
+func (w *proposalWaiters) cancel(id uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.m, id)
+}
+
+// len returns the number of registered waiters.
+func (w *proposalWaiters) len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.m)
+}
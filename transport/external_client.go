@@ -6,10 +6,11 @@ import (
 	transpb "github.com/mjolk/epx2/transport/transportpb"
 )
 
-// ExternalClient is a client stub implementing the KVServiceClient
-// interface.
+// ExternalClient is a client stub implementing the KVServiceClient and
+// AdminServiceClient interfaces.
 type ExternalClient struct {
 	transpb.KVServiceClient
+	transpb.AdminServiceClient
 	*grpc.ClientConn
 }
 
@@ -20,5 +21,6 @@ func NewExternalClient(addr string) (*ExternalClient, error) {
 		return nil, err
 	}
 	client := transpb.NewKVServiceClient(conn)
-	return &ExternalClient{client, conn}, nil
+	admin := transpb.NewAdminServiceClient(conn)
+	return &ExternalClient{client, admin, conn}, nil
 }
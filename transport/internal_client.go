@@ -3,8 +3,10 @@ package transport
 import (
 	"time"
 
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 
+	epaxospb "github.com/mjolk/epx2/epaxos/epaxospb"
 	transpb "github.com/mjolk/epx2/transport/transportpb"
 )
 
@@ -14,6 +16,10 @@ var clientOpts = []grpc.DialOption{
 	grpc.WithTimeout(15 * time.Second),
 }
 
+// snapshotChunkSize is the maximum number of bytes of snapshot data sent in
+// each SnapshotChunk.
+const snapshotChunkSize = 1 << 20
+
 // EPaxosClient is a client stub implementing the EPaxosTransportClient
 // interface.
 type EPaxosClient struct {
@@ -30,3 +36,29 @@ func NewEPaxosClient(addr string) (*EPaxosClient, error) {
 	client := transpb.NewEPaxosTransportClient(conn)
 	return &EPaxosClient{client, conn}, nil
 }
+
+// SendSnapshot streams the snapshot to the EPaxos node in chunks.
+func (c *EPaxosClient) SendSnapshot(ctx context.Context, snap epaxospb.Snapshot) error {
+	stream, err := c.DeliverSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	data := snap.Data
+	chunk := &transpb.SnapshotChunk{Metadata: &snap.Metadata}
+	for {
+		n := len(data)
+		if n > snapshotChunkSize {
+			n = snapshotChunkSize
+		}
+		chunk.Data, data = data[:n], data[n:]
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			break
+		}
+		chunk = &transpb.SnapshotChunk{}
+	}
+	_, err = stream.CloseAndRecv()
+	return err
+}
@@ -1,6 +1,7 @@
 package transport
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -22,16 +23,37 @@ func init() {
 }
 
 // Request represents a request to perform a client update. It includes
-// a channel to return the globally ordered result on.
+// a channel to return the globally ordered result on, which is closed without
+// a result if the update fails.
 type Request struct {
+	// Context is the context of the client's RPC. It is done when the client
+	// stops waiting for the result.
+	Context context.Context
 	Command epaxospb.Command
 	ReturnC chan<- transpb.KVResult
 }
 
+// StatusRequest represents a request for the status of the local EPaxos node.
+// It includes a channel to return the status on, which is closed without a
+// status if it can not be determined.
+type StatusRequest struct {
+	ReturnC chan<- transpb.NodeStatus
+}
+
+// ErrRequestFailed is returned to clients whose update failed before it was
+// globally ordered.
+var ErrRequestFailed = errors.New("request failed")
+
+// ErrInvalidTxn is returned to clients whose transaction is empty or accesses
+// a key more than once.
+var ErrInvalidTxn = errors.New("invalid transaction")
+
 // EPaxosServer handles internal and external RPC messages for an EPaxos node.
 type EPaxosServer struct {
-	msgC chan *epaxospb.Message
-	reqC chan Request
+	msgC  chan *epaxospb.Message
+	snapC chan *epaxospb.Snapshot
+	reqC  chan Request
+	statC chan StatusRequest
 
 	lis        net.Listener
 	grpcServer *grpc.Server
@@ -45,12 +67,15 @@ func NewEPaxosServer(port int) (*EPaxosServer, error) {
 	}
 	ps := &EPaxosServer{
 		msgC:       make(chan *epaxospb.Message, 16),
+		snapC:      make(chan *epaxospb.Snapshot),
 		reqC:       make(chan Request, 16),
+		statC:      make(chan StatusRequest),
 		lis:        lis,
 		grpcServer: grpc.NewServer(),
 	}
 	transpb.RegisterEPaxosTransportServer(ps.grpcServer, ps)
 	transpb.RegisterKVServiceServer(ps.grpcServer, ps)
+	transpb.RegisterAdminServiceServer(ps.grpcServer, ps)
 	return ps, nil
 }
 
@@ -77,9 +102,72 @@ func (ps *EPaxosServer) DeliverMessage(
 	}
 }
 
+// DeliverSnapshot implements the PaxosTransportServer interface. It receives
+// the chunks of a snapshot from the client stream, reassembles them, and
+// passes the snapshot to the server's snapshot channel.
+func (ps *EPaxosServer) DeliverSnapshot(
+	stream transpb.EPaxosTransport_DeliverSnapshotServer,
+) error {
+	ctx := stream.Context()
+	var snap *epaxospb.Snapshot
+	for {
+		chunk, err := stream.Recv()
+		if err != nil {
+			if err != io.EOF {
+				return err
+			}
+			break
+		}
+		if snap == nil {
+			if chunk.Metadata == nil {
+				return errors.New("first snapshot chunk missing metadata")
+			}
+			snap = &epaxospb.Snapshot{Metadata: *chunk.Metadata}
+		}
+		snap.Data = append(snap.Data, chunk.Data...)
+	}
+	if snap == nil {
+		return errors.New("empty snapshot stream")
+	}
+	select {
+	case ps.snapC <- snap:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return stream.SendAndClose(&transpb.Empty{})
+}
+
+// ProposeConfChange implements the EPaxosTransportServer interface. It
+// receives a ConfChange from another node and passes it as a Request on the
+// server's update channel. The method will block until the ConfChange is
+// globally ordered and applied.
+func (ps *EPaxosServer) ProposeConfChange(
+	ctx context.Context, req *epaxospb.ConfChange,
+) (*transpb.Empty, error) {
+	cmd := epaxospb.Command{
+		ID:         rand.Uint64(),
+		ConfChange: req,
+	}
+	ret := make(chan transpb.KVResult, 1)
+	ps.reqC <- Request{
+		Context: ctx,
+		Command: cmd,
+		ReturnC: ret,
+	}
+	select {
+	case _, ok := <-ret:
+		if !ok {
+			return nil, ErrRequestFailed
+		}
+		return &transpb.Empty{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 // Read implements the KVServiceServer interface. It receives the KVReadRequest
 // from the client and passes it as a Request on the server's update channel.
-// The method will block until the update is globally ordered.
+// The method will block until the read has been served.
 func (ps *EPaxosServer) Read(
 	ctx context.Context, req *transpb.KVReadRequest,
 ) (*transpb.KVResult, error) {
@@ -92,11 +180,15 @@ func (ps *EPaxosServer) Read(
 	}
 	ret := make(chan transpb.KVResult, 1)
 	ps.reqC <- Request{
+		Context: ctx,
 		Command: cmd,
 		ReturnC: ret,
 	}
 	select {
-	case res := <-ret:
+	case res, ok := <-ret:
+		if !ok {
+			return nil, ErrRequestFailed
+		}
 		return &res, nil
 	case <-ctx.Done():
 		return nil, ctx.Err()
@@ -116,32 +208,166 @@ func (ps *EPaxosServer) Write(
 		},
 		Writing: true,
 		Data:    req.Value,
+		Session: req.Session,
 	}
 	ret := make(chan transpb.KVResult, 1)
 	ps.reqC <- Request{
+		Context: ctx,
 		Command: cmd,
 		ReturnC: ret,
 	}
 	select {
-	case res := <-ret:
+	case res, ok := <-ret:
+		if !ok {
+			return nil, ErrRequestFailed
+		}
 		return &res, nil
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
 }
 
+// CompareAndSwap implements the KVServiceServer interface. It receives the
+// KVCompareAndSwapRequest from the client and passes it as a Request on the
+// server's update channel. The method will block until the update is globally
+// ordered and applied.
+func (ps *EPaxosServer) CompareAndSwap(
+	ctx context.Context, req *transpb.KVCompareAndSwapRequest,
+) (*transpb.KVResult, error) {
+	cmd := epaxospb.Command{
+		ID: rand.Uint64(),
+		Span: epaxospb.Span{
+			Key: epaxospb.Key(req.Key),
+		},
+		Writing:       true,
+		Data:          req.Value,
+		Op:            epaxospb.Command_CompareAndSwap,
+		ExpectedValue: req.ExpectedValue,
+		Session:       req.Session,
+	}
+	if req.ExpectAbsent {
+		cmd.Op = epaxospb.Command_PutIfAbsent
+		cmd.ExpectedValue = nil
+	}
+	ret := make(chan transpb.KVResult, 1)
+	ps.reqC <- Request{
+		Context: ctx,
+		Command: cmd,
+		ReturnC: ret,
+	}
+	select {
+	case res, ok := <-ret:
+		if !ok {
+			return nil, ErrRequestFailed
+		}
+		return &res, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Txn implements the KVServiceServer interface. It receives the KVTxnRequest
+// from the client and passes it as a Request on the server's update channel.
+// The transaction is proposed as a single multi-span command that reads the
+// keys of its gets and writes the keys of its puts, and carries the request as
+// its data. The method will block until the transaction is globally ordered
+// and applied.
+func (ps *EPaxosServer) Txn(
+	ctx context.Context, req *transpb.KVTxnRequest,
+) (*transpb.KVTxnResult, error) {
+	cmd := epaxospb.Command{ID: rand.Uint64(), Session: req.Session}
+	keys := make(map[string]struct{}, len(req.Puts)+len(req.Gets))
+	addSpan := func(spans *[]epaxospb.Span, key []byte) bool {
+		if _, ok := keys[string(key)]; ok {
+			return false
+		}
+		keys[string(key)] = struct{}{}
+		*spans = append(*spans, epaxospb.Span{Key: epaxospb.Key(key)})
+		return true
+	}
+	for _, put := range req.Puts {
+		if !addSpan(&cmd.WriteSpans, put.Key) {
+			return nil, ErrInvalidTxn
+		}
+	}
+	for _, get := range req.Gets {
+		if !addSpan(&cmd.ReadSpans, get.Key) {
+			return nil, ErrInvalidTxn
+		}
+	}
+	if len(keys) == 0 {
+		return nil, ErrInvalidTxn
+	}
+	data, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Data = data
+
+	ret := make(chan transpb.KVResult, 1)
+	ps.reqC <- Request{
+		Context: ctx,
+		Command: cmd,
+		ReturnC: ret,
+	}
+	select {
+	case res, ok := <-ret:
+		if !ok || res.Txn == nil {
+			return nil, ErrRequestFailed
+		}
+		return res.Txn, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Status implements the AdminServiceServer interface. It passes a
+// StatusRequest on the server's status channel and blocks until the status of
+// the local EPaxos node is returned.
+func (ps *EPaxosServer) Status(
+	ctx context.Context, req *transpb.Empty,
+) (*transpb.NodeStatus, error) {
+	ret := make(chan transpb.NodeStatus, 1)
+	select {
+	case ps.statC <- StatusRequest{ReturnC: ret}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case status, ok := <-ret:
+		if !ok {
+			return nil, ErrRequestFailed
+		}
+		return &status, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 // Msgs returns the channel that all Paxos messages will be delivered from
 // the server on.
 func (ps *EPaxosServer) Msgs() <-chan *epaxospb.Message {
 	return ps.msgC
 }
 
+// Snapshots returns the channel that all snapshots from other EPaxos nodes
+// will be delivered from the server on.
+func (ps *EPaxosServer) Snapshots() <-chan *epaxospb.Snapshot {
+	return ps.snapC
+}
+
 // Requests returns the channel that all client requests will be delivered from
 // the server on.
 func (ps *EPaxosServer) Requests() <-chan Request {
 	return ps.reqC
 }
 
+// StatusRequests returns the channel that all requests for the status of the
+// local EPaxos node will be delivered from the server on.
+func (ps *EPaxosServer) StatusRequests() <-chan StatusRequest {
+	return ps.statC
+}
+
 // Serve begins serving on server, blocking until Stop is called or an error
 // is observed.
 func (ps *EPaxosServer) Serve() error {
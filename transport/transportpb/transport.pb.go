@@ -9,9 +9,16 @@
 
 	It has these top-level messages:
 		Empty
+		SnapshotChunk
 		KVReadRequest
 		KVWriteRequest
+		KVCompareAndSwapRequest
+		KVTxnRequest
+		KVTxnResult
 		KVResult
+		ReplicaStatus
+		InstanceCount
+		NodeStatus
 */
 package transportpb
 
@@ -49,6 +56,32 @@ func (m *Empty) String() string            { return proto.CompactTextString(m) }
 func (*Empty) ProtoMessage()               {}
 func (*Empty) Descriptor() ([]byte, []int) { return fileDescriptorTransport, []int{0} }
 
+// SnapshotChunk is a piece of a snapshot that is streamed between EPaxos
+// nodes. The first chunk of each snapshot carries the snapshot's metadata.
+type SnapshotChunk struct {
+	Metadata *epaxospb.SnapshotMetadata `protobuf:"bytes,1,opt,name=metadata" json:"metadata,omitempty"`
+	Data     []byte                     `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *SnapshotChunk) Reset()                    { *m = SnapshotChunk{} }
+func (m *SnapshotChunk) String() string            { return proto.CompactTextString(m) }
+func (*SnapshotChunk) ProtoMessage()               {}
+func (*SnapshotChunk) Descriptor() ([]byte, []int) { return fileDescriptorTransport, []int{1} }
+
+func (m *SnapshotChunk) GetMetadata() *epaxospb.SnapshotMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *SnapshotChunk) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
 type KVReadRequest struct {
 	Key []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
 }
@@ -56,7 +89,7 @@ type KVReadRequest struct {
 func (m *KVReadRequest) Reset()                    { *m = KVReadRequest{} }
 func (m *KVReadRequest) String() string            { return proto.CompactTextString(m) }
 func (*KVReadRequest) ProtoMessage()               {}
-func (*KVReadRequest) Descriptor() ([]byte, []int) { return fileDescriptorTransport, []int{1} }
+func (*KVReadRequest) Descriptor() ([]byte, []int) { return fileDescriptorTransport, []int{2} }
 
 func (m *KVReadRequest) GetKey() []byte {
 	if m != nil {
@@ -68,12 +101,15 @@ func (m *KVReadRequest) GetKey() []byte {
 type KVWriteRequest struct {
 	Key   []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
 	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// session identifies the request so that retries are applied at most
+	// once. It is unused for the puts of a transaction.
+	Session epaxospb.ClientSession `protobuf:"bytes,3,opt,name=session" json:"session"`
 }
 
 func (m *KVWriteRequest) Reset()                    { *m = KVWriteRequest{} }
 func (m *KVWriteRequest) String() string            { return proto.CompactTextString(m) }
 func (*KVWriteRequest) ProtoMessage()               {}
-func (*KVWriteRequest) Descriptor() ([]byte, []int) { return fileDescriptorTransport, []int{2} }
+func (*KVWriteRequest) Descriptor() ([]byte, []int) { return fileDescriptorTransport, []int{3} }
 
 func (m *KVWriteRequest) GetKey() []byte {
 	if m != nil {
@@ -89,17 +125,141 @@ func (m *KVWriteRequest) GetValue() []byte {
 	return nil
 }
 
+func (m *KVWriteRequest) GetSession() epaxospb.ClientSession {
+	if m != nil {
+		return m.Session
+	}
+	return epaxospb.ClientSession{}
+}
+
+// KVCompareAndSwapRequest is a request to write a value to a key only if the
+// key currently holds an expected value.
+type KVCompareAndSwapRequest struct {
+	Key []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// expected_value is the value that the key must hold for the swap to
+	// succeed.
+	ExpectedValue []byte `protobuf:"bytes,2,opt,name=expected_value,json=expectedValue,proto3" json:"expected_value,omitempty"`
+	// expect_absent, if set, makes the swap succeed only if the key holds no
+	// value instead, which turns the request into a put-if-absent.
+	ExpectAbsent bool   `protobuf:"varint,3,opt,name=expect_absent,json=expectAbsent,proto3" json:"expect_absent,omitempty"`
+	Value        []byte `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	// session identifies the request so that retries are applied at most
+	// once.
+	Session epaxospb.ClientSession `protobuf:"bytes,5,opt,name=session" json:"session"`
+}
+
+func (m *KVCompareAndSwapRequest) Reset()         { *m = KVCompareAndSwapRequest{} }
+func (m *KVCompareAndSwapRequest) String() string { return proto.CompactTextString(m) }
+func (*KVCompareAndSwapRequest) ProtoMessage()    {}
+func (*KVCompareAndSwapRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptorTransport, []int{4}
+}
+
+func (m *KVCompareAndSwapRequest) GetKey() []byte {
+	if m != nil {
+		return m.Key
+	}
+	return nil
+}
+
+func (m *KVCompareAndSwapRequest) GetExpectedValue() []byte {
+	if m != nil {
+		return m.ExpectedValue
+	}
+	return nil
+}
+
+func (m *KVCompareAndSwapRequest) GetExpectAbsent() bool {
+	if m != nil {
+		return m.ExpectAbsent
+	}
+	return false
+}
+
+func (m *KVCompareAndSwapRequest) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *KVCompareAndSwapRequest) GetSession() epaxospb.ClientSession {
+	if m != nil {
+		return m.Session
+	}
+	return epaxospb.ClientSession{}
+}
+
+// KVTxnRequest is a request to apply several puts and gets atomically. Each
+// key may only be accessed once in a transaction.
+type KVTxnRequest struct {
+	Puts []KVWriteRequest `protobuf:"bytes,1,rep,name=puts" json:"puts"`
+	Gets []KVReadRequest  `protobuf:"bytes,2,rep,name=gets" json:"gets"`
+	// session identifies the request so that retries are applied at most
+	// once.
+	Session epaxospb.ClientSession `protobuf:"bytes,3,opt,name=session" json:"session"`
+}
+
+func (m *KVTxnRequest) Reset()                    { *m = KVTxnRequest{} }
+func (m *KVTxnRequest) String() string            { return proto.CompactTextString(m) }
+func (*KVTxnRequest) ProtoMessage()               {}
+func (*KVTxnRequest) Descriptor() ([]byte, []int) { return fileDescriptorTransport, []int{5} }
+
+func (m *KVTxnRequest) GetPuts() []KVWriteRequest {
+	if m != nil {
+		return m.Puts
+	}
+	return nil
+}
+
+func (m *KVTxnRequest) GetGets() []KVReadRequest {
+	if m != nil {
+		return m.Gets
+	}
+	return nil
+}
+
+func (m *KVTxnRequest) GetSession() epaxospb.ClientSession {
+	if m != nil {
+		return m.Session
+	}
+	return epaxospb.ClientSession{}
+}
+
+// KVTxnResult is the result of a transaction. It holds the value of each key
+// that the transaction read, in the order of its gets.
+type KVTxnResult struct {
+	Gets []KVResult `protobuf:"bytes,1,rep,name=gets" json:"gets"`
+}
+
+func (m *KVTxnResult) Reset()                    { *m = KVTxnResult{} }
+func (m *KVTxnResult) String() string            { return proto.CompactTextString(m) }
+func (*KVTxnResult) ProtoMessage()               {}
+func (*KVTxnResult) Descriptor() ([]byte, []int) { return fileDescriptorTransport, []int{6} }
+
+func (m *KVTxnResult) GetGets() []KVResult {
+	if m != nil {
+		return m.Gets
+	}
+	return nil
+}
+
 // Result is an empty message. It is identical to google.protobuf.Empty, but
 // permits future modifications because it is custom.
 type KVResult struct {
 	Key   []byte `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
 	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// succeeded is whether a compare-and-swap was applied. If it was not,
+	// value is the key's current value.
+	Succeeded bool `protobuf:"varint,3,opt,name=succeeded,proto3" json:"succeeded,omitempty"`
+	// txn is the result of a transaction.
+	Txn *KVTxnResult `protobuf:"bytes,4,opt,name=txn" json:"txn,omitempty"`
 }
 
 func (m *KVResult) Reset()                    { *m = KVResult{} }
 func (m *KVResult) String() string            { return proto.CompactTextString(m) }
 func (*KVResult) ProtoMessage()               {}
-func (*KVResult) Descriptor() ([]byte, []int) { return fileDescriptorTransport, []int{3} }
+func (*KVResult) Descriptor() ([]byte, []int) { return fileDescriptorTransport, []int{7} }
 
 func (m *KVResult) GetKey() []byte {
 	if m != nil {
@@ -115,11 +275,189 @@ func (m *KVResult) GetValue() []byte {
 	return nil
 }
 
+func (m *KVResult) GetSucceeded() bool {
+	if m != nil {
+		return m.Succeeded
+	}
+	return false
+}
+
+func (m *KVResult) GetTxn() *KVTxnResult {
+	if m != nil {
+		return m.Txn
+	}
+	return nil
+}
+
+// ReplicaStatus is the status of a replica's command space, as seen by the
+// node reporting it.
+type ReplicaStatus struct {
+	ReplicaID               uint64 `protobuf:"varint,1,opt,name=replica_id,json=replicaId,proto3" json:"replica_id,omitempty"`
+	MaxInstanceNum          uint64 `protobuf:"varint,2,opt,name=max_instance_num,json=maxInstanceNum,proto3" json:"max_instance_num,omitempty"`
+	MaxSeqNum               uint64 `protobuf:"varint,3,opt,name=max_seq_num,json=maxSeqNum,proto3" json:"max_seq_num,omitempty"`
+	MaxTruncatedInstanceNum uint64 `protobuf:"varint,4,opt,name=max_truncated_instance_num,json=maxTruncatedInstanceNum,proto3" json:"max_truncated_instance_num,omitempty"`
+}
+
+func (m *ReplicaStatus) Reset()                    { *m = ReplicaStatus{} }
+func (m *ReplicaStatus) String() string            { return proto.CompactTextString(m) }
+func (*ReplicaStatus) ProtoMessage()               {}
+func (*ReplicaStatus) Descriptor() ([]byte, []int) { return fileDescriptorTransport, []int{8} }
+
+func (m *ReplicaStatus) GetReplicaID() uint64 {
+	if m != nil {
+		return m.ReplicaID
+	}
+	return 0
+}
+
+func (m *ReplicaStatus) GetMaxInstanceNum() uint64 {
+	if m != nil {
+		return m.MaxInstanceNum
+	}
+	return 0
+}
+
+func (m *ReplicaStatus) GetMaxSeqNum() uint64 {
+	if m != nil {
+		return m.MaxSeqNum
+	}
+	return 0
+}
+
+func (m *ReplicaStatus) GetMaxTruncatedInstanceNum() uint64 {
+	if m != nil {
+		return m.MaxTruncatedInstanceNum
+	}
+	return 0
+}
+
+// InstanceCount is the number of instances with a status.
+type InstanceCount struct {
+	Status epaxospb.InstanceState_Status `protobuf:"varint,1,opt,name=status,proto3,enum=epaxospb.InstanceState_Status" json:"status,omitempty"`
+	Count  uint64                        `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+}
+
+func (m *InstanceCount) Reset()                    { *m = InstanceCount{} }
+func (m *InstanceCount) String() string            { return proto.CompactTextString(m) }
+func (*InstanceCount) ProtoMessage()               {}
+func (*InstanceCount) Descriptor() ([]byte, []int) { return fileDescriptorTransport, []int{9} }
+
+func (m *InstanceCount) GetStatus() epaxospb.InstanceState_Status {
+	if m != nil {
+		return m.Status
+	}
+	return epaxospb.InstanceState_None
+}
+
+func (m *InstanceCount) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+// NodeStatus is a point-in-time snapshot of the state of an EPaxos node. See
+// epaxos.Status.
+type NodeStatus struct {
+	ID                 uint64                `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Nodes              []uint64              `protobuf:"varint,2,rep,packed,name=nodes" json:"nodes,omitempty"`
+	Epoch              uint64                `protobuf:"varint,3,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	ConfChangeInstance epaxospb.InstanceID   `protobuf:"bytes,4,opt,name=conf_change_instance,json=confChangeInstance" json:"conf_change_instance"`
+	Replicas           []ReplicaStatus       `protobuf:"bytes,5,rep,name=replicas" json:"replicas"`
+	InstanceCounts     []InstanceCount       `protobuf:"bytes,6,rep,name=instance_counts,json=instanceCounts" json:"instance_counts"`
+	PendingExecutions  []epaxospb.InstanceID `protobuf:"bytes,7,rep,name=pending_executions,json=pendingExecutions" json:"pending_executions"`
+	BlockedExecutions  uint64                `protobuf:"varint,8,opt,name=blocked_executions,json=blockedExecutions,proto3" json:"blocked_executions,omitempty"`
+	ArmedTimers        uint64                `protobuf:"varint,9,opt,name=armed_timers,json=armedTimers,proto3" json:"armed_timers,omitempty"`
+	PendingReads       uint64                `protobuf:"varint,10,opt,name=pending_reads,json=pendingReads,proto3" json:"pending_reads,omitempty"`
+}
+
+func (m *NodeStatus) Reset()                    { *m = NodeStatus{} }
+func (m *NodeStatus) String() string            { return proto.CompactTextString(m) }
+func (*NodeStatus) ProtoMessage()               {}
+func (*NodeStatus) Descriptor() ([]byte, []int) { return fileDescriptorTransport, []int{10} }
+
+func (m *NodeStatus) GetID() uint64 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *NodeStatus) GetNodes() []uint64 {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+func (m *NodeStatus) GetEpoch() uint64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *NodeStatus) GetConfChangeInstance() epaxospb.InstanceID {
+	if m != nil {
+		return m.ConfChangeInstance
+	}
+	return epaxospb.InstanceID{}
+}
+
+func (m *NodeStatus) GetReplicas() []ReplicaStatus {
+	if m != nil {
+		return m.Replicas
+	}
+	return nil
+}
+
+func (m *NodeStatus) GetInstanceCounts() []InstanceCount {
+	if m != nil {
+		return m.InstanceCounts
+	}
+	return nil
+}
+
+func (m *NodeStatus) GetPendingExecutions() []epaxospb.InstanceID {
+	if m != nil {
+		return m.PendingExecutions
+	}
+	return nil
+}
+
+func (m *NodeStatus) GetBlockedExecutions() uint64 {
+	if m != nil {
+		return m.BlockedExecutions
+	}
+	return 0
+}
+
+func (m *NodeStatus) GetArmedTimers() uint64 {
+	if m != nil {
+		return m.ArmedTimers
+	}
+	return 0
+}
+
+func (m *NodeStatus) GetPendingReads() uint64 {
+	if m != nil {
+		return m.PendingReads
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Empty)(nil), "transportpb.Empty")
+	proto.RegisterType((*SnapshotChunk)(nil), "transportpb.SnapshotChunk")
 	proto.RegisterType((*KVReadRequest)(nil), "transportpb.KVReadRequest")
 	proto.RegisterType((*KVWriteRequest)(nil), "transportpb.KVWriteRequest")
+	proto.RegisterType((*KVCompareAndSwapRequest)(nil), "transportpb.KVCompareAndSwapRequest")
+	proto.RegisterType((*KVTxnRequest)(nil), "transportpb.KVTxnRequest")
+	proto.RegisterType((*KVTxnResult)(nil), "transportpb.KVTxnResult")
 	proto.RegisterType((*KVResult)(nil), "transportpb.KVResult")
+	proto.RegisterType((*ReplicaStatus)(nil), "transportpb.ReplicaStatus")
+	proto.RegisterType((*InstanceCount)(nil), "transportpb.InstanceCount")
+	proto.RegisterType((*NodeStatus)(nil), "transportpb.NodeStatus")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -134,6 +472,8 @@ const _ = grpc.SupportPackageIsVersion4
 
 type EPaxosTransportClient interface {
 	DeliverMessage(ctx context.Context, opts ...grpc.CallOption) (EPaxosTransport_DeliverMessageClient, error)
+	DeliverSnapshot(ctx context.Context, opts ...grpc.CallOption) (EPaxosTransport_DeliverSnapshotClient, error)
+	ProposeConfChange(ctx context.Context, in *epaxospb.ConfChange, opts ...grpc.CallOption) (*Empty, error)
 }
 
 type ePaxosTransportClient struct {
@@ -178,10 +518,55 @@ func (x *ePaxosTransportDeliverMessageClient) CloseAndRecv() (*Empty, error) {
 	return m, nil
 }
 
+func (c *ePaxosTransportClient) DeliverSnapshot(ctx context.Context, opts ...grpc.CallOption) (EPaxosTransport_DeliverSnapshotClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_EPaxosTransport_serviceDesc.Streams[1], c.cc, "/transportpb.EPaxosTransport/DeliverSnapshot", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &ePaxosTransportDeliverSnapshotClient{stream}
+	return x, nil
+}
+
+type EPaxosTransport_DeliverSnapshotClient interface {
+	Send(*SnapshotChunk) error
+	CloseAndRecv() (*Empty, error)
+	grpc.ClientStream
+}
+
+type ePaxosTransportDeliverSnapshotClient struct {
+	grpc.ClientStream
+}
+
+func (x *ePaxosTransportDeliverSnapshotClient) Send(m *SnapshotChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *ePaxosTransportDeliverSnapshotClient) CloseAndRecv() (*Empty, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(Empty)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *ePaxosTransportClient) ProposeConfChange(ctx context.Context, in *epaxospb.ConfChange, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := grpc.Invoke(ctx, "/transportpb.EPaxosTransport/ProposeConfChange", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for EPaxosTransport service
 
 type EPaxosTransportServer interface {
 	DeliverMessage(EPaxosTransport_DeliverMessageServer) error
+	DeliverSnapshot(EPaxosTransport_DeliverSnapshotServer) error
+	ProposeConfChange(context.Context, *epaxospb.ConfChange) (*Empty, error)
 }
 
 func RegisterEPaxosTransportServer(s *grpc.Server, srv EPaxosTransportServer) {
@@ -214,16 +599,70 @@ func (x *ePaxosTransportDeliverMessageServer) Recv() (*epaxospb.Message, error)
 	return m, nil
 }
 
+func _EPaxosTransport_DeliverSnapshot_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(EPaxosTransportServer).DeliverSnapshot(&ePaxosTransportDeliverSnapshotServer{stream})
+}
+
+type EPaxosTransport_DeliverSnapshotServer interface {
+	SendAndClose(*Empty) error
+	Recv() (*SnapshotChunk, error)
+	grpc.ServerStream
+}
+
+type ePaxosTransportDeliverSnapshotServer struct {
+	grpc.ServerStream
+}
+
+func (x *ePaxosTransportDeliverSnapshotServer) SendAndClose(m *Empty) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *ePaxosTransportDeliverSnapshotServer) Recv() (*SnapshotChunk, error) {
+	m := new(SnapshotChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _EPaxosTransport_ProposeConfChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(epaxospb.ConfChange)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EPaxosTransportServer).ProposeConfChange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/transportpb.EPaxosTransport/ProposeConfChange",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EPaxosTransportServer).ProposeConfChange(ctx, req.(*epaxospb.ConfChange))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _EPaxosTransport_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "transportpb.EPaxosTransport",
 	HandlerType: (*EPaxosTransportServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ProposeConfChange",
+			Handler:    _EPaxosTransport_ProposeConfChange_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "DeliverMessage",
 			Handler:       _EPaxosTransport_DeliverMessage_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "DeliverSnapshot",
+			Handler:       _EPaxosTransport_DeliverSnapshot_Handler,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "transport.proto",
 }
@@ -233,6 +672,8 @@ var _EPaxosTransport_serviceDesc = grpc.ServiceDesc{
 type KVServiceClient interface {
 	Read(ctx context.Context, in *KVReadRequest, opts ...grpc.CallOption) (*KVResult, error)
 	Write(ctx context.Context, in *KVWriteRequest, opts ...grpc.CallOption) (*KVResult, error)
+	CompareAndSwap(ctx context.Context, in *KVCompareAndSwapRequest, opts ...grpc.CallOption) (*KVResult, error)
+	Txn(ctx context.Context, in *KVTxnRequest, opts ...grpc.CallOption) (*KVTxnResult, error)
 }
 
 type kVServiceClient struct {
@@ -261,11 +702,31 @@ func (c *kVServiceClient) Write(ctx context.Context, in *KVWriteRequest, opts ..
 	return out, nil
 }
 
+func (c *kVServiceClient) CompareAndSwap(ctx context.Context, in *KVCompareAndSwapRequest, opts ...grpc.CallOption) (*KVResult, error) {
+	out := new(KVResult)
+	err := grpc.Invoke(ctx, "/transportpb.KVService/CompareAndSwap", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kVServiceClient) Txn(ctx context.Context, in *KVTxnRequest, opts ...grpc.CallOption) (*KVTxnResult, error) {
+	out := new(KVTxnResult)
+	err := grpc.Invoke(ctx, "/transportpb.KVService/Txn", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for KVService service
 
 type KVServiceServer interface {
 	Read(context.Context, *KVReadRequest) (*KVResult, error)
 	Write(context.Context, *KVWriteRequest) (*KVResult, error)
+	CompareAndSwap(context.Context, *KVCompareAndSwapRequest) (*KVResult, error)
+	Txn(context.Context, *KVTxnRequest) (*KVTxnResult, error)
 }
 
 func RegisterKVServiceServer(s *grpc.Server, srv KVServiceServer) {
@@ -308,21 +769,129 @@ func _KVService_Write_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
-var _KVService_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "transportpb.KVService",
-	HandlerType: (*KVServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "Read",
-			Handler:    _KVService_Read_Handler,
-		},
-		{
-			MethodName: "Write",
-			Handler:    _KVService_Write_Handler,
-		},
-	},
-	Streams:  []grpc.StreamDesc{},
-	Metadata: "transport.proto",
+func _KVService_CompareAndSwap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KVCompareAndSwapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).CompareAndSwap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/transportpb.KVService/CompareAndSwap",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).CompareAndSwap(ctx, req.(*KVCompareAndSwapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KVService_Txn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KVTxnRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KVServiceServer).Txn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/transportpb.KVService/Txn",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KVServiceServer).Txn(ctx, req.(*KVTxnRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _KVService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "transportpb.KVService",
+	HandlerType: (*KVServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Read",
+			Handler:    _KVService_Read_Handler,
+		},
+		{
+			MethodName: "Write",
+			Handler:    _KVService_Write_Handler,
+		},
+		{
+			MethodName: "CompareAndSwap",
+			Handler:    _KVService_CompareAndSwap_Handler,
+		},
+		{
+			MethodName: "Txn",
+			Handler:    _KVService_Txn_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "transport.proto",
+}
+
+// Client API for AdminService service
+
+type AdminServiceClient interface {
+	Status(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*NodeStatus, error)
+}
+
+type adminServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAdminServiceClient(cc *grpc.ClientConn) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) Status(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*NodeStatus, error) {
+	out := new(NodeStatus)
+	err := grpc.Invoke(ctx, "/transportpb.AdminService/Status", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for AdminService service
+
+type AdminServiceServer interface {
+	Status(context.Context, *Empty) (*NodeStatus, error)
+}
+
+func RegisterAdminServiceServer(s *grpc.Server, srv AdminServiceServer) {
+	s.RegisterService(&_AdminService_serviceDesc, srv)
+}
+
+func _AdminService_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/transportpb.AdminService/Status",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Status(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _AdminService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "transportpb.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Status",
+			Handler:    _AdminService_Status_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "transport.proto",
 }
 
 func (m *Empty) Marshal() (dAtA []byte, err error) {
@@ -343,6 +912,40 @@ func (m *Empty) MarshalTo(dAtA []byte) (int, error) {
 	return i, nil
 }
 
+func (m *SnapshotChunk) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SnapshotChunk) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Metadata != nil {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.Metadata.Size()))
+		n1, err := m.Metadata.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n1
+	}
+	if len(m.Data) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(len(m.Data)))
+		i += copy(dAtA[i:], m.Data)
+	}
+	return i, nil
+}
+
 func (m *KVReadRequest) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -394,10 +997,18 @@ func (m *KVWriteRequest) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintTransport(dAtA, i, uint64(len(m.Value)))
 		i += copy(dAtA[i:], m.Value)
 	}
+	dAtA[i] = 0x1a
+	i++
+	i = encodeVarintTransport(dAtA, i, uint64(m.Session.Size()))
+	n2, err := m.Session.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n2
 	return i, nil
 }
 
-func (m *KVResult) Marshal() (dAtA []byte, err error) {
+func (m *KVCompareAndSwapRequest) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
 	n, err := m.MarshalTo(dAtA)
@@ -407,7 +1018,7 @@ func (m *KVResult) Marshal() (dAtA []byte, err error) {
 	return dAtA[:n], nil
 }
 
-func (m *KVResult) MarshalTo(dAtA []byte) (int, error) {
+func (m *KVCompareAndSwapRequest) MarshalTo(dAtA []byte) (int, error) {
 	var i int
 	_ = i
 	var l int
@@ -418,128 +1029,1652 @@ func (m *KVResult) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintTransport(dAtA, i, uint64(len(m.Key)))
 		i += copy(dAtA[i:], m.Key)
 	}
-	if len(m.Value) > 0 {
+	if len(m.ExpectedValue) > 0 {
 		dAtA[i] = 0x12
 		i++
+		i = encodeVarintTransport(dAtA, i, uint64(len(m.ExpectedValue)))
+		i += copy(dAtA[i:], m.ExpectedValue)
+	}
+	if m.ExpectAbsent {
+		dAtA[i] = 0x18
+		i++
+		if m.ExpectAbsent {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if len(m.Value) > 0 {
+		dAtA[i] = 0x22
+		i++
 		i = encodeVarintTransport(dAtA, i, uint64(len(m.Value)))
 		i += copy(dAtA[i:], m.Value)
 	}
+	dAtA[i] = 0x2a
+	i++
+	i = encodeVarintTransport(dAtA, i, uint64(m.Session.Size()))
+	n3, err := m.Session.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n3
 	return i, nil
 }
 
-func encodeFixed64Transport(dAtA []byte, offset int, v uint64) int {
-	dAtA[offset] = uint8(v)
-	dAtA[offset+1] = uint8(v >> 8)
-	dAtA[offset+2] = uint8(v >> 16)
-	dAtA[offset+3] = uint8(v >> 24)
-	dAtA[offset+4] = uint8(v >> 32)
-	dAtA[offset+5] = uint8(v >> 40)
-	dAtA[offset+6] = uint8(v >> 48)
-	dAtA[offset+7] = uint8(v >> 56)
-	return offset + 8
-}
-func encodeFixed32Transport(dAtA []byte, offset int, v uint32) int {
-	dAtA[offset] = uint8(v)
-	dAtA[offset+1] = uint8(v >> 8)
-	dAtA[offset+2] = uint8(v >> 16)
-	dAtA[offset+3] = uint8(v >> 24)
-	return offset + 4
-}
-func encodeVarintTransport(dAtA []byte, offset int, v uint64) int {
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+func (m *KVTxnRequest) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
 	}
-	dAtA[offset] = uint8(v)
-	return offset + 1
+	return dAtA[:n], nil
 }
-func (m *Empty) Size() (n int) {
+
+func (m *KVTxnRequest) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	return n
+	if len(m.Puts) > 0 {
+		for _, msg := range m.Puts {
+			dAtA[i] = 0xa
+			i++
+			i = encodeVarintTransport(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if len(m.Gets) > 0 {
+		for _, msg := range m.Gets {
+			dAtA[i] = 0x12
+			i++
+			i = encodeVarintTransport(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	dAtA[i] = 0x1a
+	i++
+	i = encodeVarintTransport(dAtA, i, uint64(m.Session.Size()))
+	n4, err := m.Session.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n4
+	return i, nil
 }
 
-func (m *KVReadRequest) Size() (n int) {
-	var l int
-	_ = l
-	l = len(m.Key)
-	if l > 0 {
-		n += 1 + l + sovTransport(uint64(l))
+func (m *KVTxnResult) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
 	}
-	return n
+	return dAtA[:n], nil
 }
 
-func (m *KVWriteRequest) Size() (n int) {
+func (m *KVTxnResult) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	l = len(m.Key)
-	if l > 0 {
-		n += 1 + l + sovTransport(uint64(l))
+	if len(m.Gets) > 0 {
+		for _, msg := range m.Gets {
+			dAtA[i] = 0xa
+			i++
+			i = encodeVarintTransport(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
 	}
-	l = len(m.Value)
-	if l > 0 {
-		n += 1 + l + sovTransport(uint64(l))
+	return i, nil
+}
+
+func (m *KVResult) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
 	}
-	return n
+	return dAtA[:n], nil
 }
 
-func (m *KVResult) Size() (n int) {
+func (m *KVResult) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
 	var l int
 	_ = l
-	l = len(m.Key)
-	if l > 0 {
-		n += 1 + l + sovTransport(uint64(l))
+	if len(m.Key) > 0 {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(len(m.Key)))
+		i += copy(dAtA[i:], m.Key)
 	}
-	l = len(m.Value)
-	if l > 0 {
-		n += 1 + l + sovTransport(uint64(l))
+	if len(m.Value) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(len(m.Value)))
+		i += copy(dAtA[i:], m.Value)
 	}
-	return n
-}
-
-func sovTransport(x uint64) (n int) {
-	for {
-		n++
-		x >>= 7
-		if x == 0 {
-			break
+	if m.Succeeded {
+		dAtA[i] = 0x18
+		i++
+		if m.Succeeded {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i++
+	}
+	if m.Txn != nil {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.Txn.Size()))
+		n5, err := m.Txn.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
 		}
+		i += n5
 	}
-	return n
+	return i, nil
 }
-func sozTransport(x uint64) (n int) {
-	return sovTransport(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+
+func (m *ReplicaStatus) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
 }
-func (m *Empty) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
+
+func (m *ReplicaStatus) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.ReplicaID != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.ReplicaID))
+	}
+	if m.MaxInstanceNum != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.MaxInstanceNum))
+	}
+	if m.MaxSeqNum != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.MaxSeqNum))
+	}
+	if m.MaxTruncatedInstanceNum != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.MaxTruncatedInstanceNum))
+	}
+	return i, nil
+}
+
+func (m *InstanceCount) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *InstanceCount) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Status != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.Status))
+	}
+	if m.Count != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.Count))
+	}
+	return i, nil
+}
+
+func (m *NodeStatus) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *NodeStatus) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.ID != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.ID))
+	}
+	if len(m.Nodes) > 0 {
+		dAtA7 := make([]byte, len(m.Nodes)*10)
+		var j6 int
+		for _, num := range m.Nodes {
+			for num >= 1<<7 {
+				dAtA7[j6] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j6++
+			}
+			dAtA7[j6] = uint8(num)
+			j6++
+		}
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(j6))
+		i += copy(dAtA[i:], dAtA7[:j6])
+	}
+	if m.Epoch != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.Epoch))
+	}
+	dAtA[i] = 0x22
+	i++
+	i = encodeVarintTransport(dAtA, i, uint64(m.ConfChangeInstance.Size()))
+	n8, err := m.ConfChangeInstance.MarshalTo(dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n8
+	if len(m.Replicas) > 0 {
+		for _, msg := range m.Replicas {
+			dAtA[i] = 0x2a
+			i++
+			i = encodeVarintTransport(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if len(m.InstanceCounts) > 0 {
+		for _, msg := range m.InstanceCounts {
+			dAtA[i] = 0x32
+			i++
+			i = encodeVarintTransport(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if len(m.PendingExecutions) > 0 {
+		for _, msg := range m.PendingExecutions {
+			dAtA[i] = 0x3a
+			i++
+			i = encodeVarintTransport(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if m.BlockedExecutions != 0 {
+		dAtA[i] = 0x40
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.BlockedExecutions))
+	}
+	if m.ArmedTimers != 0 {
+		dAtA[i] = 0x48
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.ArmedTimers))
+	}
+	if m.PendingReads != 0 {
+		dAtA[i] = 0x50
+		i++
+		i = encodeVarintTransport(dAtA, i, uint64(m.PendingReads))
+	}
+	return i, nil
+}
+
+func encodeFixed64Transport(dAtA []byte, offset int, v uint64) int {
+	dAtA[offset] = uint8(v)
+	dAtA[offset+1] = uint8(v >> 8)
+	dAtA[offset+2] = uint8(v >> 16)
+	dAtA[offset+3] = uint8(v >> 24)
+	dAtA[offset+4] = uint8(v >> 32)
+	dAtA[offset+5] = uint8(v >> 40)
+	dAtA[offset+6] = uint8(v >> 48)
+	dAtA[offset+7] = uint8(v >> 56)
+	return offset + 8
+}
+func encodeFixed32Transport(dAtA []byte, offset int, v uint32) int {
+	dAtA[offset] = uint8(v)
+	dAtA[offset+1] = uint8(v >> 8)
+	dAtA[offset+2] = uint8(v >> 16)
+	dAtA[offset+3] = uint8(v >> 24)
+	return offset + 4
+}
+func encodeVarintTransport(dAtA []byte, offset int, v uint64) int {
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return offset + 1
+}
+func (m *Empty) Size() (n int) {
+	var l int
+	_ = l
+	return n
+}
+
+func (m *SnapshotChunk) Size() (n int) {
+	var l int
+	_ = l
+	if m.Metadata != nil {
+		l = m.Metadata.Size()
+		n += 1 + l + sovTransport(uint64(l))
+	}
+	l = len(m.Data)
+	if l > 0 {
+		n += 1 + l + sovTransport(uint64(l))
+	}
+	return n
+}
+
+func (m *KVReadRequest) Size() (n int) {
+	var l int
+	_ = l
+	l = len(m.Key)
+	if l > 0 {
+		n += 1 + l + sovTransport(uint64(l))
+	}
+	return n
+}
+
+func (m *KVWriteRequest) Size() (n int) {
+	var l int
+	_ = l
+	l = len(m.Key)
+	if l > 0 {
+		n += 1 + l + sovTransport(uint64(l))
+	}
+	l = len(m.Value)
+	if l > 0 {
+		n += 1 + l + sovTransport(uint64(l))
+	}
+	l = m.Session.Size()
+	n += 1 + l + sovTransport(uint64(l))
+	return n
+}
+
+func (m *KVCompareAndSwapRequest) Size() (n int) {
+	var l int
+	_ = l
+	l = len(m.Key)
+	if l > 0 {
+		n += 1 + l + sovTransport(uint64(l))
+	}
+	l = len(m.ExpectedValue)
+	if l > 0 {
+		n += 1 + l + sovTransport(uint64(l))
+	}
+	if m.ExpectAbsent {
+		n += 2
+	}
+	l = len(m.Value)
+	if l > 0 {
+		n += 1 + l + sovTransport(uint64(l))
+	}
+	l = m.Session.Size()
+	n += 1 + l + sovTransport(uint64(l))
+	return n
+}
+
+func (m *KVTxnRequest) Size() (n int) {
+	var l int
+	_ = l
+	if len(m.Puts) > 0 {
+		for _, e := range m.Puts {
+			l = e.Size()
+			n += 1 + l + sovTransport(uint64(l))
+		}
+	}
+	if len(m.Gets) > 0 {
+		for _, e := range m.Gets {
+			l = e.Size()
+			n += 1 + l + sovTransport(uint64(l))
+		}
+	}
+	l = m.Session.Size()
+	n += 1 + l + sovTransport(uint64(l))
+	return n
+}
+
+func (m *KVTxnResult) Size() (n int) {
+	var l int
+	_ = l
+	if len(m.Gets) > 0 {
+		for _, e := range m.Gets {
+			l = e.Size()
+			n += 1 + l + sovTransport(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *KVResult) Size() (n int) {
+	var l int
+	_ = l
+	l = len(m.Key)
+	if l > 0 {
+		n += 1 + l + sovTransport(uint64(l))
+	}
+	l = len(m.Value)
+	if l > 0 {
+		n += 1 + l + sovTransport(uint64(l))
+	}
+	if m.Succeeded {
+		n += 2
+	}
+	if m.Txn != nil {
+		l = m.Txn.Size()
+		n += 1 + l + sovTransport(uint64(l))
+	}
+	return n
+}
+
+func (m *ReplicaStatus) Size() (n int) {
+	var l int
+	_ = l
+	if m.ReplicaID != 0 {
+		n += 1 + sovTransport(uint64(m.ReplicaID))
+	}
+	if m.MaxInstanceNum != 0 {
+		n += 1 + sovTransport(uint64(m.MaxInstanceNum))
+	}
+	if m.MaxSeqNum != 0 {
+		n += 1 + sovTransport(uint64(m.MaxSeqNum))
+	}
+	if m.MaxTruncatedInstanceNum != 0 {
+		n += 1 + sovTransport(uint64(m.MaxTruncatedInstanceNum))
+	}
+	return n
+}
+
+func (m *InstanceCount) Size() (n int) {
+	var l int
+	_ = l
+	if m.Status != 0 {
+		n += 1 + sovTransport(uint64(m.Status))
+	}
+	if m.Count != 0 {
+		n += 1 + sovTransport(uint64(m.Count))
+	}
+	return n
+}
+
+func (m *NodeStatus) Size() (n int) {
+	var l int
+	_ = l
+	if m.ID != 0 {
+		n += 1 + sovTransport(uint64(m.ID))
+	}
+	if len(m.Nodes) > 0 {
+		l = 0
+		for _, e := range m.Nodes {
+			l += sovTransport(uint64(e))
+		}
+		n += 1 + sovTransport(uint64(l)) + l
+	}
+	if m.Epoch != 0 {
+		n += 1 + sovTransport(uint64(m.Epoch))
+	}
+	l = m.ConfChangeInstance.Size()
+	n += 1 + l + sovTransport(uint64(l))
+	if len(m.Replicas) > 0 {
+		for _, e := range m.Replicas {
+			l = e.Size()
+			n += 1 + l + sovTransport(uint64(l))
+		}
+	}
+	if len(m.InstanceCounts) > 0 {
+		for _, e := range m.InstanceCounts {
+			l = e.Size()
+			n += 1 + l + sovTransport(uint64(l))
+		}
+	}
+	if len(m.PendingExecutions) > 0 {
+		for _, e := range m.PendingExecutions {
+			l = e.Size()
+			n += 1 + l + sovTransport(uint64(l))
+		}
+	}
+	if m.BlockedExecutions != 0 {
+		n += 1 + sovTransport(uint64(m.BlockedExecutions))
+	}
+	if m.ArmedTimers != 0 {
+		n += 1 + sovTransport(uint64(m.ArmedTimers))
+	}
+	if m.PendingReads != 0 {
+		n += 1 + sovTransport(uint64(m.PendingReads))
+	}
+	return n
+}
+
+func sovTransport(x uint64) (n int) {
+	for {
+		n++
+		x >>= 7
+		if x == 0 {
+			break
+		}
+	}
+	return n
+}
+func sozTransport(x uint64) (n int) {
+	return sovTransport(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *Empty) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTransport
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Empty: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Empty: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTransport(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthTransport
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SnapshotChunk) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTransport
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SnapshotChunk: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SnapshotChunk: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Metadata", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Metadata == nil {
+				m.Metadata = &epaxospb.SnapshotMetadata{}
+			}
+			if err := m.Metadata.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Data = append(m.Data[:0], dAtA[iNdEx:postIndex]...)
+			if m.Data == nil {
+				m.Data = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTransport(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthTransport
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *KVReadRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTransport
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: KVReadRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: KVReadRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
+			if m.Key == nil {
+				m.Key = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTransport(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthTransport
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *KVWriteRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTransport
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: KVWriteRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: KVWriteRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
+			if m.Key == nil {
+				m.Key = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
+			if m.Value == nil {
+				m.Value = []byte{}
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Session", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Session.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTransport(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthTransport
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *KVCompareAndSwapRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTransport
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: KVCompareAndSwapRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: KVCompareAndSwapRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
+			if m.Key == nil {
+				m.Key = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ExpectedValue", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ExpectedValue = append(m.ExpectedValue[:0], dAtA[iNdEx:postIndex]...)
+			if m.ExpectedValue == nil {
+				m.ExpectedValue = []byte{}
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ExpectAbsent", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ExpectAbsent = bool(v != 0)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
+			if m.Value == nil {
+				m.Value = []byte{}
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Session", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Session.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTransport(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthTransport
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *KVTxnRequest) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTransport
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: KVTxnRequest: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: KVTxnRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Puts", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Puts = append(m.Puts, KVWriteRequest{})
+			if err := m.Puts[len(m.Puts)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Gets", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Gets = append(m.Gets, KVReadRequest{})
+			if err := m.Gets[len(m.Gets)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Session", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Session.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTransport(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthTransport
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *KVTxnResult) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTransport
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: KVTxnResult: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: KVTxnResult: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Gets", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Gets = append(m.Gets, KVResult{})
+			if err := m.Gets[len(m.Gets)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTransport(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthTransport
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *KVResult) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTransport
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: KVResult: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: KVResult: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
+			if m.Key == nil {
+				m.Key = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
+			if m.Value == nil {
+				m.Value = []byte{}
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Succeeded", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Succeeded = bool(v != 0)
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Txn", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTransport
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Txn == nil {
+				m.Txn = &KVTxnResult{}
+			}
+			if err := m.Txn.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTransport(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthTransport
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ReplicaStatus) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
 		var wire uint64
 		for shift := uint(0); ; shift += 7 {
 			if shift >= 64 {
 				return ErrIntOverflowTransport
 			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ReplicaStatus: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ReplicaStatus: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReplicaID", wireType)
+			}
+			m.ReplicaID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReplicaID |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxInstanceNum", wireType)
+			}
+			m.MaxInstanceNum = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxInstanceNum |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxSeqNum", wireType)
+			}
+			m.MaxSeqNum = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxSeqNum |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxTruncatedInstanceNum", wireType)
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
+			m.MaxTruncatedInstanceNum = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxTruncatedInstanceNum |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Empty: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Empty: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTransport(dAtA[iNdEx:])
@@ -561,7 +2696,7 @@ func (m *Empty) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *KVReadRequest) Unmarshal(dAtA []byte) error {
+func (m *InstanceCount) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -584,17 +2719,17 @@ func (m *KVReadRequest) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: KVReadRequest: wiretype end group for non-group")
+			return fmt.Errorf("proto: InstanceCount: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: KVReadRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: InstanceCount: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Status", wireType)
 			}
-			var byteLen int
+			m.Status = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTransport
@@ -604,23 +2739,30 @@ func (m *KVReadRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				m.Status |= (epaxospb.InstanceState_Status(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
-				return ErrInvalidLengthTransport
-			}
-			postIndex := iNdEx + byteLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Count", wireType)
 			}
-			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
-			if m.Key == nil {
-				m.Key = []byte{}
+			m.Count = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Count |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTransport(dAtA[iNdEx:])
@@ -642,7 +2784,7 @@ func (m *KVReadRequest) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
-func (m *KVWriteRequest) Unmarshal(dAtA []byte) error {
+func (m *NodeStatus) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -665,17 +2807,17 @@ func (m *KVWriteRequest) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: KVWriteRequest: wiretype end group for non-group")
+			return fmt.Errorf("proto: NodeStatus: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: KVWriteRequest: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: NodeStatus: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ID", wireType)
 			}
-			var byteLen int
+			m.ID = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTransport
@@ -685,28 +2827,97 @@ func (m *KVWriteRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				m.ID |= (uint64(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
-				return ErrInvalidLengthTransport
+		case 2:
+			if wireType == 0 {
+				var v uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowTransport
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= (uint64(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.Nodes = append(m.Nodes, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowTransport
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= (int(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthTransport
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				for iNdEx < postIndex {
+					var v uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowTransport
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= (uint64(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.Nodes = append(m.Nodes, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Nodes", wireType)
 			}
-			postIndex := iNdEx + byteLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Epoch", wireType)
 			}
-			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
-			if m.Key == nil {
-				m.Key = []byte{}
+			m.Epoch = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Epoch |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			iNdEx = postIndex
-		case 2:
+		case 4:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ConfChangeInstance", wireType)
 			}
-			var byteLen int
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTransport
@@ -716,78 +2927,58 @@ func (m *KVWriteRequest) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthTransport
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + msglen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
-			if m.Value == nil {
-				m.Value = []byte{}
-			}
-			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipTransport(dAtA[iNdEx:])
-			if err != nil {
+			if err := m.ConfChangeInstance.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthTransport
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Replicas", wireType)
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *KVResult) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowTransport
+			if msglen < 0 {
+				return ErrInvalidLengthTransport
 			}
-			if iNdEx >= l {
+			postIndex := iNdEx + msglen
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
+			m.Replicas = append(m.Replicas, ReplicaStatus{})
+			if err := m.Replicas[len(m.Replicas)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: KVResult: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: KVResult: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			iNdEx = postIndex
+		case 6:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Key", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field InstanceCounts", wireType)
 			}
-			var byteLen int
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTransport
@@ -797,28 +2988,28 @@ func (m *KVResult) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthTransport
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + msglen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Key = append(m.Key[:0], dAtA[iNdEx:postIndex]...)
-			if m.Key == nil {
-				m.Key = []byte{}
+			m.InstanceCounts = append(m.InstanceCounts, InstanceCount{})
+			if err := m.InstanceCounts[len(m.InstanceCounts)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
 			iNdEx = postIndex
-		case 2:
+		case 7:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field PendingExecutions", wireType)
 			}
-			var byteLen int
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowTransport
@@ -828,23 +3019,80 @@ func (m *KVResult) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				msglen |= (int(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthTransport
 			}
-			postIndex := iNdEx + byteLen
+			postIndex := iNdEx + msglen
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
-			if m.Value == nil {
-				m.Value = []byte{}
+			m.PendingExecutions = append(m.PendingExecutions, epaxospb.InstanceID{})
+			if err := m.PendingExecutions[len(m.PendingExecutions)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
 			iNdEx = postIndex
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlockedExecutions", wireType)
+			}
+			m.BlockedExecutions = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.BlockedExecutions |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ArmedTimers", wireType)
+			}
+			m.ArmedTimers = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ArmedTimers |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PendingReads", wireType)
+			}
+			m.PendingReads = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTransport
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.PendingReads |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTransport(dAtA[iNdEx:])
@@ -974,25 +3222,67 @@ var (
 func init() { proto.RegisterFile("transport.proto", fileDescriptorTransport) }
 
 var fileDescriptorTransport = []byte{
-	// 311 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x2f, 0x29, 0x4a, 0xcc,
-	0x2b, 0x2e, 0xc8, 0x2f, 0x2a, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x86, 0x0b, 0x14,
-	0x24, 0x49, 0xe9, 0xa6, 0x67, 0x96, 0x64, 0x94, 0x26, 0xe9, 0x25, 0xe7, 0xe7, 0xea, 0xa7, 0xe7,
-	0xa7, 0xe7, 0xeb, 0x83, 0xd5, 0x24, 0x95, 0xa6, 0x81, 0x79, 0x60, 0x0e, 0x98, 0x05, 0xd1, 0x2b,
-	0x65, 0x84, 0xa4, 0x3c, 0x37, 0x2b, 0x3f, 0x27, 0x5b, 0x3f, 0xb5, 0xa0, 0xc2, 0x48, 0x3f, 0xb5,
-	0x20, 0xb1, 0x22, 0xbf, 0x18, 0x4a, 0x15, 0x24, 0x41, 0x19, 0x10, 0x3d, 0x4a, 0xec, 0x5c, 0xac,
-	0xae, 0xb9, 0x05, 0x25, 0x95, 0x4a, 0x8a, 0x5c, 0xbc, 0xde, 0x61, 0x41, 0xa9, 0x89, 0x29, 0x41,
-	0xa9, 0x85, 0xa5, 0xa9, 0xc5, 0x25, 0x42, 0x02, 0x5c, 0xcc, 0xd9, 0xa9, 0x95, 0x12, 0x8c, 0x0a,
-	0x8c, 0x1a, 0x3c, 0x41, 0x20, 0xa6, 0x92, 0x05, 0x17, 0x9f, 0x77, 0x58, 0x78, 0x51, 0x66, 0x49,
-	0x2a, 0x4e, 0x35, 0x42, 0x22, 0x5c, 0xac, 0x65, 0x89, 0x39, 0xa5, 0xa9, 0x12, 0x4c, 0x60, 0x31,
-	0x08, 0x47, 0xc9, 0x88, 0x8b, 0x03, 0x64, 0x78, 0x71, 0x69, 0x0e, 0xd1, 0x7a, 0x8c, 0xfc, 0xb8,
-	0xf8, 0x5d, 0x03, 0x40, 0x2e, 0x0d, 0x81, 0x85, 0x88, 0x90, 0x35, 0x17, 0x9f, 0x4b, 0x6a, 0x4e,
-	0x66, 0x59, 0x6a, 0x91, 0x6f, 0x6a, 0x71, 0x71, 0x62, 0x7a, 0xaa, 0x90, 0xa0, 0x1e, 0xcc, 0x5b,
-	0x7a, 0x50, 0x21, 0x29, 0x21, 0x3d, 0xa4, 0x20, 0xd4, 0x83, 0x78, 0x8e, 0x41, 0x83, 0xd1, 0xa8,
-	0x9d, 0x91, 0x8b, 0xd3, 0x3b, 0x2c, 0x38, 0xb5, 0xa8, 0x2c, 0x33, 0x39, 0x55, 0xc8, 0x9a, 0x8b,
-	0x05, 0xe4, 0x59, 0x21, 0x29, 0x14, 0xd5, 0x28, 0x21, 0x20, 0x25, 0x8a, 0x21, 0x07, 0xf2, 0x80,
-	0x12, 0x83, 0x90, 0x2d, 0x17, 0x2b, 0x38, 0x18, 0x84, 0xa4, 0xd1, 0x54, 0x20, 0x07, 0x0e, 0x4e,
-	0xed, 0x4e, 0x02, 0x27, 0x1e, 0xc9, 0x31, 0x5e, 0x78, 0x24, 0xc7, 0xf8, 0xe0, 0x91, 0x1c, 0xe3,
-	0x84, 0xc7, 0x72, 0x0c, 0x49, 0x6c, 0xe0, 0xc8, 0x30, 0x06, 0x04, 0x00, 0x00, 0xff, 0xff, 0x12,
-	0xe7, 0x07, 0x91, 0x0f, 0x02, 0x00, 0x00,
+	// 988 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x56, 0xdd, 0x6e, 0xe3, 0x44,
+	0x14, 0x8e, 0x93, 0xb4, 0xdb, 0x9c, 0xfc, 0xb4, 0x1d, 0x15, 0x6a, 0x02, 0x4a, 0xbb, 0x06, 0xa4,
+	0x0a, 0xb1, 0xa9, 0x14, 0x60, 0xf7, 0xa2, 0x0b, 0xa8, 0x4d, 0x2b, 0x14, 0x95, 0x5d, 0x56, 0x4e,
+	0x15, 0x2e, 0x10, 0x8a, 0x26, 0xf6, 0xd9, 0xd4, 0x34, 0x9e, 0x71, 0x3d, 0xe3, 0xe2, 0x95, 0x78,
+	0x08, 0xae, 0x78, 0x12, 0x1e, 0x01, 0xa1, 0xbd, 0xe4, 0x8a, 0xcb, 0x0a, 0x85, 0x2b, 0xde, 0x02,
+	0x79, 0x3c, 0x4e, 0x9c, 0x6e, 0xb2, 0xfc, 0x5c, 0xd5, 0xf3, 0xcd, 0x77, 0xce, 0x7c, 0xdf, 0x39,
+	0xd3, 0x33, 0x81, 0x4d, 0x19, 0x52, 0x26, 0x02, 0x1e, 0xca, 0x76, 0x10, 0x72, 0xc9, 0x49, 0x75,
+	0x06, 0x04, 0xa3, 0xe6, 0x83, 0xb1, 0x27, 0x2f, 0xa3, 0x51, 0xdb, 0xe1, 0xfe, 0xe1, 0x98, 0x8f,
+	0xf9, 0xa1, 0xe2, 0x8c, 0xa2, 0xe7, 0x6a, 0xa5, 0x16, 0xea, 0x2b, 0x8d, 0x6d, 0x76, 0x72, 0x74,
+	0xff, 0x3b, 0x3e, 0xb9, 0x3a, 0xc4, 0x20, 0xee, 0x1c, 0x62, 0x40, 0x63, 0x2e, 0xf4, 0x9f, 0x60,
+	0xa4, 0x3f, 0xd2, 0x18, 0xeb, 0x1e, 0xac, 0x9d, 0xf9, 0x81, 0x7c, 0x61, 0x7d, 0x03, 0xf5, 0x3e,
+	0xa3, 0x81, 0xb8, 0xe4, 0xb2, 0x7b, 0x19, 0xb1, 0x2b, 0xf2, 0x10, 0x36, 0x7c, 0x94, 0xd4, 0xa5,
+	0x92, 0x9a, 0xc6, 0xbe, 0x71, 0x50, 0xed, 0x34, 0xdb, 0x59, 0x8e, 0x76, 0x46, 0x7d, 0xa2, 0x19,
+	0xf6, 0x8c, 0x4b, 0x08, 0x94, 0x55, 0x4c, 0x71, 0xdf, 0x38, 0xa8, 0xd9, 0xea, 0xdb, 0xba, 0x0f,
+	0xf5, 0xf3, 0x81, 0x8d, 0xd4, 0xb5, 0xf1, 0x3a, 0x42, 0x21, 0xc9, 0x16, 0x94, 0xae, 0xf0, 0x85,
+	0xca, 0x5b, 0xb3, 0x93, 0x4f, 0xeb, 0x1a, 0x1a, 0xe7, 0x83, 0xaf, 0x43, 0x4f, 0xe2, 0x4a, 0x0e,
+	0xd9, 0x81, 0xb5, 0x1b, 0x3a, 0x89, 0x50, 0xe7, 0x4e, 0x17, 0xe4, 0x11, 0xdc, 0x13, 0x28, 0x84,
+	0xc7, 0x99, 0x59, 0x52, 0x3a, 0x77, 0xe7, 0x3a, 0xbb, 0x13, 0x0f, 0x99, 0xec, 0xa7, 0xdb, 0x27,
+	0xe5, 0x97, 0xb7, 0x7b, 0x05, 0x3b, 0x63, 0x5b, 0xbf, 0x1a, 0xb0, 0x7b, 0x3e, 0xe8, 0x72, 0x3f,
+	0xa0, 0x21, 0x1e, 0x33, 0xb7, 0xff, 0x3d, 0x0d, 0x56, 0x1f, 0xfe, 0x3e, 0x34, 0x30, 0x0e, 0xd0,
+	0x91, 0xe8, 0x0e, 0xf3, 0x2a, 0xea, 0x19, 0x3a, 0x50, 0x6a, 0xde, 0x05, 0x0d, 0x0c, 0xe9, 0x48,
+	0x20, 0x93, 0x4a, 0xd3, 0x86, 0x5d, 0x4b, 0xc1, 0x63, 0x85, 0xcd, 0x8d, 0x94, 0x57, 0x18, 0x59,
+	0xfb, 0x4f, 0x46, 0x7e, 0x36, 0xa0, 0x76, 0x3e, 0xb8, 0x88, 0x59, 0xa6, 0xfe, 0x13, 0x28, 0x07,
+	0x91, 0x14, 0xa6, 0xb1, 0x5f, 0x3a, 0xa8, 0x76, 0xde, 0x6e, 0xe7, 0x2e, 0x55, 0x7b, 0xb1, 0xca,
+	0x3a, 0x95, 0xa2, 0x93, 0x8f, 0xa1, 0x3c, 0x46, 0x29, 0xcc, 0xa2, 0x0a, 0x6b, 0xde, 0x09, 0xcb,
+	0xf5, 0x2f, 0x8b, 0x4a, 0xd8, 0xff, 0xbf, 0xfe, 0x9f, 0x41, 0x55, 0xab, 0x16, 0xd1, 0x44, 0x92,
+	0x43, 0x7d, 0x7a, 0x2a, 0xfa, 0x8d, 0x57, 0x4e, 0x4f, 0x48, 0xf9, 0x83, 0xad, 0x1f, 0x60, 0x23,
+	0xc3, 0xff, 0xf5, 0x65, 0x79, 0x07, 0x2a, 0x22, 0x72, 0x1c, 0x44, 0x17, 0x5d, 0xdd, 0x9a, 0x39,
+	0x40, 0x3e, 0x80, 0x92, 0x8c, 0x99, 0xea, 0x4a, 0xb5, 0x63, 0xde, 0x51, 0x30, 0x53, 0x6a, 0x27,
+	0x24, 0xeb, 0x17, 0x03, 0xea, 0x36, 0x06, 0x13, 0xcf, 0xa1, 0x7d, 0x49, 0x65, 0x24, 0xc8, 0x87,
+	0x00, 0x61, 0x0a, 0x0c, 0x3d, 0x57, 0x49, 0x29, 0x9f, 0xd4, 0xa7, 0xb7, 0x7b, 0x15, 0x4d, 0xeb,
+	0x9d, 0xda, 0x15, 0x4d, 0xe8, 0xb9, 0xe4, 0x00, 0xb6, 0x7c, 0x1a, 0x0f, 0x3d, 0x26, 0x24, 0x65,
+	0x0e, 0x0e, 0x59, 0xe4, 0x2b, 0xa9, 0x65, 0xbb, 0xe1, 0xd3, 0xb8, 0xa7, 0xe1, 0xa7, 0x91, 0x4f,
+	0x5a, 0x50, 0x4d, 0x98, 0x02, 0xaf, 0x15, 0xa9, 0xa4, 0x48, 0x15, 0x9f, 0xc6, 0x7d, 0xbc, 0x4e,
+	0xf6, 0x8f, 0xa0, 0x99, 0xec, 0xcb, 0x30, 0x62, 0x0e, 0x4d, 0xae, 0xe7, 0x42, 0xce, 0xb2, 0xa2,
+	0xef, 0xfa, 0x34, 0xbe, 0xc8, 0x08, 0xb9, 0xe4, 0xd6, 0xb7, 0x50, 0xcf, 0x96, 0x5d, 0x1e, 0x31,
+	0x49, 0x1e, 0xc2, 0xba, 0x50, 0x7e, 0x94, 0x83, 0x46, 0xa7, 0x35, 0xef, 0x66, 0x46, 0x4c, 0xfc,
+	0x62, 0x3b, 0x75, 0x6d, 0x6b, 0x76, 0x52, 0x6f, 0x27, 0x49, 0xa0, 0x4d, 0xa4, 0x0b, 0xeb, 0xaf,
+	0x12, 0xc0, 0x53, 0xee, 0xa2, 0x2e, 0xd1, 0x9b, 0x50, 0x9c, 0x95, 0x66, 0x7d, 0x7a, 0xbb, 0x57,
+	0xec, 0x9d, 0xda, 0x45, 0xcf, 0x4d, 0x82, 0x19, 0x77, 0x31, 0xbd, 0x7a, 0x65, 0x3b, 0x5d, 0x24,
+	0x28, 0x06, 0xdc, 0xb9, 0xd4, 0x96, 0xd3, 0x05, 0xf9, 0x12, 0x76, 0x1c, 0xce, 0x9e, 0x0f, 0x9d,
+	0x4b, 0xca, 0xc6, 0x38, 0x33, 0xab, 0xbb, 0xb6, 0xf3, 0xaa, 0xdc, 0xde, 0xa9, 0xbe, 0x36, 0x24,
+	0x89, 0xeb, 0xaa, 0xb0, 0x6c, 0x8f, 0x3c, 0x86, 0x0d, 0xdd, 0x13, 0x61, 0xae, 0x2d, 0xb9, 0xf7,
+	0x0b, 0x2d, 0xd6, 0x79, 0x66, 0x11, 0xa4, 0x07, 0x9b, 0xb3, 0x62, 0x2b, 0xc3, 0xc2, 0x5c, 0x5f,
+	0x92, 0x64, 0xa1, 0xc2, 0x3a, 0x49, 0xc3, 0xcb, 0x83, 0x49, 0x2a, 0x12, 0x20, 0x73, 0x3d, 0x36,
+	0x1e, 0x62, 0x8c, 0x4e, 0x24, 0x3d, 0xce, 0x84, 0x79, 0x6f, 0xbf, 0xf4, 0x0f, 0xa6, 0xb6, 0x75,
+	0xd4, 0xd9, 0x2c, 0x88, 0x3c, 0x00, 0x32, 0x9a, 0x70, 0xe7, 0x0a, 0xdd, 0x7c, 0xaa, 0x0d, 0x55,
+	0xc4, 0x6d, 0xbd, 0x93, 0xa3, 0xdf, 0x87, 0x1a, 0x0d, 0x7d, 0x74, 0x87, 0xd2, 0xf3, 0x31, 0x14,
+	0x66, 0x45, 0x11, 0xab, 0x0a, 0xbb, 0x50, 0x50, 0x32, 0xd5, 0x32, 0x71, 0x21, 0x52, 0x57, 0x98,
+	0xa0, 0x38, 0x35, 0x0d, 0x26, 0xa3, 0x41, 0x74, 0x7e, 0x37, 0x60, 0xf3, 0xec, 0x59, 0xa2, 0xf3,
+	0x22, 0xf3, 0x4e, 0x8e, 0xa0, 0x71, 0x8a, 0x13, 0xef, 0x06, 0xc3, 0x27, 0x28, 0x04, 0x1d, 0x23,
+	0xd9, 0x9e, 0x7b, 0xd1, 0x50, 0x93, 0x2c, 0x14, 0x2b, 0x7d, 0x8f, 0x0a, 0x07, 0x06, 0x39, 0x83,
+	0x4d, 0x1d, 0x9c, 0xbd, 0x37, 0x64, 0xb1, 0xae, 0x0b, 0x2f, 0xd6, 0xca, 0x34, 0x9f, 0xc3, 0xf6,
+	0xb3, 0x90, 0x07, 0x5c, 0x60, 0x77, 0xd6, 0x7f, 0x92, 0x2b, 0xe9, 0x1c, 0x5d, 0x9e, 0xa2, 0xf3,
+	0x53, 0x11, 0x2a, 0xe7, 0x83, 0x3e, 0x86, 0x37, 0x9e, 0x83, 0xe4, 0x08, 0xca, 0x89, 0x5f, 0xf2,
+	0x9a, 0xf9, 0xd8, 0x5c, 0x3e, 0xbd, 0xac, 0x02, 0xf9, 0x14, 0xd6, 0xd4, 0xf8, 0x25, 0xaf, 0x1b,
+	0xca, 0xab, 0xc3, 0xbf, 0x82, 0xc6, 0xe2, 0x7b, 0x45, 0xde, 0xbb, 0x43, 0x5d, 0xfa, 0x9c, 0xad,
+	0x4e, 0xf8, 0x18, 0x4a, 0x17, 0x31, 0x23, 0x6f, 0x2d, 0x9b, 0x75, 0x69, 0xe8, 0xca, 0x31, 0x68,
+	0x15, 0x3a, 0x5f, 0x40, 0xed, 0xd8, 0xf5, 0x3d, 0x96, 0x95, 0xe6, 0x11, 0xac, 0xeb, 0x7f, 0xf4,
+	0x25, 0x85, 0x6c, 0xee, 0x2e, 0x60, 0xf3, 0xa9, 0x60, 0x15, 0x4e, 0xb6, 0x5e, 0x4e, 0x5b, 0xc6,
+	0x6f, 0xd3, 0x96, 0xf1, 0xc7, 0xb4, 0x65, 0xfc, 0xf8, 0x67, 0xab, 0x30, 0x5a, 0x57, 0xbf, 0x4f,
+	0x3e, 0xfa, 0x7b, 0x00, 0xd8, 0x78, 0x77, 0x82, 0x22, 0x09, 0x00, 0x00,
 }